@@ -2,38 +2,112 @@ package app
 
 import (
 	"back-end/handlers"
+	"back-end/middleware"
+	"back-end/problem"
 
 	"github.com/gorilla/mux"
 )
 
 func RegisterRoutes(router *mux.Router, h *handlers.Handler) {
+	// Tag every request with an id that error responses and logs carry
+	router.Use(middleware.RequestIDMiddleware)
+
+	// Unknown routes and methods answer with problem details too
+	router.NotFoundHandler = middleware.RequestIDMiddleware(problem.NotFoundHandler())
+	router.MethodNotAllowedHandler = middleware.RequestIDMiddleware(problem.MethodNotAllowedHandler())
+
+	// Auth routes. Their responses carry tokens, so they are registered
+	// ahead of v1 to stay clear of its middleware, which would store them.
+	auth := router.PathPrefix("/v1/auth").Subrouter()
+	auth.HandleFunc("/signup", h.SignUp).Methods("POST")
+	auth.HandleFunc("/login", h.Login).Methods("POST")
+
 	// Create a subrouter for v1
 	v1 := router.PathPrefix("/v1").Subrouter()
 
-	// Auth routes
-	v1.HandleFunc("/auth/signup", h.SignUp).Methods("POST")
-	v1.HandleFunc("/auth/login", h.Login).Methods("POST")
+	// Writes with a bearer token are audited as the token's user
+	v1.Use(middleware.OptionalAuthMiddleware(h.Logger))
+
+	// POSTs sent with an Idempotency-Key are safe for clients to retry
+	v1.Use(middleware.IdempotencyMiddleware(h.DB, h.Logger))
 
 	// Health check
 	v1.HandleFunc("/health", h.HealthCheck).Methods("GET")
 
+	// Routes of the signed-in user, who is taken from the bearer token
+	v1.HandleFunc("/me", middleware.AuthMiddleware(h.Logger, h.EraseMe)).Methods("DELETE")
+	v1.HandleFunc("/me/export", middleware.AuthMiddleware(h.Logger, h.RequestMyExport)).Methods("POST")
+	v1.HandleFunc("/me/exports/{id}", middleware.AuthMiddleware(h.Logger, h.GetMyExport)).Methods("GET")
+	v1.HandleFunc("/sync", middleware.AuthMiddleware(h.Logger, h.GetMySyncChanges)).Methods("GET")
+	v1.HandleFunc("/sync", middleware.AuthMiddleware(h.Logger, h.PushMySyncChanges)).Methods("POST")
+
+	// Export archives, authenticated by the signature of the link
+	v1.HandleFunc("/exports/{id}/download", h.DownloadExport).Methods("GET")
+
 	// User profile routes
 	v1.HandleFunc("/profiles", h.CreateUserProfile).Methods("POST")
 	v1.HandleFunc("/profiles/{id}", h.GetUserProfile).Methods("GET")
 	v1.HandleFunc("/profiles", h.ListUserProfiles).Methods("GET")
 	v1.HandleFunc("/profiles/{id}", h.UpdateUserProfile).Methods("PUT")
+	v1.HandleFunc("/profiles/{id}", h.PatchUserProfile).Methods("PATCH")
 	v1.HandleFunc("/profiles/{id}", h.DeleteUserProfile).Methods("DELETE")
+	v1.HandleFunc("/profiles/{id}/restore", h.RestoreUserProfile).Methods("POST")
+	v1.HandleFunc("/profiles/{id}/history", h.GetUserProfileHistory).Methods("GET")
+	v1.HandleFunc("/profiles/{id}/revert", h.RevertUserProfile).Methods("POST")
 
 	// Workout task routes
 	v1.HandleFunc("/tasks", h.CreateWorkoutTask).Methods("POST")
+	v1.HandleFunc("/tasks:batch", h.BatchWorkoutTasks).Methods("POST")
+	v1.HandleFunc("/tasks/reorder", h.ReorderWorkoutTasks).Methods("POST")
 	v1.HandleFunc("/tasks/{id}", h.GetWorkoutTask).Methods("GET")
 	v1.HandleFunc("/tasks", h.ListWorkoutTasks).Methods("GET")
 	v1.HandleFunc("/tasks/{id}", h.UpdateWorkoutTask).Methods("PUT")
+	v1.HandleFunc("/tasks/{id}", h.PatchWorkoutTask).Methods("PATCH")
 	v1.HandleFunc("/tasks/{id}", h.DeleteWorkoutTask).Methods("DELETE")
+	v1.HandleFunc("/tasks/{id}/restore", h.RestoreWorkoutTask).Methods("POST")
+	v1.HandleFunc("/tasks/{id}/history", h.GetWorkoutTaskHistory).Methods("GET")
+	v1.HandleFunc("/tasks/{id}/revert", h.RevertWorkoutTask).Methods("POST")
+
+	// Workout group routes (supersets, circuits and interval blocks)
+	v1.HandleFunc("/groups", h.CreateWorkoutGroup).Methods("POST")
+	v1.HandleFunc("/groups/{id}", h.GetWorkoutGroup).Methods("GET")
+	v1.HandleFunc("/groups/{id}", h.UpdateWorkoutGroup).Methods("PUT")
+	v1.HandleFunc("/groups/{id}", h.DeleteWorkoutGroup).Methods("DELETE")
+	v1.HandleFunc("/groups/{id}/rounds", h.LogWorkoutGroupRound).Methods("POST")
+
+	// iCalendar feed, authenticated by its secret token
+	v1.HandleFunc("/calendar/{token:[A-Za-z0-9_-]+}.ics", h.GetCalendarFeed).Methods("GET")
+
+	// Plan pause routes
+	v1.HandleFunc("/pauses/{id}/resume", h.ResumePlanPause).Methods("POST")
+
+	// Scheduled occurrence routes
+	v1.HandleFunc("/occurrences/{id}/complete", h.CompleteOccurrence).Methods("POST")
+	v1.HandleFunc("/occurrences/{id}/skip", h.SkipOccurrence).Methods("POST")
+
+	// Import routes
+	v1.HandleFunc("/imports/{id}", h.GetImport).Methods("GET")
 
 	// Add these new routes
 	v1.HandleFunc("/profiles/user/{userId}", h.GetUserProfileByUserId).Methods("GET")
+	v1.HandleFunc("/profiles/user/{userId}", h.CreateOrGetUserProfileByUserId).Methods("POST")
 	v1.HandleFunc("/profiles/user/{userId}", h.UpdateUserProfileByUserId).Methods("PUT")
+	v1.HandleFunc("/profiles/user/{userId}", h.PatchUserProfileByUserId).Methods("PATCH")
 	v1.HandleFunc("/profiles/user/{userId}", h.DeleteUserProfileByUserId).Methods("DELETE")
+	v1.HandleFunc("/profiles/user/{userId}/restore", h.RestoreUserProfileByUserId).Methods("POST")
+	v1.HandleFunc("/profiles/user/{userId}/trash", h.GetTrashByUserId).Methods("GET")
 	v1.HandleFunc("/profiles/user/{userId}/tasks", h.GetWorkoutTasksByUserId).Methods("GET")
+	v1.HandleFunc("/profiles/user/{userId}/groups", h.GetWorkoutGroupsByUserId).Methods("GET")
+	v1.HandleFunc("/profiles/user/{userId}/session", h.GetSessionPlanByUserId).Methods("GET")
+	v1.HandleFunc("/profiles/user/{userId}/schedule", h.GetScheduleByUserId).Methods("GET")
+	v1.HandleFunc("/profiles/user/{userId}/schedule/reschedule", h.RescheduleMissedByUserId).Methods("POST")
+	v1.HandleFunc("/profiles/user/{userId}/reschedules", h.GetRescheduleEventsByUserId).Methods("GET")
+	v1.HandleFunc("/profiles/user/{userId}/pauses", h.CreatePlanPauseByUserId).Methods("POST")
+	v1.HandleFunc("/profiles/user/{userId}/pauses", h.GetPlanPausesByUserId).Methods("GET")
+	v1.HandleFunc("/profiles/user/{userId}/stats", h.GetStatsByUserId).Methods("GET")
+	v1.HandleFunc("/profiles/user/{userId}/calendar-token", h.RotateCalendarTokenByUserId).Methods("POST")
+	v1.HandleFunc("/profiles/user/{userId}/calendar-token", h.RevokeCalendarTokenByUserId).Methods("DELETE")
+	v1.HandleFunc("/profiles/user/{userId}/session/warmup", h.GenerateSessionWarmupByUserId).Methods("POST")
+	v1.HandleFunc("/profiles/user/{userId}/workouts/fit", h.FitGeneratedWorkout).Methods("POST")
+	v1.HandleFunc("/profiles/user/{userId}/imports", h.CreateImportByUserId).Methods("POST")
 } 
\ No newline at end of file
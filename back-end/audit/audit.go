@@ -0,0 +1,141 @@
+// audit/audit.go
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"reflect"
+	"time"
+
+	"back-end/models"
+	"back-end/requestid"
+
+	"github.com/go-pg/pg/v10/orm"
+)
+
+// Actor is who made a change: an authenticated user, coach or admin, or an
+// anonymous caller on routes that do not require a token.
+type Actor struct {
+	Type string
+	ID   string
+}
+
+type contextKey struct{}
+
+// NewContext returns a copy of ctx carrying actor.
+func NewContext(ctx context.Context, actor Actor) context.Context {
+	return context.WithValue(ctx, contextKey{}, actor)
+}
+
+// FromContext returns the actor stored in ctx, or an anonymous actor.
+func FromContext(ctx context.Context) Actor {
+	actor, ok := ctx.Value(contextKey{}).(Actor)
+	if !ok {
+		return Actor{Type: models.ActorAnonymous}
+	}
+	return actor
+}
+
+// RoleActor returns the actor for an auth user with the given role. Coaches
+// and admins are marked by their role; everyone else acts as a user.
+func RoleActor(id, role string) Actor {
+	switch role {
+	case models.ActorCoach, models.ActorAdmin:
+		return Actor{Type: role, ID: id}
+	default:
+		return Actor{Type: models.ActorUser, ID: id}
+	}
+}
+
+// Event describes one write. Before is nil for a create.
+type Event struct {
+	Action   string
+	Entity   string
+	EntityID int
+	UserID   int
+	Version  int
+	Before   interface{}
+	After    interface{}
+}
+
+// unaudited fields change on every write and are left out of the diff.
+var unaudited = map[string]bool{
+	"version":   true,
+	"updatedAt": true,
+}
+
+// Record appends an entry for e. It should run in the transaction of the
+// write itself, whose context carries the actor and request id.
+func Record(db orm.DB, e Event) error {
+	before, err := snapshot(e.Before)
+	if err != nil {
+		return err
+	}
+	after, err := snapshot(e.After)
+	if err != nil {
+		return err
+	}
+
+	ctx := db.Context()
+	actor := FromContext(ctx)
+	entry := models.AuditEntry{
+		ActorType: actor.Type,
+		ActorID:   actor.ID,
+		Action:    e.Action,
+		Entity:    e.Entity,
+		EntityID:  e.EntityID,
+		UserID:    e.UserID,
+		Version:   e.Version,
+		Before:    before,
+		After:     after,
+		Changes:   Diff(before, after),
+		RequestID: requestid.FromContext(ctx),
+		CreatedAt: time.Now(),
+	}
+	_, err = db.Model(&entry).Insert()
+	return err
+}
+
+// snapshot returns the JSON form of v as a map, or nil for nil.
+func snapshot(v interface{}) (map[string]interface{}, error) {
+	if v == nil {
+		return nil, nil
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	var m map[string]interface{}
+	if err := json.Unmarshal(b, &m); err != nil {
+		return nil, err
+	}
+	return m, nil
+}
+
+// Diff returns the fields whose values differ between two snapshots.
+// Fields missing from one side, such as omitted empty values, count as
+// null.
+func Diff(before, after map[string]interface{}) map[string]models.AuditChange {
+	changes := make(map[string]models.AuditChange)
+	for name, to := range after {
+		if from := before[name]; !unaudited[name] && !reflect.DeepEqual(from, to) {
+			changes[name] = models.AuditChange{From: from, To: to}
+		}
+	}
+	for name, from := range before {
+		if _, ok := after[name]; !ok && !unaudited[name] && from != nil {
+			changes[name] = models.AuditChange{From: from, To: nil}
+		}
+	}
+	return changes
+}
+
+// Restore decodes the after snapshot of entry into dst, the record as it
+// was once the entry's change was made.
+func Restore(entry *models.AuditEntry, dst interface{}) error {
+	b, err := json.Marshal(entry.After)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(b, dst)
+}
@@ -0,0 +1,41 @@
+// authadmin/authadmin.go
+package authadmin
+
+import (
+	"context"
+	"strings"
+
+	"github.com/google/uuid"
+	"github.com/supabase-community/gotrue-go"
+	"github.com/supabase-community/gotrue-go/types"
+)
+
+// Client manages users in the auth provider with admin rights.
+type Client interface {
+	// DeleteUser deletes the auth user with the given id. Deleting a user
+	// that does not exist succeeds, so a failed erasure can be retried.
+	DeleteUser(ctx context.Context, id string) error
+}
+
+// Supabase deletes users through the Supabase auth admin API. It needs the
+// project's service role key, which must never reach a client.
+type Supabase struct {
+	client gotrue.Client
+}
+
+func NewSupabase(projectID, serviceRoleKey string) *Supabase {
+	return &Supabase{client: gotrue.New(projectID, serviceRoleKey).WithToken(serviceRoleKey)}
+}
+
+func (s *Supabase) DeleteUser(ctx context.Context, id string) error {
+	userID, err := uuid.Parse(id)
+	if err != nil {
+		return err
+	}
+	err = s.client.AdminDeleteUser(types.AdminDeleteUserRequest{UserID: userID})
+	// The client reports API errors by status code only
+	if err != nil && strings.HasPrefix(err.Error(), "response status code 404") {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,33 @@
+// authadmin/fake.go
+package authadmin
+
+import (
+	"context"
+	"sync"
+)
+
+// Fake stands in for the auth provider in local development and tests,
+// so erasing an account never touches a real project. It remembers the
+// users it deleted and fails with Err when one is set.
+type Fake struct {
+	mu      sync.Mutex
+	deleted []string
+	Err     error
+}
+
+func (f *Fake) DeleteUser(ctx context.Context, id string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if f.Err != nil {
+		return f.Err
+	}
+	f.deleted = append(f.deleted, id)
+	return nil
+}
+
+// Deleted returns the ids of the users deleted so far.
+func (f *Fake) Deleted() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.deleted...)
+}
@@ -0,0 +1,107 @@
+// calendar/ical.go
+package calendar
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	prodID        = "-//FitTrack//Workout Calendar//EN"
+	maxLineOctets = 75
+)
+
+// Event is an all-day VEVENT.
+type Event struct {
+	UID         string
+	Date        time.Time
+	Summary     string
+	Description string
+	Cancelled   bool
+	Updated     time.Time
+}
+
+// Calendar is an iCalendar (RFC 5545) VCALENDAR of all-day events.
+type Calendar struct {
+	Name     string
+	TimeZone string
+	Events   []Event
+}
+
+// WriteTo renders the calendar with CRLF line endings, escaped text values
+// and lines folded at 75 octets.
+func (c *Calendar) WriteTo(w io.Writer) (int64, error) {
+	var b bytes.Buffer
+	stamp := time.Now().UTC().Format("20060102T150405Z")
+
+	line(&b, "BEGIN:VCALENDAR")
+	line(&b, "VERSION:2.0")
+	line(&b, "PRODID:"+prodID)
+	line(&b, "CALSCALE:GREGORIAN")
+	line(&b, "METHOD:PUBLISH")
+	if c.Name != "" {
+		line(&b, "X-WR-CALNAME:"+escape(c.Name))
+	}
+	if c.TimeZone != "" {
+		line(&b, "X-WR-TIMEZONE:"+c.TimeZone)
+	}
+
+	for _, e := range c.Events {
+		line(&b, "BEGIN:VEVENT")
+		line(&b, "UID:"+e.UID)
+		line(&b, "DTSTAMP:"+stamp)
+		if !e.Updated.IsZero() {
+			line(&b, "LAST-MODIFIED:"+e.Updated.UTC().Format("20060102T150405Z"))
+		}
+		line(&b, "DTSTART;VALUE=DATE:"+e.Date.Format("20060102"))
+		line(&b, "DTEND;VALUE=DATE:"+e.Date.AddDate(0, 0, 1).Format("20060102"))
+		line(&b, "SUMMARY:"+escape(e.Summary))
+		if e.Description != "" {
+			line(&b, "DESCRIPTION:"+escape(e.Description))
+		}
+		line(&b, "TRANSP:TRANSPARENT")
+		if e.Cancelled {
+			line(&b, "STATUS:CANCELLED")
+		} else {
+			line(&b, "STATUS:CONFIRMED")
+		}
+		line(&b, "END:VEVENT")
+	}
+
+	line(&b, "END:VCALENDAR")
+	return b.WriteTo(w)
+}
+
+// escape applies TEXT value escaping from RFC 5545 section 3.3.11.
+func escape(s string) string {
+	return strings.NewReplacer(
+		`\`, `\\`,
+		";", `\;`,
+		",", `\,`,
+		"\r\n", `\n`,
+		"\n", `\n`,
+		"\r", "",
+	).Replace(s)
+}
+
+// line writes a content line, folding it so no physical line exceeds 75
+// octets without splitting a UTF-8 sequence.
+func line(b *bytes.Buffer, s string) {
+	limit := maxLineOctets
+	for len(s) > limit {
+		cut := limit
+		for cut > 0 && !utf8.RuneStart(s[cut]) {
+			cut--
+		}
+		b.WriteString(s[:cut])
+		b.WriteString("\r\n ")
+		s = s[cut:]
+		// Continuation lines start with a space, which counts toward the limit
+		limit = maxLineOctets - 1
+	}
+	b.WriteString(s)
+	b.WriteString("\r\n")
+}
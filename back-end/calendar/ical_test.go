@@ -0,0 +1,283 @@
+package calendar
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+	"unicode/utf8"
+)
+
+func render(t *testing.T, c *Calendar) string {
+	t.Helper()
+	var b bytes.Buffer
+	if _, err := c.WriteTo(&b); err != nil {
+		t.Fatalf("WriteTo: %v", err)
+	}
+	return b.String()
+}
+
+func testCalendar() *Calendar {
+	return &Calendar{
+		Name:     "FitTrack Workouts",
+		TimeZone: "Europe/Berlin",
+		Events: []Event{
+			{
+				UID:     "occurrence-1@fittrack",
+				Date:    time.Date(2024, 3, 5, 0, 0, 0, 0, time.UTC),
+				Summary: "Bench Press; heavy, then light (~12 min)",
+				Description: "Bench Press: 3 x 5\nEstimated duration: 12 min\n\n" +
+					"Session (2 exercises, ~40 min):\n- Bench Press: 3 x 5\n- Back Squat: 5 x 5 \\ paused",
+				Updated: time.Date(2024, 3, 1, 12, 30, 0, 0, time.UTC),
+			},
+			{
+				UID:         "occurrence-2@fittrack",
+				Date:        time.Date(2024, 3, 6, 0, 0, 0, 0, time.UTC),
+				Summary:     "Übungen mit Kurzhanteln für Schultern und Rücken (~20 min)",
+				Description: strings.Repeat("Schulterdrücken mit Kurzhanteln – ", 10),
+				Cancelled:   true,
+			},
+		},
+	}
+}
+
+func TestWriteToUsesCRLF(t *testing.T) {
+	out := render(t, testCalendar())
+	if !strings.HasSuffix(out, "\r\n") {
+		t.Fatal("output does not end with CRLF")
+	}
+	if n := strings.Count(out, "\n"); n != strings.Count(out, "\r\n") {
+		t.Fatalf("found %d line feeds not preceded by a carriage return", n-strings.Count(out, "\r\n"))
+	}
+	if strings.Contains(strings.ReplaceAll(out, "\r\n", ""), "\r") {
+		t.Fatal("found a bare carriage return")
+	}
+}
+
+func TestWriteToFoldsAt75Octets(t *testing.T) {
+	out := render(t, testCalendar())
+	folded := false
+	for _, l := range strings.Split(strings.TrimSuffix(out, "\r\n"), "\r\n") {
+		if len(l) > maxLineOctets {
+			t.Errorf("line is %d octets long: %q", len(l), l)
+		}
+		if !utf8.ValidString(l) {
+			t.Errorf("fold splits a UTF-8 sequence: %q", l)
+		}
+		if strings.HasPrefix(l, " ") {
+			folded = true
+		}
+	}
+	if !folded {
+		t.Fatal("no line was folded")
+	}
+}
+
+func TestEscape(t *testing.T) {
+	tests := []struct {
+		in, want string
+	}{
+		{"plain", "plain"},
+		{"a,b;c", `a\,b\;c`},
+		{`back\slash`, `back\\slash`},
+		{"two\nlines", `two\nlines`},
+		{"crlf\r\nline", `crlf\nline`},
+		{`\n`, `\\n`},
+	}
+	for _, tt := range tests {
+		if got := escape(tt.in); got != tt.want {
+			t.Errorf("escape(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestWriteToValidates(t *testing.T) {
+	c := testCalendar()
+	cal, err := parse(render(t, c))
+	if err != nil {
+		t.Fatalf("invalid iCalendar: %v", err)
+	}
+	if err := cal.validate(); err != nil {
+		t.Fatalf("invalid iCalendar: %v", err)
+	}
+
+	events := cal.components("VEVENT")
+	if len(events) != len(c.Events) {
+		t.Fatalf("got %d events, want %d", len(events), len(c.Events))
+	}
+	for i, e := range events {
+		want := c.Events[i]
+		if got := e.value("UID"); got != want.UID {
+			t.Errorf("event %d: UID = %q, want %q", i, got, want.UID)
+		}
+		// Text values come back unchanged after unfolding and unescaping
+		if got := unescape(e.value("SUMMARY")); got != want.Summary {
+			t.Errorf("event %d: SUMMARY = %q, want %q", i, got, want.Summary)
+		}
+		if got := unescape(e.value("DESCRIPTION")); got != want.Description {
+			t.Errorf("event %d: DESCRIPTION = %q, want %q", i, got, want.Description)
+		}
+		if got := e.value("DTSTART;VALUE=DATE"); got != want.Date.Format("20060102") {
+			t.Errorf("event %d: DTSTART = %q", i, got)
+		}
+	}
+	if got := events[1].value("STATUS"); got != "CANCELLED" {
+		t.Errorf("cancelled event has STATUS %q", got)
+	}
+}
+
+// component is a parsed iCalendar component: its content lines as name
+// (with parameters) and value, and its nested components.
+type component struct {
+	name   string
+	props  [][2]string
+	nested []*component
+}
+
+func (c *component) value(name string) string {
+	for _, p := range c.props {
+		if p[0] == name {
+			return p[1]
+		}
+	}
+	return ""
+}
+
+func (c *component) count(name string) int {
+	n := 0
+	for _, p := range c.props {
+		if p[0] == name || strings.HasPrefix(p[0], name+";") {
+			n++
+		}
+	}
+	return n
+}
+
+func (c *component) components(name string) []*component {
+	var out []*component
+	for _, child := range c.nested {
+		if child.name == name {
+			out = append(out, child)
+		}
+	}
+	return out
+}
+
+// Property names are IANA tokens or X-names, optionally with parameters
+var contentLine = regexp.MustCompile(`^([A-Za-z0-9-]+(?:;[A-Za-z0-9-]+=[^:;]*)*):(.*)$`)
+
+// parse reads an iCalendar stream as RFC 5545 section 3.1 describes:
+// content lines end in CRLF and are unfolded at a CRLF followed by a
+// space or tab. Components must nest properly.
+func parse(data string) (*component, error) {
+	if !strings.HasSuffix(data, "\r\n") {
+		return nil, fmt.Errorf("stream does not end with CRLF")
+	}
+	unfolded := regexp.MustCompile("\r\n[ \t]").ReplaceAllString(data, "")
+
+	var stack []*component
+	var root *component
+	for i, l := range strings.Split(strings.TrimSuffix(unfolded, "\r\n"), "\r\n") {
+		m := contentLine.FindStringSubmatch(l)
+		if m == nil {
+			return nil, fmt.Errorf("line %d is not a content line: %q", i+1, l)
+		}
+		switch m[1] {
+		case "BEGIN":
+			c := &component{name: m[2]}
+			if len(stack) > 0 {
+				top := stack[len(stack)-1]
+				top.nested = append(top.nested, c)
+			} else if root != nil {
+				return nil, fmt.Errorf("line %d: second top-level component", i+1)
+			} else {
+				root = c
+			}
+			stack = append(stack, c)
+		case "END":
+			if len(stack) == 0 || stack[len(stack)-1].name != m[2] {
+				return nil, fmt.Errorf("line %d: END:%s does not close the open component", i+1, m[2])
+			}
+			stack = stack[:len(stack)-1]
+		default:
+			if len(stack) == 0 {
+				return nil, fmt.Errorf("line %d: property outside a component", i+1)
+			}
+			top := stack[len(stack)-1]
+			top.props = append(top.props, [2]string{m[1], m[2]})
+		}
+	}
+	if len(stack) > 0 {
+		return nil, fmt.Errorf("%s is not closed", stack[len(stack)-1].name)
+	}
+	if root == nil || root.name != "VCALENDAR" {
+		return nil, fmt.Errorf("stream is not a VCALENDAR")
+	}
+	return root, nil
+}
+
+var (
+	dateValue     = regexp.MustCompile(`^\d{8}$`)
+	dateTimeValue = regexp.MustCompile(`^\d{8}T\d{6}Z$`)
+	// TEXT values escape backslashes, semicolons, commas and newlines
+	textValue = regexp.MustCompile(`^(?:[^\\;,]|\\[\\;,nN])*$`)
+)
+
+// validate checks the properties RFC 5545 requires, and allows once, in a
+// calendar and its events, and the form of their values.
+func (c *component) validate() error {
+	for _, name := range []string{"VERSION", "PRODID"} {
+		if c.count(name) != 1 {
+			return fmt.Errorf("VCALENDAR has %d %s properties, want 1", c.count(name), name)
+		}
+	}
+	if c.value("VERSION") != "2.0" {
+		return fmt.Errorf("VERSION is %q", c.value("VERSION"))
+	}
+	uids := make(map[string]bool)
+	for i, e := range c.components("VEVENT") {
+		for _, name := range []string{"UID", "DTSTAMP", "DTSTART"} {
+			if e.count(name) != 1 {
+				return fmt.Errorf("event %d has %d %s properties, want 1", i, e.count(name), name)
+			}
+		}
+		for _, name := range []string{"DTEND", "SUMMARY", "DESCRIPTION", "STATUS", "LAST-MODIFIED", "TRANSP"} {
+			if e.count(name) > 1 {
+				return fmt.Errorf("event %d has more than one %s", i, name)
+			}
+		}
+		uid := e.value("UID")
+		if uids[uid] {
+			return fmt.Errorf("UID %q is not unique", uid)
+		}
+		uids[uid] = true
+
+		if !dateTimeValue.MatchString(e.value("DTSTAMP")) {
+			return fmt.Errorf("event %d: DTSTAMP %q is not a UTC date-time", i, e.value("DTSTAMP"))
+		}
+		start, end := e.value("DTSTART;VALUE=DATE"), e.value("DTEND;VALUE=DATE")
+		if !dateValue.MatchString(start) || !dateValue.MatchString(end) {
+			return fmt.Errorf("event %d: DTSTART %q or DTEND %q is not a date", i, start, end)
+		}
+		if end <= start {
+			return fmt.Errorf("event %d ends before it starts", i)
+		}
+		for _, name := range []string{"SUMMARY", "DESCRIPTION"} {
+			if !textValue.MatchString(e.value(name)) {
+				return fmt.Errorf("event %d: %s is not an escaped TEXT value: %q", i, name, e.value(name))
+			}
+		}
+		switch e.value("STATUS") {
+		case "", "TENTATIVE", "CONFIRMED", "CANCELLED":
+		default:
+			return fmt.Errorf("event %d: STATUS %q is not an event status", i, e.value("STATUS"))
+		}
+	}
+	return nil
+}
+
+func unescape(s string) string {
+	return strings.NewReplacer(`\\`, `\`, `\;`, ";", `\,`, ",", `\n`, "\n", `\N`, "\n").Replace(s)
+}
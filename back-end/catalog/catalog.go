@@ -0,0 +1,215 @@
+// catalog/catalog.go
+package catalog
+
+import "strings"
+
+// Muscle groups used across the catalog
+const (
+	Chest      = "chest"
+	Back       = "back"
+	Shoulders  = "shoulders"
+	Biceps     = "biceps"
+	Triceps    = "triceps"
+	Quads      = "quads"
+	Hamstrings = "hamstrings"
+	Glutes     = "glutes"
+	Calves     = "calves"
+	Core       = "core"
+)
+
+// Exercise kinds
+const (
+	KindCompound  = "compound"
+	KindIsolation = "isolation"
+	KindCardio    = "cardio"
+	KindMobility  = "mobility"
+	KindStretch   = "stretch"
+)
+
+type Exercise struct {
+	Name         string   `json:"name"`
+	Aliases      []string `json:"aliases,omitempty"`
+	Kind         string   `json:"kind"`
+	MuscleGroups []string `json:"muscleGroups"`
+	Equipment    []string `json:"equipment,omitempty"`
+}
+
+// Exercises is the built-in exercise catalog.
+var Exercises = []Exercise{
+	// Compound lifts
+	{Name: "Barbell Squat", Aliases: []string{"squat", "back squat", "squat (barbell)"}, Kind: KindCompound, MuscleGroups: []string{Quads, Glutes, Core}, Equipment: []string{"barbell"}},
+	{Name: "Front Squat", Aliases: []string{"front squat (barbell)"}, Kind: KindCompound, MuscleGroups: []string{Quads, Core}, Equipment: []string{"barbell"}},
+	{Name: "Goblet Squat", Kind: KindCompound, MuscleGroups: []string{Quads, Glutes}, Equipment: []string{"dumbbells", "kettlebell"}},
+	{Name: "Bodyweight Squats", Aliases: []string{"air squat", "bodyweight squat"}, Kind: KindCompound, MuscleGroups: []string{Quads, Glutes}},
+	{Name: "Deadlift", Aliases: []string{"conventional deadlift", "deadlift (barbell)"}, Kind: KindCompound, MuscleGroups: []string{Hamstrings, Glutes, Back}, Equipment: []string{"barbell"}},
+	{Name: "Romanian Deadlift", Aliases: []string{"rdl", "romanian deadlift (barbell)", "romanian deadlift (dumbbell)"}, Kind: KindCompound, MuscleGroups: []string{Hamstrings, Glutes}, Equipment: []string{"barbell", "dumbbells"}},
+	{Name: "Bench Press", Aliases: []string{"barbell bench press", "bench press (barbell)", "flat bench press"}, Kind: KindCompound, MuscleGroups: []string{Chest, Triceps, Shoulders}, Equipment: []string{"barbell", "bench"}},
+	{Name: "Dumbbell Bench Press", Aliases: []string{"bench press (dumbbell)"}, Kind: KindCompound, MuscleGroups: []string{Chest, Triceps, Shoulders}, Equipment: []string{"dumbbells", "bench"}},
+	{Name: "Incline Bench Press", Aliases: []string{"incline bench press (barbell)", "incline bench press (dumbbell)"}, Kind: KindCompound, MuscleGroups: []string{Chest, Shoulders}, Equipment: []string{"barbell", "dumbbells", "bench"}},
+	{Name: "Overhead Press", Aliases: []string{"ohp", "military press", "overhead press (barbell)", "shoulder press"}, Kind: KindCompound, MuscleGroups: []string{Shoulders, Triceps}, Equipment: []string{"barbell", "dumbbells"}},
+	{Name: "Barbell Row", Aliases: []string{"bent over row", "bent over row (barbell)"}, Kind: KindCompound, MuscleGroups: []string{Back, Biceps}, Equipment: []string{"barbell"}},
+	{Name: "Dumbbell Rows", Aliases: []string{"dumbbell row", "one arm dumbbell row"}, Kind: KindCompound, MuscleGroups: []string{Back, Biceps}, Equipment: []string{"dumbbells"}},
+	{Name: "Pull-ups", Aliases: []string{"pull up", "pullup", "chin up", "chin-ups"}, Kind: KindCompound, MuscleGroups: []string{Back, Biceps}, Equipment: []string{"pull-up bar"}},
+	{Name: "Lat Pulldown", Aliases: []string{"lat pulldown (cable)"}, Kind: KindCompound, MuscleGroups: []string{Back, Biceps}, Equipment: []string{"cable machine"}},
+	{Name: "Push-ups", Aliases: []string{"push up", "pushup", "press up"}, Kind: KindCompound, MuscleGroups: []string{Chest, Triceps, Core}},
+	{Name: "Dips", Aliases: []string{"dip", "chest dip", "triceps dip"}, Kind: KindCompound, MuscleGroups: []string{Chest, Triceps}},
+	{Name: "Walking Lunges", Aliases: []string{"lunge", "lunges", "walking lunge"}, Kind: KindCompound, MuscleGroups: []string{Quads, Glutes}},
+	{Name: "Bulgarian Split Squat", Aliases: []string{"split squat"}, Kind: KindCompound, MuscleGroups: []string{Quads, Glutes}, Equipment: []string{"dumbbells", "bench"}},
+	{Name: "Hip Thrust", Aliases: []string{"hip thrust (barbell)", "glute bridge"}, Kind: KindCompound, MuscleGroups: []string{Glutes, Hamstrings}, Equipment: []string{"barbell", "bench"}},
+	{Name: "Kettlebell Swing", Aliases: []string{"kb swing"}, Kind: KindCompound, MuscleGroups: []string{Glutes, Hamstrings, Core}, Equipment: []string{"kettlebell"}},
+	{Name: "Leg Press", Kind: KindCompound, MuscleGroups: []string{Quads, Glutes}, Equipment: []string{"leg press machine"}},
+
+	// Isolation
+	{Name: "Bicep Curls", Aliases: []string{"bicep curl", "biceps curl", "bicep curl (dumbbell)", "bicep curl (barbell)"}, Kind: KindIsolation, MuscleGroups: []string{Biceps}, Equipment: []string{"dumbbells", "barbell", "resistance bands"}},
+	{Name: "Triceps Extension", Aliases: []string{"tricep extension", "overhead tricep extension", "triceps pushdown", "tricep pushdown"}, Kind: KindIsolation, MuscleGroups: []string{Triceps}, Equipment: []string{"dumbbells", "cable machine"}},
+	{Name: "Lateral Raise", Aliases: []string{"lateral raises", "lateral raise (dumbbell)"}, Kind: KindIsolation, MuscleGroups: []string{Shoulders}, Equipment: []string{"dumbbells"}},
+	{Name: "Leg Curls", Aliases: []string{"leg curl", "lying leg curl", "seated leg curl"}, Kind: KindIsolation, MuscleGroups: []string{Hamstrings}, Equipment: []string{"leg curl machine"}},
+	{Name: "Leg Extension", Aliases: []string{"leg extensions"}, Kind: KindIsolation, MuscleGroups: []string{Quads}, Equipment: []string{"leg extension machine"}},
+	{Name: "Calf Raises", Aliases: []string{"calf raise", "standing calf raise"}, Kind: KindIsolation, MuscleGroups: []string{Calves}},
+	{Name: "Chest Fly", Aliases: []string{"dumbbell fly", "cable fly", "pec deck"}, Kind: KindIsolation, MuscleGroups: []string{Chest}, Equipment: []string{"dumbbells", "cable machine"}},
+	{Name: "Face Pull", Aliases: []string{"face pulls"}, Kind: KindIsolation, MuscleGroups: []string{Shoulders, Back}, Equipment: []string{"cable machine", "resistance bands"}},
+	{Name: "Plank", Aliases: []string{"front plank"}, Kind: KindIsolation, MuscleGroups: []string{Core}},
+	{Name: "Crunches", Aliases: []string{"crunch", "sit up", "sit-ups"}, Kind: KindIsolation, MuscleGroups: []string{Core}},
+	{Name: "Hanging Leg Raise", Aliases: []string{"leg raise", "leg raises"}, Kind: KindIsolation, MuscleGroups: []string{Core}, Equipment: []string{"pull-up bar"}},
+
+	// Cardio
+	{Name: "Jumping Jacks", Aliases: []string{"jumping jack"}, Kind: KindCardio, MuscleGroups: []string{Calves, Shoulders}},
+	{Name: "Burpees", Aliases: []string{"burpee"}, Kind: KindCardio, MuscleGroups: []string{Chest, Quads, Core}},
+	{Name: "Jump Rope", Aliases: []string{"skipping"}, Kind: KindCardio, MuscleGroups: []string{Calves}, Equipment: []string{"jump rope"}},
+	{Name: "Rowing Machine", Aliases: []string{"rowing", "row (machine)"}, Kind: KindCardio, MuscleGroups: []string{Back, Quads}, Equipment: []string{"rowing machine"}},
+	{Name: "Running", Aliases: []string{"run", "treadmill", "jog"}, Kind: KindCardio, MuscleGroups: []string{Quads, Calves}},
+	{Name: "Cycling", Aliases: []string{"bike", "stationary bike"}, Kind: KindCardio, MuscleGroups: []string{Quads}, Equipment: []string{"bike"}},
+
+	// Mobility drills used in warm-ups
+	{Name: "Arm Circles", Kind: KindMobility, MuscleGroups: []string{Shoulders}},
+	{Name: "Band Pull-Aparts", Kind: KindMobility, MuscleGroups: []string{Back, Shoulders}, Equipment: []string{"resistance bands"}},
+	{Name: "Scapular Push-ups", Kind: KindMobility, MuscleGroups: []string{Chest, Back}},
+	{Name: "Leg Swings", Kind: KindMobility, MuscleGroups: []string{Hamstrings, Glutes}},
+	{Name: "Bodyweight Good Mornings", Kind: KindMobility, MuscleGroups: []string{Hamstrings, Back}},
+	{Name: "World's Greatest Stretch", Kind: KindMobility, MuscleGroups: []string{Quads, Glutes, Hamstrings}},
+	{Name: "Ankle Rocks", Kind: KindMobility, MuscleGroups: []string{Calves}},
+	{Name: "Cat-Cow", Kind: KindMobility, MuscleGroups: []string{Core, Back}},
+	{Name: "Wrist and Elbow Circles", Kind: KindMobility, MuscleGroups: []string{Biceps, Triceps}},
+
+	// Stretches used in cool-downs
+	{Name: "Doorway Chest Stretch", Kind: KindStretch, MuscleGroups: []string{Chest}},
+	{Name: "Child's Pose", Kind: KindStretch, MuscleGroups: []string{Back}},
+	{Name: "Cross-Body Shoulder Stretch", Kind: KindStretch, MuscleGroups: []string{Shoulders}},
+	{Name: "Biceps Wall Stretch", Kind: KindStretch, MuscleGroups: []string{Biceps}},
+	{Name: "Overhead Triceps Stretch", Kind: KindStretch, MuscleGroups: []string{Triceps}},
+	{Name: "Couch Stretch", Kind: KindStretch, MuscleGroups: []string{Quads}},
+	{Name: "Seated Hamstring Stretch", Kind: KindStretch, MuscleGroups: []string{Hamstrings}},
+	{Name: "Pigeon Stretch", Kind: KindStretch, MuscleGroups: []string{Glutes}},
+	{Name: "Wall Calf Stretch", Kind: KindStretch, MuscleGroups: []string{Calves}},
+	{Name: "Cobra Stretch", Kind: KindStretch, MuscleGroups: []string{Core}},
+}
+
+// Normalize lower-cases a name and collapses punctuation and whitespace so
+// that "Push-Ups", "push ups" and "pushups " compare equal on lookup.
+func Normalize(name string) string {
+	var b strings.Builder
+	space := false
+	for _, c := range strings.ToLower(strings.TrimSpace(name)) {
+		switch {
+		case c >= 'a' && c <= 'z', c >= '0' && c <= '9', c == '(' || c == ')':
+			if space && b.Len() > 0 {
+				b.WriteByte(' ')
+			}
+			space = false
+			b.WriteRune(c)
+		case c == '\'':
+			// Drop apostrophes entirely ("World's" == "Worlds")
+		default:
+			space = true
+		}
+	}
+	return b.String()
+}
+
+var index = func() map[string]int {
+	m := make(map[string]int)
+	for i, e := range Exercises {
+		m[Normalize(e.Name)] = i
+		for _, a := range e.Aliases {
+			m[Normalize(a)] = i
+		}
+	}
+	return m
+}()
+
+// Lookup finds an exercise by its name or one of its aliases.
+func Lookup(name string) (Exercise, bool) {
+	i, ok := index[Normalize(name)]
+	if !ok {
+		return Exercise{}, false
+	}
+	return Exercises[i], true
+}
+
+// ByKindAndMuscle returns the exercises of a kind that train the muscle
+// group, in catalog order.
+func ByKindAndMuscle(kind, muscle string) []Exercise {
+	var out []Exercise
+	for _, e := range Exercises {
+		if e.Kind != kind {
+			continue
+		}
+		for _, m := range e.MuscleGroups {
+			if m == muscle {
+				out = append(out, e)
+				break
+			}
+		}
+	}
+	return out
+}
+
+// extraEquipment is equipment users may own that no catalog exercise
+// requires.
+var extraEquipment = []string{"yoga mat", "foam roller", "none"}
+
+var equipment = func() map[string]bool {
+	m := make(map[string]bool)
+	for _, e := range Exercises {
+		for _, item := range e.Equipment {
+			m[Normalize(item)] = true
+		}
+	}
+	for _, item := range extraEquipment {
+		m[Normalize(item)] = true
+	}
+	return m
+}()
+
+// IsKnownEquipment reports whether name is equipment the catalog knows,
+// ignoring case and punctuation.
+func IsKnownEquipment(name string) bool {
+	return equipment[Normalize(name)]
+}
+
+// MuscleGroups lists the muscle groups the catalog uses.
+var MuscleGroups = []string{Chest, Back, Shoulders, Biceps, Triceps, Quads, Hamstrings, Glutes, Calves, Core}
+
+// ByMuscle returns the exercises of any kind that train the muscle group,
+// in catalog order.
+func ByMuscle(muscle string) []Exercise {
+	var out []Exercise
+	for _, e := range Exercises {
+		for _, m := range e.MuscleGroups {
+			if m == muscle {
+				out = append(out, e)
+				break
+			}
+		}
+	}
+	return out
+}
+
+// Names returns the normalized name and aliases of the exercise.
+func (e Exercise) Names() []string {
+	names := []string{Normalize(e.Name)}
+	for _, a := range e.Aliases {
+		names = append(names, Normalize(a))
+	}
+	return names
+}
@@ -0,0 +1,23 @@
+-- Create workout_groups table
+CREATE TABLE IF NOT EXISTS workout_groups (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES user_profiles(id) ON DELETE CASCADE,
+    name VARCHAR(255) NOT NULL DEFAULT '',
+    type VARCHAR(20) NOT NULL CHECK (type IN ('superset', 'circuit', 'hiit', 'emom', 'tabata')),
+    rounds INTEGER NOT NULL DEFAULT 1,
+    work_seconds INTEGER NOT NULL DEFAULT 0,
+    rest_seconds INTEGER NOT NULL DEFAULT 0,
+    round_rest_seconds INTEGER NOT NULL DEFAULT 0,
+    position INTEGER NOT NULL DEFAULT 0,
+    completed_rounds INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Link workout tasks to an optional group, ordered within it
+ALTER TABLE workout_tasks ADD COLUMN IF NOT EXISTS group_id INTEGER REFERENCES workout_groups(id) ON DELETE SET NULL;
+ALTER TABLE workout_tasks ADD COLUMN IF NOT EXISTS group_position INTEGER NOT NULL DEFAULT 0;
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_workout_groups_user_id ON workout_groups(user_id, position);
+CREATE INDEX IF NOT EXISTS idx_workout_tasks_group_id ON workout_tasks(group_id, group_position);
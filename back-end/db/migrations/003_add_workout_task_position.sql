@@ -0,0 +1,17 @@
+-- Explicit, stable ordering of workout tasks per user
+ALTER TABLE workout_tasks ADD COLUMN IF NOT EXISTS position INTEGER NOT NULL DEFAULT 0;
+
+-- Backfill positions from creation order for users whose tasks have never
+-- been ordered; users with any non-zero position are left untouched
+UPDATE workout_tasks t
+SET position = o.rn
+FROM (
+    SELECT id, ROW_NUMBER() OVER (PARTITION BY user_id ORDER BY created_at, id) - 1 AS rn
+    FROM workout_tasks
+) o
+WHERE t.id = o.id
+  AND NOT EXISTS (
+      SELECT 1 FROM workout_tasks x WHERE x.user_id = t.user_id AND x.position <> 0
+  );
+
+CREATE INDEX IF NOT EXISTS idx_workout_tasks_user_position ON workout_tasks(user_id, position);
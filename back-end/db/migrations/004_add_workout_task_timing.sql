@@ -0,0 +1,3 @@
+-- Per-task timing used for session duration estimates
+ALTER TABLE workout_tasks ADD COLUMN IF NOT EXISTS rest_seconds INTEGER NOT NULL DEFAULT 0;
+ALTER TABLE workout_tasks ADD COLUMN IF NOT EXISTS tempo VARCHAR(20) NOT NULL DEFAULT '';
@@ -0,0 +1,5 @@
+-- Distinguish generated warm-up and cool-down work from the session itself
+ALTER TABLE workout_tasks ADD COLUMN IF NOT EXISTS category VARCHAR(20) NOT NULL DEFAULT 'exercise'
+    CHECK (category IN ('exercise', 'warmup', 'cooldown'));
+
+CREATE INDEX IF NOT EXISTS idx_workout_tasks_user_category ON workout_tasks(user_id, category);
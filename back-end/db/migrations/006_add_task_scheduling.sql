@@ -0,0 +1,22 @@
+-- Scheduled date and optional RRULE recurrence for workout tasks
+ALTER TABLE workout_tasks ADD COLUMN IF NOT EXISTS scheduled_for DATE;
+ALTER TABLE workout_tasks ADD COLUMN IF NOT EXISTS recurrence TEXT NOT NULL DEFAULT '';
+
+-- Materialized occurrences of scheduled tasks. occurs_on is the date the
+-- schedule produced and scheduled_for the date the occurrence is due.
+CREATE TABLE IF NOT EXISTS task_occurrences (
+    id SERIAL PRIMARY KEY,
+    task_id INTEGER NOT NULL REFERENCES workout_tasks(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES user_profiles(id) ON DELETE CASCADE,
+    occurs_on DATE NOT NULL,
+    scheduled_for DATE NOT NULL,
+    status VARCHAR(20) NOT NULL DEFAULT 'scheduled' CHECK (status IN ('scheduled', 'completed', 'skipped')),
+    completed_at TIMESTAMP WITH TIME ZONE,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (task_id, occurs_on)
+);
+
+-- Create indexes
+CREATE INDEX IF NOT EXISTS idx_workout_tasks_scheduled_for ON workout_tasks(user_id, scheduled_for);
+CREATE INDEX IF NOT EXISTS idx_task_occurrences_user_date ON task_occurrences(user_id, scheduled_for);
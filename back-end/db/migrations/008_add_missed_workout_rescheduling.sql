@@ -0,0 +1,20 @@
+-- Per-user policy for sessions whose day passed without being completed
+ALTER TABLE user_profiles ADD COLUMN IF NOT EXISTS missed_workout_policy VARCHAR(20) NOT NULL DEFAULT 'skip'
+    CHECK (missed_workout_policy IN ('skip', 'push', 'merge'));
+ALTER TABLE user_profiles ADD COLUMN IF NOT EXISTS merge_cap_minutes INTEGER NOT NULL DEFAULT 0;
+
+-- Record of every occurrence the rescheduler skipped or moved
+CREATE TABLE IF NOT EXISTS reschedule_events (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES user_profiles(id) ON DELETE CASCADE,
+    occurrence_id INTEGER NOT NULL REFERENCES task_occurrences(id) ON DELETE CASCADE,
+    task_id INTEGER NOT NULL REFERENCES workout_tasks(id) ON DELETE CASCADE,
+    policy VARCHAR(20) NOT NULL,
+    action VARCHAR(20) NOT NULL CHECK (action IN ('skipped', 'moved')),
+    from_date DATE NOT NULL,
+    to_date DATE,
+    reason TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_reschedule_events_user_id ON reschedule_events(user_id, created_at);
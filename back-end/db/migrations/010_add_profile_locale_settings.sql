@@ -0,0 +1,8 @@
+-- Per-user timezone, week start, locale and unit system. Dates such as
+-- "today" and week boundaries are computed in the user's timezone.
+ALTER TABLE user_profiles ADD COLUMN IF NOT EXISTS timezone VARCHAR(64) NOT NULL DEFAULT 'UTC';
+ALTER TABLE user_profiles ADD COLUMN IF NOT EXISTS week_start VARCHAR(10) NOT NULL DEFAULT 'monday'
+    CHECK (week_start IN ('sunday', 'monday', 'saturday'));
+ALTER TABLE user_profiles ADD COLUMN IF NOT EXISTS locale VARCHAR(35) NOT NULL DEFAULT 'en-US';
+ALTER TABLE user_profiles ADD COLUMN IF NOT EXISTS unit_system VARCHAR(10) NOT NULL DEFAULT 'metric'
+    CHECK (unit_system IN ('metric', 'imperial'));
@@ -0,0 +1,95 @@
+-- Delta sync: every write to a synced table takes the next value of a
+-- global sequence and bumps the row's version, and every delete leaves a
+-- tombstone. Clients pull changes with a sequence value as their cursor.
+CREATE SEQUENCE IF NOT EXISTS sync_seq;
+
+ALTER TABLE user_profiles ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE user_profiles ADD COLUMN IF NOT EXISTS sync_seq BIGINT;
+ALTER TABLE workout_tasks ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE workout_tasks ADD COLUMN IF NOT EXISTS sync_seq BIGINT;
+ALTER TABLE workout_groups ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE workout_groups ADD COLUMN IF NOT EXISTS sync_seq BIGINT;
+ALTER TABLE task_occurrences ADD COLUMN IF NOT EXISTS version INTEGER NOT NULL DEFAULT 1;
+ALTER TABLE task_occurrences ADD COLUMN IF NOT EXISTS sync_seq BIGINT;
+
+-- Backfill existing rows; only rows written before the triggers existed
+-- have no sequence value
+UPDATE user_profiles SET sync_seq = nextval('sync_seq') WHERE sync_seq IS NULL;
+UPDATE workout_tasks SET sync_seq = nextval('sync_seq') WHERE sync_seq IS NULL;
+UPDATE workout_groups SET sync_seq = nextval('sync_seq') WHERE sync_seq IS NULL;
+UPDATE task_occurrences SET sync_seq = nextval('sync_seq') WHERE sync_seq IS NULL;
+
+CREATE INDEX IF NOT EXISTS idx_user_profiles_sync ON user_profiles(id, sync_seq);
+CREATE INDEX IF NOT EXISTS idx_workout_tasks_sync ON workout_tasks(user_id, sync_seq);
+CREATE INDEX IF NOT EXISTS idx_workout_groups_sync ON workout_groups(user_id, sync_seq);
+CREATE INDEX IF NOT EXISTS idx_task_occurrences_sync ON task_occurrences(user_id, sync_seq);
+
+CREATE TABLE IF NOT EXISTS sync_tombstones (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL,
+    entity VARCHAR(20) NOT NULL,
+    entity_id INTEGER NOT NULL,
+    sync_seq BIGINT NOT NULL,
+    deleted_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_sync_tombstones_user_id ON sync_tombstones(user_id, sync_seq);
+
+-- Writers hold a shared advisory lock on (7001, profile id) until they
+-- commit. A sync read takes it exclusively before reading the sequence, so
+-- a cursor never moves past a change that is still being committed.
+CREATE OR REPLACE FUNCTION sync_track() RETURNS trigger AS $$
+DECLARE
+    owner INTEGER;
+BEGIN
+    IF TG_TABLE_NAME = 'user_profiles' THEN
+        IF TG_OP = 'DELETE' THEN owner := OLD.id; ELSE owner := NEW.id; END IF;
+    ELSIF TG_OP = 'DELETE' THEN
+        owner := OLD.user_id;
+    ELSE
+        owner := NEW.user_id;
+    END IF;
+
+    PERFORM pg_advisory_xact_lock_shared(7001, owner);
+
+    IF TG_OP = 'DELETE' THEN
+        INSERT INTO sync_tombstones (user_id, entity, entity_id, sync_seq)
+        VALUES (owner, TG_ARGV[0], OLD.id, nextval('sync_seq'));
+        RETURN OLD;
+    END IF;
+
+    IF TG_OP = 'UPDATE' THEN
+        NEW.version := OLD.version + 1;
+    END IF;
+    NEW.sync_seq := nextval('sync_seq');
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS user_profiles_sync_write ON user_profiles;
+CREATE TRIGGER user_profiles_sync_write BEFORE INSERT OR UPDATE ON user_profiles
+    FOR EACH ROW EXECUTE FUNCTION sync_track('profile');
+DROP TRIGGER IF EXISTS user_profiles_sync_delete ON user_profiles;
+CREATE TRIGGER user_profiles_sync_delete AFTER DELETE ON user_profiles
+    FOR EACH ROW EXECUTE FUNCTION sync_track('profile');
+
+DROP TRIGGER IF EXISTS workout_tasks_sync_write ON workout_tasks;
+CREATE TRIGGER workout_tasks_sync_write BEFORE INSERT OR UPDATE ON workout_tasks
+    FOR EACH ROW EXECUTE FUNCTION sync_track('task');
+DROP TRIGGER IF EXISTS workout_tasks_sync_delete ON workout_tasks;
+CREATE TRIGGER workout_tasks_sync_delete AFTER DELETE ON workout_tasks
+    FOR EACH ROW EXECUTE FUNCTION sync_track('task');
+
+DROP TRIGGER IF EXISTS workout_groups_sync_write ON workout_groups;
+CREATE TRIGGER workout_groups_sync_write BEFORE INSERT OR UPDATE ON workout_groups
+    FOR EACH ROW EXECUTE FUNCTION sync_track('group');
+DROP TRIGGER IF EXISTS workout_groups_sync_delete ON workout_groups;
+CREATE TRIGGER workout_groups_sync_delete AFTER DELETE ON workout_groups
+    FOR EACH ROW EXECUTE FUNCTION sync_track('group');
+
+DROP TRIGGER IF EXISTS task_occurrences_sync_write ON task_occurrences;
+CREATE TRIGGER task_occurrences_sync_write BEFORE INSERT OR UPDATE ON task_occurrences
+    FOR EACH ROW EXECUTE FUNCTION sync_track('occurrence');
+DROP TRIGGER IF EXISTS task_occurrences_sync_delete ON task_occurrences;
+CREATE TRIGGER task_occurrences_sync_delete AFTER DELETE ON task_occurrences
+    FOR EACH ROW EXECUTE FUNCTION sync_track('occurrence');
@@ -0,0 +1,8 @@
+-- Full-text search over task names and descriptions. The column is
+-- generated, so writers never have to keep it up to date.
+ALTER TABLE workout_tasks ADD COLUMN IF NOT EXISTS search_vector tsvector
+    GENERATED ALWAYS AS (
+        to_tsvector('english', coalesce(name, '') || ' ' || coalesce(description, ''))
+    ) STORED;
+
+CREATE INDEX IF NOT EXISTS idx_workout_tasks_search ON workout_tasks USING GIN (search_vector);
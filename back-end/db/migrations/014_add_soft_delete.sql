@@ -0,0 +1,36 @@
+-- Deleted profiles and tasks stay in the trash with deleted_at set until
+-- they are restored or purged after the retention period.
+ALTER TABLE user_profiles ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE;
+ALTER TABLE workout_tasks ADD COLUMN IF NOT EXISTS deleted_at TIMESTAMP WITH TIME ZONE;
+
+CREATE INDEX IF NOT EXISTS idx_user_profiles_deleted_at ON user_profiles(deleted_at) WHERE deleted_at IS NOT NULL;
+CREATE INDEX IF NOT EXISTS idx_workout_tasks_deleted_at ON workout_tasks(user_id, deleted_at) WHERE deleted_at IS NOT NULL;
+
+-- Syncing clients see a trashed record as deleted. Moving it to the trash
+-- leaves a tombstone after the row's own change; a restore is an ordinary
+-- update that brings the record back.
+CREATE OR REPLACE FUNCTION sync_soft_delete() RETURNS trigger AS $$
+DECLARE
+    owner INTEGER;
+BEGIN
+    IF TG_TABLE_NAME = 'user_profiles' THEN
+        owner := NEW.id;
+    ELSE
+        owner := NEW.user_id;
+    END IF;
+
+    INSERT INTO sync_tombstones (user_id, entity, entity_id, sync_seq)
+    VALUES (owner, TG_ARGV[0], NEW.id, nextval('sync_seq'));
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS user_profiles_sync_soft_delete ON user_profiles;
+CREATE TRIGGER user_profiles_sync_soft_delete AFTER UPDATE OF deleted_at ON user_profiles
+    FOR EACH ROW WHEN (OLD.deleted_at IS NULL AND NEW.deleted_at IS NOT NULL)
+    EXECUTE FUNCTION sync_soft_delete('profile');
+
+DROP TRIGGER IF EXISTS workout_tasks_sync_soft_delete ON workout_tasks;
+CREATE TRIGGER workout_tasks_sync_soft_delete AFTER UPDATE OF deleted_at ON workout_tasks
+    FOR EACH ROW WHEN (OLD.deleted_at IS NULL AND NEW.deleted_at IS NOT NULL)
+    EXECUTE FUNCTION sync_soft_delete('task');
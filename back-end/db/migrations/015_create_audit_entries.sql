@@ -0,0 +1,32 @@
+-- Every write to a profile or task leaves an audit entry: who made it, the
+-- record before and after, the fields that changed and the request it came
+-- from. Entries outlive the records they describe.
+CREATE TABLE IF NOT EXISTS audit_entries (
+    id BIGSERIAL PRIMARY KEY,
+    actor_type VARCHAR(20) NOT NULL,
+    actor_id VARCHAR(255),
+    action VARCHAR(20) NOT NULL,
+    entity VARCHAR(20) NOT NULL,
+    entity_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL,
+    version INTEGER NOT NULL DEFAULT 0,
+    before JSONB,
+    after JSONB,
+    changes JSONB,
+    request_id VARCHAR(128),
+    created_at TIMESTAMP WITH TIME ZONE DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_entries_entity ON audit_entries(entity, entity_id, id);
+CREATE INDEX IF NOT EXISTS idx_audit_entries_user_id ON audit_entries(user_id, id);
+
+-- The log is append-only
+CREATE OR REPLACE FUNCTION audit_entries_append_only() RETURNS trigger AS $$
+BEGIN
+    RAISE EXCEPTION 'audit entries cannot be changed or deleted';
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS audit_entries_append_only ON audit_entries;
+CREATE TRIGGER audit_entries_append_only BEFORE UPDATE OR DELETE ON audit_entries
+    FOR EACH ROW EXECUTE FUNCTION audit_entries_append_only();
@@ -0,0 +1,46 @@
+-- Archives of a user's data, built in the background on request and
+-- downloadable through a signed link until expires_at.
+CREATE TABLE IF NOT EXISTS data_exports (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES user_profiles(id) ON DELETE CASCADE,
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    archive BYTEA,
+    error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    completed_at TIMESTAMP WITH TIME ZONE,
+    expires_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_data_exports_user_id ON data_exports(user_id, id);
+CREATE INDEX IF NOT EXISTS idx_data_exports_pending ON data_exports(id) WHERE status = 'pending';
+
+-- Account erasures and the steps they have completed. A step that fails
+-- is retried until every step is done; the row itself holds no personal
+-- data beyond the auth user id it was requested for.
+CREATE TABLE IF NOT EXISTS account_erasures (
+    id SERIAL PRIMARY KEY,
+    auth_user_id VARCHAR(255) NOT NULL UNIQUE,
+    data_erased_at TIMESTAMP WITH TIME ZONE,
+    auth_user_deleted_at TIMESTAMP WITH TIME ZONE,
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    completed_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_account_erasures_pending ON account_erasures(id) WHERE completed_at IS NULL;
+
+-- Audit entries stay append-only except while an account is erased: the
+-- erasing transaction sets app.erasing and may then delete the user's
+-- entries and clear their id from entries about others.
+CREATE OR REPLACE FUNCTION audit_entries_append_only() RETURNS trigger AS $$
+BEGIN
+    IF current_setting('app.erasing', true) = 'on' THEN
+        IF TG_OP = 'DELETE' THEN
+            RETURN OLD;
+        END IF;
+        RETURN NEW;
+    END IF;
+    RAISE EXCEPTION 'audit entries cannot be changed or deleted';
+END;
+$$ LANGUAGE plpgsql;
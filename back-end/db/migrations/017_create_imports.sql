@@ -0,0 +1,26 @@
+-- Workout log files imported from other apps. The file is kept until the
+-- import completes so an import interrupted by a restart can resume after
+-- the last task it processed.
+CREATE TABLE IF NOT EXISTS imports (
+    id SERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES user_profiles(id) ON DELETE CASCADE,
+    format VARCHAR(20) NOT NULL,
+    weight_unit VARCHAR(10) NOT NULL DEFAULT '',
+    status VARCHAR(20) NOT NULL DEFAULT 'pending',
+    source BYTEA,
+    total INTEGER NOT NULL DEFAULT 0,
+    processed INTEGER NOT NULL DEFAULT 0,
+    created INTEGER NOT NULL DEFAULT 0,
+    skipped INTEGER NOT NULL DEFAULT 0,
+    errors JSONB,
+    failure TEXT NOT NULL DEFAULT '',
+    actor_type VARCHAR(20) NOT NULL,
+    actor_id VARCHAR(255) NOT NULL DEFAULT '',
+    request_id VARCHAR(128) NOT NULL DEFAULT '',
+    created_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP WITH TIME ZONE NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    completed_at TIMESTAMP WITH TIME ZONE
+);
+
+CREATE INDEX IF NOT EXISTS idx_imports_user_id ON imports(user_id, id);
+CREATE INDEX IF NOT EXISTS idx_imports_unfinished ON imports(id) WHERE status IN ('pending', 'running');
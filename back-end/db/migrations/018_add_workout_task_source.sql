@@ -0,0 +1,8 @@
+-- Tasks imported from another app's workout log are history rather than
+-- part of the plan. Plan, session and reorder queries leave them out, and
+-- they stay out of the plan's position sequence.
+ALTER TABLE workout_tasks ADD COLUMN IF NOT EXISTS source VARCHAR(20) NOT NULL DEFAULT 'plan'
+    CHECK (source IN ('plan', 'import'));
+
+DROP INDEX IF EXISTS idx_workout_tasks_user_position;
+CREATE INDEX IF NOT EXISTS idx_workout_tasks_plan_position ON workout_tasks(user_id, position) WHERE source = 'plan';
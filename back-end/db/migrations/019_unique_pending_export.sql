@@ -0,0 +1,8 @@
+-- A user has at most one pending export, so concurrent requests share it
+-- instead of building the same archive twice. Duplicates left by earlier
+-- requests are failed, keeping the oldest, before the index is built.
+UPDATE data_exports SET status = 'failed', error = 'Superseded by another pending export', completed_at = CURRENT_TIMESTAMP
+WHERE status = 'pending'
+  AND id NOT IN (SELECT MIN(id) FROM data_exports WHERE status = 'pending' GROUP BY user_id);
+
+CREATE UNIQUE INDEX IF NOT EXISTS idx_data_exports_user_pending ON data_exports(user_id) WHERE status = 'pending';
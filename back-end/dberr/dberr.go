@@ -0,0 +1,82 @@
+// dberr/dberr.go
+package dberr
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+
+	"github.com/go-pg/pg/v10"
+)
+
+// Kind is the kind of integrity constraint a write violated.
+type Kind int
+
+const (
+	Unique Kind = iota + 1
+	// ForeignKey is a write referencing a row that does not exist
+	ForeignKey
+	// Referenced is a delete of a row other rows still reference
+	Referenced
+	Check
+	NotNull
+)
+
+// SQLSTATE codes of the integrity violations clients can cause
+var kinds = map[string]Kind{
+	"23505": Unique,
+	"23503": ForeignKey,
+	"23514": Check,
+	"23502": NotNull,
+}
+
+// keyColumns matches the column list in messages such as
+// "Key (user_id)=(abc) already exists."
+var keyColumns = regexp.MustCompile(`^Key \(([^)]+)\)=`)
+
+// Violation describes a constraint violated by a write.
+type Violation struct {
+	Kind       Kind
+	Table      string
+	Constraint string
+	// Columns involved, when Postgres reports them or they can be read
+	// from the constraint name
+	Columns []string
+}
+
+// Classify returns the constraint violation behind err, or nil if err is
+// not one.
+func Classify(err error) *Violation {
+	var pgErr pg.Error
+	if !errors.As(err, &pgErr) {
+		return nil
+	}
+	kind, ok := kinds[pgErr.Field('C')]
+	if !ok {
+		return nil
+	}
+
+	v := &Violation{
+		Kind:       kind,
+		Table:      pgErr.Field('t'),
+		Constraint: pgErr.Field('n'),
+	}
+	switch {
+	case pgErr.Field('c') != "":
+		v.Columns = []string{pgErr.Field('c')}
+	case keyColumns.MatchString(pgErr.Field('D')):
+		for _, column := range strings.Split(keyColumns.FindStringSubmatch(pgErr.Field('D'))[1], ",") {
+			v.Columns = append(v.Columns, strings.TrimSpace(column))
+		}
+	case kind == Check:
+		// Column checks are named <table>_<column>_check by default
+		column, ok := strings.CutPrefix(v.Constraint, v.Table+"_")
+		if column, ok2 := strings.CutSuffix(column, "_check"); ok && ok2 && column != "" {
+			v.Columns = []string{column}
+		}
+	}
+	if kind == ForeignKey && strings.Contains(pgErr.Field('D'), "is still referenced") {
+		v.Kind = Referenced
+	}
+	return v
+}
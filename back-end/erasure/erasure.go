@@ -0,0 +1,151 @@
+// erasure/erasure.go
+package erasure
+
+import (
+	"context"
+	"time"
+
+	"back-end/authadmin"
+	"back-end/models"
+
+	"github.com/go-pg/pg/v10"
+	"go.uber.org/zap"
+)
+
+// Steps of an erasure, in the order they run
+const (
+	StepData     = "data"
+	StepAuthUser = "auth_user"
+)
+
+// Request returns the erasure of the auth user's account, creating it on
+// the first request. Asking again for an account being erased returns the
+// same erasure, so the steps it completed are not repeated.
+func Request(ctx context.Context, db *pg.DB, authUserID string) (*models.AccountErasure, error) {
+	e := &models.AccountErasure{AuthUserID: authUserID, CreatedAt: time.Now()}
+	_, err := db.ModelContext(ctx, e).OnConflict("(auth_user_id) DO NOTHING").Insert()
+	if err != nil {
+		return nil, err
+	}
+	err = db.ModelContext(ctx, e).Where("auth_user_id = ?", authUserID).Select()
+	return e, err
+}
+
+// Run carries out the steps of e that have not completed yet. Each step is
+// logged and its completion stored on e before the next one starts; when a
+// step fails the error is stored instead and Run can be called again.
+func Run(ctx context.Context, db *pg.DB, admin authadmin.Client, logger *zap.Logger, e *models.AccountErasure) error {
+	return run(ctx, pgStore{db}, admin, logger, e)
+}
+
+// store is where Run erases data and keeps track of erasures, so tests
+// can run the steps without a database.
+type store interface {
+	eraseData(ctx context.Context, authUserID string) error
+	save(ctx context.Context, e *models.AccountErasure) error
+}
+
+type pgStore struct {
+	db *pg.DB
+}
+
+func (s pgStore) eraseData(ctx context.Context, authUserID string) error {
+	return eraseData(ctx, s.db, authUserID)
+}
+
+func (s pgStore) save(ctx context.Context, e *models.AccountErasure) error {
+	_, err := s.db.ModelContext(ctx, e).WherePK().Update()
+	return err
+}
+
+func run(ctx context.Context, s store, admin authadmin.Client, logger *zap.Logger, e *models.AccountErasure) error {
+	if e.Done() {
+		return nil
+	}
+	logger = logger.With(zap.Int("erasure_id", e.ID))
+	e.Attempts++
+
+	err := step(logger, StepData, &e.DataErasedAt, func() error {
+		return s.eraseData(ctx, e.AuthUserID)
+	})
+	if err == nil {
+		err = step(logger, StepAuthUser, &e.AuthUserDeletedAt, func() error {
+			return admin.DeleteUser(ctx, e.AuthUserID)
+		})
+	}
+
+	e.LastError = ""
+	if err != nil {
+		e.LastError = err.Error()
+	} else {
+		now := time.Now()
+		e.CompletedAt = &now
+		logger.Info("Account erased")
+	}
+	if saveErr := s.save(ctx, e); saveErr != nil {
+		return saveErr
+	}
+	return err
+}
+
+// step runs fn unless the step already completed, recording when it did.
+func step(logger *zap.Logger, name string, done **time.Time, fn func() error) error {
+	if *done != nil {
+		return nil
+	}
+	if err := fn(); err != nil {
+		logger.Error("Account erasure step failed", zap.String("step", name), zap.Error(err))
+		return err
+	}
+	now := time.Now()
+	*done = &now
+	logger.Info("Account erasure step completed", zap.String("step", name))
+	return nil
+}
+
+// eraseData deletes every row about the user in one transaction. Deleting
+// the profile, trashed or not, removes its tasks, groups, sessions,
+// pauses, exports and calendar token through the foreign keys. Stored
+// idempotent responses are scoped by the auth user ID and go too.
+func eraseData(ctx context.Context, db *pg.DB, authUserID string) error {
+	return db.RunInTransaction(ctx, func(tx *pg.Tx) error {
+		// Lets this transaction delete from the append-only audit log
+		if _, err := tx.Exec("SET LOCAL app.erasing = 'on'"); err != nil {
+			return err
+		}
+
+		var profile models.UserProfile
+		err := tx.Model(&profile).AllWithDeleted().Where("user_id = ?", authUserID).Select()
+		if err != nil && err != pg.ErrNoRows {
+			return err
+		}
+		if err == nil {
+			if _, err := tx.Model(&profile).WherePK().ForceDelete(); err != nil {
+				return err
+			}
+			_, err = tx.Model((*models.AuditEntry)(nil)).Where("user_id = ?", profile.ID).Delete()
+			if err != nil {
+				return err
+			}
+			// The deletes above leave tombstones for syncing clients
+			_, err = tx.Model((*models.SyncTombstone)(nil)).Where("user_id = ?", profile.ID).Delete()
+			if err != nil {
+				return err
+			}
+		}
+
+		// Stored responses hold the user's data as it was when they were sent
+		_, err = tx.Model((*models.IdempotencyKey)(nil)).Where("scope = ?", authUserID).Delete()
+		if err != nil {
+			return err
+		}
+
+		// Changes the user made to other accounts, as a coach or admin,
+		// stay in those accounts' history without naming them
+		_, err = tx.Model((*models.AuditEntry)(nil)).
+			Set("actor_id = NULL").
+			Where("actor_id = ?", authUserID).
+			Update()
+		return err
+	})
+}
@@ -0,0 +1,127 @@
+package erasure
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"back-end/authadmin"
+	"back-end/models"
+
+	"go.uber.org/zap"
+)
+
+// fakeStore records the calls Run makes instead of touching a database.
+type fakeStore struct {
+	eraseErr error
+	erased   []string
+	saved    []models.AccountErasure
+}
+
+func (s *fakeStore) eraseData(ctx context.Context, authUserID string) error {
+	if s.eraseErr != nil {
+		return s.eraseErr
+	}
+	s.erased = append(s.erased, authUserID)
+	return nil
+}
+
+func (s *fakeStore) save(ctx context.Context, e *models.AccountErasure) error {
+	s.saved = append(s.saved, *e)
+	return nil
+}
+
+const authUserID = "6f1c2f4e-8a5b-4c3d-9e2f-1a2b3c4d5e6f"
+
+func TestRunKeepsDataStepWhenDeleteUserFails(t *testing.T) {
+	s := &fakeStore{}
+	admin := &authadmin.Fake{Err: errors.New("auth provider unavailable")}
+	e := &models.AccountErasure{ID: 1, AuthUserID: authUserID}
+
+	if err := run(context.Background(), s, admin, zap.NewNop(), e); err == nil {
+		t.Fatal("Run succeeded although DeleteUser failed")
+	}
+	if e.DataErasedAt == nil {
+		t.Error("DataErasedAt is not set after the data was erased")
+	}
+	if e.AuthUserDeletedAt != nil || e.CompletedAt != nil {
+		t.Error("erasure is marked done although DeleteUser failed")
+	}
+	if e.LastError != "auth provider unavailable" {
+		t.Errorf("LastError = %q", e.LastError)
+	}
+	if len(s.saved) != 1 || s.saved[0].DataErasedAt == nil {
+		t.Fatalf("saved %+v, want the erasure with its data step", s.saved)
+	}
+}
+
+func TestRunResumesAtAuthUser(t *testing.T) {
+	s := &fakeStore{}
+	admin := &authadmin.Fake{Err: errors.New("auth provider unavailable")}
+	e := &models.AccountErasure{ID: 1, AuthUserID: authUserID}
+	run(context.Background(), s, admin, zap.NewNop(), e)
+	dataErasedAt := *e.DataErasedAt
+
+	admin.Err = nil
+	if err := run(context.Background(), s, admin, zap.NewNop(), e); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if len(s.erased) != 1 {
+		t.Errorf("data was erased %d times, want once", len(s.erased))
+	}
+	if !e.DataErasedAt.Equal(dataErasedAt) {
+		t.Error("DataErasedAt changed on the rerun")
+	}
+	if got := admin.Deleted(); len(got) != 1 || got[0] != authUserID {
+		t.Errorf("deleted auth users %v, want [%s]", got, authUserID)
+	}
+	if e.AuthUserDeletedAt == nil || !e.Done() {
+		t.Error("erasure is not done after the rerun")
+	}
+	if e.LastError != "" {
+		t.Errorf("LastError = %q after success", e.LastError)
+	}
+	if e.Attempts != 2 {
+		t.Errorf("Attempts = %d, want 2", e.Attempts)
+	}
+}
+
+func TestRunStopsWhenDataStepFails(t *testing.T) {
+	s := &fakeStore{eraseErr: errors.New("connection reset")}
+	admin := &authadmin.Fake{}
+	e := &models.AccountErasure{ID: 1, AuthUserID: authUserID}
+
+	if err := run(context.Background(), s, admin, zap.NewNop(), e); err == nil {
+		t.Fatal("Run succeeded although erasing the data failed")
+	}
+	if e.DataErasedAt != nil {
+		t.Error("DataErasedAt is set although erasing the data failed")
+	}
+	if len(admin.Deleted()) != 0 {
+		t.Error("auth user was deleted before the data was erased")
+	}
+}
+
+func TestRunCompletedErasureIsNoop(t *testing.T) {
+	s := &fakeStore{}
+	admin := &authadmin.Fake{}
+	e := &models.AccountErasure{ID: 1, AuthUserID: authUserID}
+	if err := run(context.Background(), s, admin, zap.NewNop(), e); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	done := *e
+	saves := len(s.saved)
+
+	if err := run(context.Background(), s, admin, zap.NewNop(), e); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	if len(s.erased) != 1 || len(admin.Deleted()) != 1 {
+		t.Errorf("steps ran again: %d data erasures, %d auth user deletions", len(s.erased), len(admin.Deleted()))
+	}
+	if len(s.saved) != saves {
+		t.Error("completed erasure was saved again")
+	}
+	if e.Attempts != done.Attempts || !e.CompletedAt.Equal(*done.CompletedAt) {
+		t.Errorf("completed erasure changed: %+v, was %+v", *e, done)
+	}
+}
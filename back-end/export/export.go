@@ -0,0 +1,211 @@
+// export/export.go
+package export
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/csv"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"back-end/models"
+	"back-end/schedule"
+
+	"github.com/go-pg/pg/v10/orm"
+)
+
+// metricsDays is the adherence window of the exported metrics.
+const metricsDays = 365
+
+// Build returns a zip archive of everything stored about the profile: the
+// profile, its tasks including trashed ones, groups, scheduled sessions,
+// pauses, reschedules, metrics and change history. Every file is JSON; the
+// tabular ones are repeated as CSV for spreadsheets.
+func Build(db orm.DB, profile *models.UserProfile) ([]byte, error) {
+	var tasks []models.WorkoutTask
+	if err := db.Model(&tasks).AllWithDeleted().Where("user_id = ?", profile.ID).Order("id ASC").Select(); err != nil {
+		return nil, err
+	}
+	var groups []models.WorkoutGroup
+	if err := db.Model(&groups).Where("user_id = ?", profile.ID).Order("id ASC").Select(); err != nil {
+		return nil, err
+	}
+	var occurrences []models.TaskOccurrence
+	if err := db.Model(&occurrences).Where("user_id = ?", profile.ID).Order("occurs_on ASC", "id ASC").Select(); err != nil {
+		return nil, err
+	}
+	var pauses []models.PlanPause
+	if err := db.Model(&pauses).Where("user_id = ?", profile.ID).Order("start_date ASC").Select(); err != nil {
+		return nil, err
+	}
+	var reschedules []models.RescheduleEvent
+	if err := db.Model(&reschedules).Where("user_id = ?", profile.ID).Order("id ASC").Select(); err != nil {
+		return nil, err
+	}
+	var entries []models.AuditEntry
+	if err := db.Model(&entries).Where("user_id = ?", profile.ID).Order("id ASC").Select(); err != nil {
+		return nil, err
+	}
+	metrics, err := schedule.ComputeStats(db, profile.ID, profile.Today(), metricsDays)
+	if err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	a := &archive{zip: zip.NewWriter(&buf)}
+	a.json("profile.json", profile)
+	a.json("tasks.json", tasks)
+	a.csv("tasks.csv", taskRows(tasks))
+	a.json("groups.json", groups)
+	a.json("sessions.json", occurrences)
+	a.csv("sessions.csv", sessionRows(occurrences, tasks))
+	a.json("pauses.json", pauses)
+	a.json("reschedules.json", reschedules)
+	a.json("metrics.json", metrics)
+	a.json("history.json", entries)
+	a.csv("history.csv", historyRows(entries))
+	if a.err != nil {
+		return nil, a.err
+	}
+	if err := a.zip.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// archive writes files into a zip, keeping the first error.
+type archive struct {
+	zip *zip.Writer
+	err error
+}
+
+func (a *archive) json(name string, v interface{}) {
+	if a.err != nil {
+		return
+	}
+	w, err := a.zip.Create(name)
+	if err != nil {
+		a.err = err
+		return
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	a.err = enc.Encode(v)
+}
+
+func (a *archive) csv(name string, rows [][]string) {
+	if a.err != nil {
+		return
+	}
+	w, err := a.zip.Create(name)
+	if err != nil {
+		a.err = err
+		return
+	}
+	cw := csv.NewWriter(w)
+	cw.WriteAll(rows)
+	a.err = cw.Error()
+}
+
+func taskRows(tasks []models.WorkoutTask) [][]string {
+	rows := [][]string{{"id", "name", "category", "sets", "reps", "restSeconds", "tempo", "description", "completed", "scheduledFor", "recurrence", "groupId", "createdAt", "updatedAt", "deletedAt"}}
+	for _, t := range tasks {
+		groupID := ""
+		if t.GroupID != nil {
+			groupID = strconv.Itoa(*t.GroupID)
+		}
+		rows = append(rows, []string{
+			strconv.Itoa(t.ID),
+			t.Name,
+			t.Category,
+			strconv.Itoa(t.Sets),
+			strconv.Itoa(t.Reps),
+			strconv.Itoa(t.RestSeconds),
+			t.Tempo,
+			t.Description,
+			strconv.FormatBool(t.Completed),
+			dateCell(t.ScheduledFor),
+			t.Recurrence,
+			groupID,
+			timeCell(&t.CreatedAt),
+			timeCell(&t.UpdatedAt),
+			timeCell(t.DeletedAt),
+		})
+	}
+	return rows
+}
+
+func sessionRows(occurrences []models.TaskOccurrence, tasks []models.WorkoutTask) [][]string {
+	names := make(map[int]string, len(tasks))
+	for _, t := range tasks {
+		names[t.ID] = t.Name
+	}
+	rows := [][]string{{"id", "taskId", "task", "occursOn", "scheduledFor", "status", "completedAt"}}
+	for _, o := range occurrences {
+		rows = append(rows, []string{
+			strconv.Itoa(o.ID),
+			strconv.Itoa(o.TaskID),
+			names[o.TaskID],
+			o.OccursOn.String(),
+			o.ScheduledFor.String(),
+			o.Status,
+			timeCell(o.CompletedAt),
+		})
+	}
+	return rows
+}
+
+func historyRows(entries []models.AuditEntry) [][]string {
+	rows := [][]string{{"id", "createdAt", "actorType", "action", "entity", "entityId", "version", "requestId", "changes"}}
+	for _, e := range entries {
+		changes, _ := json.Marshal(e.Changes)
+		rows = append(rows, []string{
+			strconv.FormatInt(e.ID, 10),
+			timeCell(&e.CreatedAt),
+			e.ActorType,
+			e.Action,
+			e.Entity,
+			strconv.Itoa(e.EntityID),
+			strconv.Itoa(e.Version),
+			e.RequestID,
+			string(changes),
+		})
+	}
+	return rows
+}
+
+func timeCell(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.UTC().Format(time.RFC3339)
+}
+
+func dateCell(d *models.Date) string {
+	if d == nil {
+		return ""
+	}
+	return d.String()
+}
+
+// Sign returns the signature of a download link for the export that is
+// valid until expires.
+func Sign(key []byte, id int, expires time.Time) string {
+	mac := hmac.New(sha256.New, key)
+	fmt.Fprintf(mac, "%d:%d", id, expires.Unix())
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify reports whether signature was made by Sign for the export and
+// expiry, and the link has not expired yet.
+func Verify(key []byte, id int, expires time.Time, signature string) bool {
+	if time.Now().After(expires) {
+		return false
+	}
+	return hmac.Equal([]byte(Sign(key, id, expires)), []byte(signature))
+}
@@ -0,0 +1,215 @@
+// fields/fields.go
+package fields
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"strings"
+
+	"back-end/validate"
+)
+
+// maxFields bounds the number of names a client may select.
+const maxFields = 100
+
+// Field is one selected JSON field. Fields narrows an object or array of
+// objects further; nil keeps the whole value.
+type Field struct {
+	Name   string
+	Fields Selection
+}
+
+// Selection is the set of JSON fields a client asked for with ?fields=,
+// such as fields=id,name,tasks.name. Fields of nested objects are named
+// with a dot. An empty selection keeps everything.
+type Selection []Field
+
+// Parse reads a comma-separated field list and checks every name against
+// the JSON fields of t, the type of the response or of one element of a
+// list. Unknown names are reported as field errors on "fields".
+func Parse(raw string, t reflect.Type) (Selection, error) {
+	if raw == "" {
+		return nil, nil
+	}
+	names := strings.Split(raw, ",")
+	if len(names) > maxFields {
+		return nil, validate.Field("fields", validate.CodeTooMany, "fields must name at most %d fields", maxFields)
+	}
+
+	var s Selection
+	var v validate.Validator
+	for _, name := range names {
+		path := strings.Split(name, ".")
+		if name == "" {
+			v.Add("fields", validate.CodeInvalid, "fields must not contain empty names")
+			continue
+		}
+		if !known(t, path) {
+			v.Add("fields", validate.CodeNotAllowed, "%s is not a field of this resource", name)
+			continue
+		}
+		s = s.add(path)
+	}
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+	return s, nil
+}
+
+// add merges a field path into the selection. Selecting a field whole
+// overrides selecting some of its fields.
+func (s Selection) add(path []string) Selection {
+	for i := range s {
+		if s[i].Name != path[0] {
+			continue
+		}
+		if len(path) == 1 {
+			s[i].Fields = nil
+		} else if s[i].Fields != nil {
+			s[i].Fields = s[i].Fields.add(path[1:])
+		}
+		return s
+	}
+	f := Field{Name: path[0]}
+	if len(path) > 1 {
+		f.Fields = Selection(nil).add(path[1:])
+	}
+	return append(s, f)
+}
+
+// Apply trims v to the selected fields, in the order they were asked for.
+// Objects in an array are trimmed one by one, so a list can be passed as
+// is. A value that cannot be encoded is returned unchanged for the
+// response encoder to report.
+func (s Selection) Apply(v interface{}) interface{} {
+	if len(s) == 0 {
+		return v
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	out, err := s.trim(b)
+	if err != nil {
+		return v
+	}
+	return json.RawMessage(out)
+}
+
+func (s Selection) trim(b json.RawMessage) (json.RawMessage, error) {
+	switch first(b) {
+	case '[':
+		var items []json.RawMessage
+		if err := json.Unmarshal(b, &items); err != nil {
+			return nil, err
+		}
+		for i, item := range items {
+			trimmed, err := s.trim(item)
+			if err != nil {
+				return nil, err
+			}
+			items[i] = trimmed
+		}
+		return json.Marshal(items)
+	case '{':
+		var members map[string]json.RawMessage
+		if err := json.Unmarshal(b, &members); err != nil {
+			return nil, err
+		}
+		var buf bytes.Buffer
+		buf.WriteByte('{')
+		for _, f := range s {
+			value, ok := members[f.Name]
+			if !ok {
+				continue
+			}
+			if f.Fields != nil {
+				var err error
+				if value, err = f.Fields.trim(value); err != nil {
+					return nil, err
+				}
+			}
+			if buf.Len() > 1 {
+				buf.WriteByte(',')
+			}
+			key, _ := json.Marshal(f.Name)
+			buf.Write(key)
+			buf.WriteByte(':')
+			buf.Write(value)
+		}
+		buf.WriteByte('}')
+		return buf.Bytes(), nil
+	}
+	// Scalars and null have no fields to trim
+	return b, nil
+}
+
+func first(b []byte) byte {
+	b = bytes.TrimLeft(b, " \t\r\n")
+	if len(b) == 0 {
+		return 0
+	}
+	return b[0]
+}
+
+// known reports whether path names a JSON field of t, following nested
+// objects and lists of objects.
+func known(t reflect.Type, path []string) bool {
+	t = element(t)
+	if t.Kind() != reflect.Struct || path[0] == "" {
+		return false
+	}
+	field, ok := jsonField(t, path[0])
+	if !ok {
+		return false
+	}
+	if len(path) == 1 {
+		return true
+	}
+	return known(field.Type, path[1:])
+}
+
+// element unwraps pointers, slices and arrays to the type of the values
+// they hold.
+func element(t reflect.Type) reflect.Type {
+	for {
+		switch t.Kind() {
+		case reflect.Ptr, reflect.Slice, reflect.Array:
+			t = t.Elem()
+		default:
+			return t
+		}
+	}
+}
+
+// jsonField finds the field of struct t that encodes as name, including
+// fields promoted from embedded structs.
+func jsonField(t reflect.Type, name string) (reflect.StructField, bool) {
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		tag := f.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		tagName, _, _ := strings.Cut(tag, ",")
+		if f.Anonymous && tagName == "" {
+			if inner := element(f.Type); inner.Kind() == reflect.Struct {
+				if found, ok := jsonField(inner, name); ok {
+					return found, true
+				}
+			}
+			continue
+		}
+		if !f.IsExported() {
+			continue
+		}
+		if tagName == "" {
+			tagName = f.Name
+		}
+		if tagName == name {
+			return f, true
+		}
+	}
+	return reflect.StructField{}, false
+}
@@ -0,0 +1,214 @@
+// filter/filter.go
+package filter
+
+import (
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"back-end/models"
+	"back-end/paging"
+	"back-end/validate"
+
+	"github.com/go-pg/pg/v10"
+	"github.com/go-pg/pg/v10/orm"
+)
+
+// Type is the type of value a filter parameter takes.
+type Type int
+
+const (
+	Text Type = iota
+	Bool
+	Int
+	Date
+)
+
+// Op is how a filter compares its column with the value.
+type Op int
+
+const (
+	Eq Op = iota
+	Gte
+	Lte
+	// Contains matches a case-insensitive substring
+	Contains
+	// Search matches a tsvector column against a web-style search query
+	Search
+)
+
+// maxTextLength bounds text values so filters stay cheap.
+const maxTextLength = 200
+
+// Field is a filter clients may send as a query parameter.
+type Field struct {
+	Param  string
+	Column string
+	Type   Type
+	Op     Op
+	// Allowed restricts a text value to one of these values, when set
+	Allowed []string
+	// Where builds the condition for filters that are not a comparison of
+	// one column. An error rejects the value.
+	Where func(value string) (string, []interface{}, error)
+}
+
+// Spec lists the filters and sorts an endpoint accepts.
+type Spec struct {
+	Fields []Field
+	// Sorts maps the names clients sort by to columns
+	Sorts map[string]paging.Column
+}
+
+// With returns a copy of the spec accepting additional filters.
+func (s Spec) With(fields ...Field) Spec {
+	s.Fields = append(append([]Field(nil), s.Fields...), fields...)
+	return s
+}
+
+type condition struct {
+	sql    string
+	params []interface{}
+}
+
+// Query is a parsed and validated filter, ready to apply.
+type Query struct {
+	conditions []condition
+	// Sort is the order the client asked for with ?sort=, ending with the
+	// tie-break column, or nil to keep the endpoint's default order.
+	Sort paging.Keyset
+}
+
+// Parse reads the spec's parameters from values. Parameters the spec
+// does not know are left alone, since lists take paging parameters too.
+// Sorting is given as ?sort=name,-createdAt; tiebreak is appended so
+// the order is total. Invalid values are reported as field errors named
+// after the parameter.
+func (s Spec) Parse(values url.Values, tiebreak paging.Column) (*Query, error) {
+	var v validate.Validator
+	q := &Query{}
+
+	for _, f := range s.Fields {
+		raw := values.Get(f.Param)
+		if raw == "" {
+			continue
+		}
+		c, err := f.condition(raw)
+		if err != nil {
+			v.Merge(f.Param, err)
+			continue
+		}
+		q.conditions = append(q.conditions, c)
+	}
+
+	if raw := values.Get("sort"); raw != "" {
+		q.Sort = s.parseSort(&v, raw, tiebreak)
+	}
+
+	if err := v.Err(); err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+func (s Spec) parseSort(v *validate.Validator, raw string, tiebreak paging.Column) paging.Keyset {
+	var keyset paging.Keyset
+	seen := make(map[string]bool)
+	for _, name := range strings.Split(raw, ",") {
+		desc := strings.HasPrefix(name, "-")
+		name = strings.TrimPrefix(name, "-")
+		column, ok := s.Sorts[name]
+		if !ok {
+			v.Add("sort", validate.CodeNotAllowed, "sort must name one of %s", strings.Join(s.sortNames(), ", "))
+			return nil
+		}
+		if seen[column.Name] {
+			v.Add("sort", validate.CodeInvalid, "sort names %s more than once", name)
+			return nil
+		}
+		seen[column.Name] = true
+		column.Desc = desc
+		keyset = append(keyset, column)
+	}
+	if !seen[tiebreak.Name] {
+		keyset = append(keyset, tiebreak)
+	}
+	return keyset
+}
+
+func (s Spec) sortNames() []string {
+	names := make([]string, 0, len(s.Sorts))
+	for name := range s.Sorts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func (f Field) condition(raw string) (condition, error) {
+	if len([]rune(raw)) > maxTextLength {
+		return condition{}, validate.Field(f.Param, validate.CodeTooLong, "%s must be at most %d characters", f.Param, maxTextLength)
+	}
+	if f.Where != nil {
+		sql, params, err := f.Where(raw)
+		return condition{sql: sql, params: params}, err
+	}
+
+	var value interface{}
+	switch f.Type {
+	case Bool:
+		b, err := strconv.ParseBool(raw)
+		if err != nil {
+			return condition{}, validate.Field(f.Param, validate.CodeType, "%s must be true or false", f.Param)
+		}
+		value = b
+	case Int:
+		n, err := strconv.Atoi(raw)
+		if err != nil {
+			return condition{}, validate.Field(f.Param, validate.CodeType, "%s must be a whole number", f.Param)
+		}
+		value = n
+	case Date:
+		d, err := models.ParseDate(raw)
+		if err != nil {
+			return condition{}, validate.Field(f.Param, validate.CodeType, "%s must be a date (YYYY-MM-DD)", f.Param)
+		}
+		value = d
+	default:
+		if f.Allowed != nil {
+			var v validate.Validator
+			v.OneOf(f.Param, raw, f.Allowed...)
+			if err := v.Err(); err != nil {
+				return condition{}, err
+			}
+		}
+		value = raw
+	}
+
+	column := pg.Ident(f.Column)
+	switch f.Op {
+	case Gte:
+		return condition{"? >= ?", []interface{}{column, value}}, nil
+	case Lte:
+		return condition{"? <= ?", []interface{}{column, value}}, nil
+	case Contains:
+		return condition{"? ILIKE ?", []interface{}{column, "%" + escapeLike(raw) + "%"}}, nil
+	case Search:
+		return condition{"? @@ websearch_to_tsquery('english', ?)", []interface{}{column, raw}}, nil
+	}
+	return condition{"? = ?", []interface{}{column, value}}, nil
+}
+
+// Apply adds the filter conditions to q. Ordering is left to paging.
+func (q *Query) Apply(query *orm.Query) *orm.Query {
+	for _, c := range q.conditions {
+		query = query.Where(c.sql, c.params...)
+	}
+	return query
+}
+
+// escapeLike makes LIKE wildcards in s match literally.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -4,6 +4,8 @@ import (
 	"encoding/json"
 	"net/http"
 
+	"back-end/problem"
+
 	"github.com/supabase-community/gotrue-go"
 	"github.com/supabase-community/gotrue-go/types"
 	"go.uber.org/zap"
@@ -23,13 +25,13 @@ func (h *Handler) SignUp(w http.ResponseWriter, r *http.Request) {
 	var req SignUpRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		h.Logger.Error("Failed to decode request body", zap.Error(err))
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidRequest, "Invalid request body")
 		return
 	}
 
 	if h.SupabaseID == "" || h.SupabaseKey == "" {
 		h.Logger.Error("Supabase credentials not set")
-		http.Error(w, "Server configuration error", http.StatusInternalServerError)
+		problem.Error(w, r, http.StatusInternalServerError, problem.CodeInternal, "Server configuration error")
 		return
 	}
 
@@ -40,19 +42,18 @@ func (h *Handler) SignUp(w http.ResponseWriter, r *http.Request) {
 		Password: req.Password,
 	})
 	if err != nil {
-		h.Logger.Error("Failed to sign up user", zap.Error(err))
-		http.Error(w, "Failed to sign up", http.StatusInternalServerError)
+		h.writeError(w, r, err, "Failed to sign up")
 		return
 	}
 
-	json.NewEncoder(w).Encode(user)
+	writeJSON(w, http.StatusOK, user)
 }
 
 func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
 	var req LoginRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		h.Logger.Error("Failed to decode request body", zap.Error(err))
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidRequest, "Invalid request body")
 		return
 	}
 
@@ -61,9 +62,9 @@ func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
 	user, err := client.SignInWithEmailPassword(req.Email, req.Password)
 	if err != nil {
 		h.Logger.Error("Failed to authenticate", zap.Error(err))
-		http.Error(w, "Invalid credentials", http.StatusUnauthorized)
+		problem.Error(w, r, http.StatusUnauthorized, problem.CodeUnauthorized, "Invalid credentials")
 		return
 	}
 
-	json.NewEncoder(w).Encode(user)
+	writeJSON(w, http.StatusOK, user)
 } 
\ No newline at end of file
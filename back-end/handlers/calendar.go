@@ -0,0 +1,234 @@
+// handlers/calendar.go
+package handlers
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"back-end/calendar"
+	"back-end/models"
+	"back-end/planner"
+	"back-end/problem"
+	"back-end/schedule"
+
+	"github.com/go-pg/pg/v10"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+// The feed covers recent history and the upcoming months, within the
+// window occurrences are kept materialized for.
+const (
+	calendarPastDays   = 30
+	calendarFutureDays = 180
+)
+
+type CalendarTokenResponse struct {
+	Token     string    `json:"token"`
+	URL       string    `json:"url"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func hashCalendarToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func newCalendarToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// baseURL returns the scheme and host the client reached the API on.
+func baseURL(r *http.Request) string {
+	scheme := "http"
+	if r.TLS != nil {
+		scheme = "https"
+	}
+	if proto := r.Header.Get("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+	return fmt.Sprintf("%s://%s", scheme, r.Host)
+}
+
+func calendarFeedURL(r *http.Request, token string) string {
+	return fmt.Sprintf("%s/v1/calendar/%s.ics", baseURL(r), token)
+}
+
+// RotateCalendarTokenByUserId issues a new calendar feed token for the
+// user, invalidating any previous one. The token is only returned here.
+func (h *Handler) RotateCalendarTokenByUserId(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	profile, ok := h.findProfileByUserId(w, r, vars["userId"])
+	if !ok {
+		return
+	}
+
+	token, err := newCalendarToken()
+	if err != nil {
+		h.writeError(w, r, err, "Failed to generate calendar token")
+		return
+	}
+
+	calendarToken := &models.CalendarToken{
+		UserID:    profile.ID,
+		TokenHash: hashCalendarToken(token),
+		CreatedAt: time.Now(),
+	}
+	_, err = h.DB.Model(calendarToken).
+		OnConflict("(user_id) DO UPDATE").
+		Set("token_hash = EXCLUDED.token_hash").
+		Set("created_at = EXCLUDED.created_at").
+		Insert()
+	if err != nil {
+		h.writeError(w, r, err, "Failed to generate calendar token")
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, CalendarTokenResponse{
+		Token:     token,
+		URL:       calendarFeedURL(r, token),
+		CreatedAt: calendarToken.CreatedAt,
+	})
+}
+
+// RevokeCalendarTokenByUserId disables the user's calendar feed.
+func (h *Handler) RevokeCalendarTokenByUserId(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	profile, ok := h.findProfileByUserId(w, r, vars["userId"])
+	if !ok {
+		return
+	}
+
+	res, err := h.DB.Model((*models.CalendarToken)(nil)).Where("user_id = ?", profile.ID).Delete()
+	if err != nil {
+		h.writeError(w, r, err, "Failed to revoke calendar token")
+		return
+	}
+
+	if res.RowsAffected() == 0 {
+		problem.Error(w, r, http.StatusNotFound, problem.CodeNotFound, "Calendar token not found")
+		return
+	}
+
+	response := map[string]string{
+		"message": "Calendar feed has been revoked",
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// GetCalendarFeed renders the scheduled sessions of the token's owner as an
+// iCalendar feed with one all-day event per occurrence. Calendar apps poll
+// the feed, so it only reads.
+func (h *Handler) GetCalendarFeed(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	var calendarToken models.CalendarToken
+	err := h.DB.Model(&calendarToken).Where("token_hash = ?", hashCalendarToken(vars["token"])).Select()
+	if err != nil {
+		if err == pg.ErrNoRows {
+			problem.Error(w, r, http.StatusNotFound, problem.CodeNotFound, "Calendar not found")
+			return
+		}
+		h.writeError(w, r, err, "Failed to load calendar")
+		return
+	}
+
+	var profile models.UserProfile
+	if err := h.DB.Model(&profile).Where("id = ?", calendarToken.UserID).Select(); err != nil {
+		h.writeError(w, r, err, "Failed to load calendar")
+		return
+	}
+
+	today := profile.Today()
+	occurrences, err := schedule.LoadOccurrences(h.DB, calendarToken.UserID, today.AddDays(-calendarPastDays), today.AddDays(calendarFutureDays))
+	if err != nil {
+		h.writeError(w, r, err, "Failed to load calendar")
+		return
+	}
+
+	var groups []models.WorkoutGroup
+	if err := h.DB.Model(&groups).Where("user_id = ?", calendarToken.UserID).Select(); err != nil {
+		h.writeError(w, r, err, "Failed to load calendar")
+		return
+	}
+
+	cal := &calendar.Calendar{Name: "FitTrack Workouts", TimeZone: profile.Timezone}
+	for _, day := range sessionsByDate(occurrences) {
+		cal.Events = append(cal.Events, sessionEvents(day, groups)...)
+	}
+
+	w.Header().Set("Content-Type", "text/calendar; charset=utf-8")
+	w.Header().Set("Content-Disposition", `inline; filename="workouts.ics"`)
+	if _, err := cal.WriteTo(w); err != nil {
+		h.Logger.Error("Failed to write calendar", zap.Error(err))
+	}
+}
+
+// sessionsByDate splits date-ordered occurrences into one slice per day.
+func sessionsByDate(occurrences []models.TaskOccurrence) [][]models.TaskOccurrence {
+	var days [][]models.TaskOccurrence
+	for i, o := range occurrences {
+		if i == 0 || !o.ScheduledFor.Equal(occurrences[i-1].ScheduledFor.Time) {
+			days = append(days, nil)
+		}
+		days[len(days)-1] = append(days[len(days)-1], o)
+	}
+	return days
+}
+
+// sessionEvents returns one event per occurrence of a session day. The UID
+// comes from the occurrence, so an event keeps its identity when it is
+// moved or completed. Each event lists the whole session, as calendar
+// apps show the events of a day separately.
+func sessionEvents(day []models.TaskOccurrence, groups []models.WorkoutGroup) []calendar.Event {
+	var tasks []models.WorkoutTask
+	var lines []string
+	for _, o := range day {
+		if o.Task == nil {
+			continue
+		}
+		tasks = append(tasks, *o.Task)
+		lines = append(lines, "- "+taskLine(o.Task, o.Status))
+	}
+	session := planner.EstimateSession(planner.Blocks(tasks, groups), planner.DefaultOptions)
+
+	var events []calendar.Event
+	for _, o := range day {
+		if o.Task == nil {
+			continue
+		}
+		est := planner.EstimateSession(planner.Blocks([]models.WorkoutTask{*o.Task}, nil), planner.DefaultOptions)
+		events = append(events, calendar.Event{
+			UID:       fmt.Sprintf("occurrence-%d@fittrack", o.ID),
+			Date:      o.ScheduledFor.Time,
+			Summary:   fmt.Sprintf("%s (~%d min)", o.Task.Name, est.TotalMinutes),
+			Cancelled: o.Status == models.OccurrenceSkipped,
+			Updated:   o.UpdatedAt,
+			Description: fmt.Sprintf("%s\nEstimated duration: %d min\n\nSession (%d exercises, ~%d min):\n%s",
+				taskLine(o.Task, o.Status), est.TotalMinutes, len(tasks), session.TotalMinutes, strings.Join(lines, "\n")),
+		})
+	}
+	return events
+}
+
+// taskLine describes a task of a session in one line.
+func taskLine(task *models.WorkoutTask, status string) string {
+	line := fmt.Sprintf("%s: %d x %d", task.Name, task.Sets, task.Reps)
+	if !task.IsTraining() {
+		line += fmt.Sprintf(" (%s)", task.Category)
+	}
+	if status != models.OccurrenceScheduled {
+		line += fmt.Sprintf(" [%s]", status)
+	}
+	return line
+}
@@ -0,0 +1,77 @@
+// handlers/etag.go
+package handlers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"back-end/problem"
+)
+
+// errVersionChanged is returned when a conditional write finds the record
+// at a different version than the one the client sent in If-Match.
+var errVersionChanged = errors.New("record was modified by another request")
+
+// etag returns the strong entity tag of a record version, e.g. "3".
+func etag(version int) string {
+	return `"` + strconv.Itoa(version) + `"`
+}
+
+func setETag(w http.ResponseWriter, version int) {
+	w.Header().Set("ETag", etag(version))
+}
+
+// matchesETag reports whether an If-Match or If-None-Match header lists
+// the tag. Weak tags only match when weak comparison is allowed.
+func matchesETag(header, tag string, weak bool) bool {
+	for _, t := range strings.Split(header, ",") {
+		t = strings.TrimSpace(t)
+		if t == "*" {
+			return true
+		}
+		if weak {
+			t = strings.TrimPrefix(t, "W/")
+		}
+		if t == tag {
+			return true
+		}
+	}
+	return false
+}
+
+// notModified sets the ETag of a record being read and answers 304 Not
+// Modified when If-None-Match shows the client already has this version.
+func notModified(w http.ResponseWriter, r *http.Request, version int) bool {
+	setETag(w, version)
+	header := r.Header.Get("If-None-Match")
+	if header == "" || !matchesETag(header, etag(version), true) {
+		return false
+	}
+	w.WriteHeader(http.StatusNotModified)
+	return true
+}
+
+// requireIfMatch checks that a write was made against the current version
+// of a record. It answers 428 when If-Match is missing and 412 when it
+// names another version.
+func requireIfMatch(w http.ResponseWriter, r *http.Request, version int) bool {
+	header := r.Header.Get("If-Match")
+	if header == "" {
+		problem.Error(w, r, http.StatusPreconditionRequired, problem.CodePreconditionRequired, "If-Match header with the record's ETag is required")
+		return false
+	}
+	if !matchesETag(header, etag(version), false) {
+		writeVersionChanged(w, r, version)
+		return false
+	}
+	return true
+}
+
+func writeVersionChanged(w http.ResponseWriter, r *http.Request, version int) {
+	if version > 0 {
+		setETag(w, version)
+	}
+	problem.Error(w, r, http.StatusPreconditionFailed, problem.CodeVersionMismatch, "Record has been modified, fetch it again and retry")
+}
@@ -1,6 +1,8 @@
 package handlers
 
 import (
+	"back-end/authadmin"
+
 	"github.com/go-pg/pg/v10"
 	"go.uber.org/zap"
 )
@@ -10,4 +12,8 @@ type Handler struct {
 	Logger       *zap.Logger
 	SupabaseID  string
 	SupabaseKey  string
+	// AuthAdmin deletes auth users when their account is erased
+	AuthAdmin    authadmin.Client
+	// ExportKey signs the download links of data exports
+	ExportKey    []byte
 } 
\ No newline at end of file
@@ -2,10 +2,9 @@
 package handlers
 
 import (
-	"encoding/json"
 	"net/http"
 )
 
 func (h *Handler) HealthCheck(w http.ResponseWriter, r *http.Request) {
-	json.NewEncoder(w).Encode(map[string]string{"status": "healthy"})
+	writeJSON(w, http.StatusOK, map[string]string{"status": "healthy"})
 }
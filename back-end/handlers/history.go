@@ -0,0 +1,196 @@
+// handlers/history.go
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+
+	"back-end/audit"
+	"back-end/models"
+	"back-end/paging"
+	"back-end/problem"
+	"back-end/validate"
+
+	"github.com/go-pg/pg/v10"
+	"github.com/go-pg/pg/v10/orm"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+// auditTask records a write to a task in db, the transaction of the
+// write. before is nil for a create.
+func auditTask(db orm.DB, action string, before, after *models.WorkoutTask) error {
+	e := audit.Event{
+		Action:   action,
+		Entity:   models.AuditEntityTask,
+		EntityID: after.ID,
+		UserID:   after.UserID,
+		Version:  after.Version,
+		After:    after,
+	}
+	if before != nil {
+		e.Before = before
+	}
+	return audit.Record(db, e)
+}
+
+// auditProfile records a write to a profile in db. Both copies must hold
+// the stored metric values, not a rendering in the client's units.
+func auditProfile(db orm.DB, action string, before, after *models.UserProfile) error {
+	e := audit.Event{
+		Action:   action,
+		Entity:   models.AuditEntityProfile,
+		EntityID: after.ID,
+		UserID:   after.ID,
+		Version:  after.Version,
+		After:    after,
+	}
+	if before != nil {
+		e.Before = before
+	}
+	return audit.Record(db, e)
+}
+
+// History entries are listed newest first
+var historyKeyset = paging.Desc(paging.IntColumn("id"))
+
+// GetWorkoutTaskHistory lists the changes made to a task. The history
+// stays readable while the task is in the trash and after it is purged.
+func (h *Handler) GetWorkoutTaskHistory(w http.ResponseWriter, r *http.Request) {
+	h.listHistory(w, r, models.AuditEntityTask)
+}
+
+// GetUserProfileHistory lists the changes made to a profile.
+func (h *Handler) GetUserProfileHistory(w http.ResponseWriter, r *http.Request) {
+	h.listHistory(w, r, models.AuditEntityProfile)
+}
+
+func (h *Handler) listHistory(w http.ResponseWriter, r *http.Request, entity string) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		h.Logger.Error("Invalid ID format", zap.String("id", vars["id"]))
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidRequest, "Invalid ID format")
+		return
+	}
+
+	params, err := paging.FromRequest(r, historyKeyset)
+	if err != nil {
+		writeInvalid(w, r, err)
+		return
+	}
+
+	var entries []models.AuditEntry
+	query := h.DB.Model(&entries).
+		Where("entity = ?", entity).
+		Where("entity_id = ?", id)
+	total, err := params.Total(query)
+	if err == nil {
+		err = params.Apply(query).Select()
+	}
+	if err != nil {
+		h.writeError(w, r, err, "Failed to get history")
+		return
+	}
+
+	entries, next := paging.Trim(params, entries, func(e *models.AuditEntry) []string {
+		return []string{strconv.FormatInt(e.ID, 10)}
+	})
+	writePage(w, r, entries, next, total)
+}
+
+// RevertRequest names the history entry to go back to. The record is
+// restored to how it was right after that entry's change.
+type RevertRequest struct {
+	Revision int64 `json:"revision"`
+}
+
+// findRevision decodes a revert request and loads the entry it names,
+// which must belong to the given record and not be its deletion.
+func (h *Handler) findRevision(w http.ResponseWriter, r *http.Request, entity string, id int) (*models.AuditEntry, bool) {
+	var req RevertRequest
+	if err := decodeJSON(r.Body, &req); err != nil {
+		writeInvalid(w, r, err)
+		return nil, false
+	}
+	var v validate.Validator
+	if !v.Required("revision", req.Revision > 0) {
+		writeInvalid(w, r, v.Err())
+		return nil, false
+	}
+
+	entry := &models.AuditEntry{}
+	err := h.DB.Model(entry).
+		Where("id = ?", req.Revision).
+		Where("entity = ?", entity).
+		Where("entity_id = ?", id).
+		Select()
+	if err != nil {
+		if err == pg.ErrNoRows {
+			writeInvalid(w, r, validate.Field("revision", validate.CodeNotFound, "revision %d is not in this record's history", req.Revision))
+			return nil, false
+		}
+		h.writeError(w, r, err, "Failed to get history")
+		return nil, false
+	}
+	if entry.Action == models.AuditDelete {
+		writeInvalid(w, r, validate.Field("revision", validate.CodeNotAllowed, "revision %d deleted the record, restore it from the trash instead", req.Revision))
+		return nil, false
+	}
+	return entry, true
+}
+
+// RevertWorkoutTask puts a task back the way it was at an earlier
+// revision. It is an ordinary update: the task keeps its place in the
+// list, needs If-Match and is recorded in the history as a revert.
+func (h *Handler) RevertWorkoutTask(w http.ResponseWriter, r *http.Request) {
+	existingTask, ok := h.findTaskForWrite(w, r)
+	if !ok {
+		return
+	}
+	entry, ok := h.findRevision(w, r, models.AuditEntityTask, existingTask.ID)
+	if !ok {
+		return
+	}
+
+	var task models.WorkoutTask
+	if err := audit.Restore(entry, &task); err != nil {
+		h.writeError(w, r, err, "Failed to read revision")
+		return
+	}
+	task.DeletedAt = nil
+	h.saveTask(w, r, models.AuditRevert, existingTask, &task)
+}
+
+// RevertUserProfile puts a profile back the way it was at an earlier
+// revision, like RevertWorkoutTask.
+func (h *Handler) RevertUserProfile(w http.ResponseWriter, r *http.Request) {
+	unitSystem, ok := requestedUnits(w, r)
+	if !ok {
+		return
+	}
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		h.Logger.Error("Invalid profile ID", zap.Error(err))
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidRequest, "Invalid profile ID")
+		return
+	}
+	existingProfile, ok := h.findProfileForWrite(w, r, "id", id)
+	if !ok {
+		return
+	}
+	entry, ok := h.findRevision(w, r, models.AuditEntityProfile, existingProfile.ID)
+	if !ok {
+		return
+	}
+
+	var profile models.UserProfile
+	if err := audit.Restore(entry, &profile); err != nil {
+		h.writeError(w, r, err, "Failed to read revision")
+		return
+	}
+	profile.DeletedAt = nil
+	h.saveProfile(w, r, models.AuditRevert, existingProfile, &profile, unitSystem)
+}
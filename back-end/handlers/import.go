@@ -0,0 +1,166 @@
+// handlers/import.go
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"back-end/audit"
+	"back-end/importer"
+	"back-end/models"
+	"back-end/problem"
+	"back-end/requestid"
+	"back-end/units"
+
+	"github.com/go-pg/pg/v10"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+// maxImportSize bounds the files that can be imported. Years of logs from
+// the apps we import from fit comfortably.
+const maxImportSize = 10 << 20
+
+// ImportPreview is what an import would do, returned for a dry run.
+type ImportPreview struct {
+	Format string `json:"format"`
+	// Total counts the tasks, Create those not already logged
+	Total     int                     `json:"total"`
+	Create    int                     `json:"create"`
+	Skip      int                     `json:"skip"`
+	Entries   []importer.Entry        `json:"entries"`
+	Unmatched []string                `json:"unmatched"`
+	Errors    []models.ImportRowError `json:"errors"`
+}
+
+// CreateImportByUserId imports a workout log exported from another app.
+// The file is the raw request body; ?format= names the app or generic
+// format and ?weightUnit= the unit of weights the file does not name one
+// for. Every exercise done on a day becomes a completed task.
+//
+// With ?dryRun=true nothing is created: the answer lists the tasks the
+// import would create, the exercises not found in the catalog and the rows
+// that cannot be imported. Otherwise the import runs in the background and
+// the answer is 202; poll the Location for its progress.
+func (h *Handler) CreateImportByUserId(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	profile, ok := h.findProfileByUserId(w, r, vars["userId"])
+	if !ok {
+		return
+	}
+
+	query := r.URL.Query()
+	format := query.Get("format")
+	if !importer.IsValidFormat(format) {
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidRequest,
+			"format must be one of "+strings.Join(importer.Formats, ", "))
+		return
+	}
+	weightUnit := query.Get("weightUnit")
+	if weightUnit != "" && weightUnit != units.Kilogram && weightUnit != units.Pound {
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidRequest, "weightUnit must be kg or lb")
+		return
+	}
+	dryRun := false
+	if value := query.Get("dryRun"); value != "" {
+		var err error
+		if dryRun, err = strconv.ParseBool(value); err != nil {
+			problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidRequest, "dryRun must be true or false")
+			return
+		}
+	}
+
+	data, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxImportSize))
+	if err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			problem.Error(w, r, http.StatusRequestEntityTooLarge, problem.CodeInvalidRequest,
+				fmt.Sprintf("The file must not be larger than %d MB", maxImportSize>>20))
+			return
+		}
+		h.Logger.Error("Failed to read request body", zap.Error(err))
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidRequest, "Invalid request body")
+		return
+	}
+
+	sets, rowErrs, err := importer.Parse(format, data, weightUnit)
+	if err != nil {
+		problem.Error(w, r, http.StatusUnprocessableEntity, problem.CodeValidation, "The file cannot be imported: "+err.Error())
+		return
+	}
+
+	if dryRun {
+		entries, checkErrs, err := importer.Check(h.DB, profile, format, importer.Plan(sets))
+		if err != nil {
+			h.writeError(w, r, err, "Failed to preview import")
+			return
+		}
+		preview := ImportPreview{
+			Format:    format,
+			Total:     len(entries),
+			Entries:   entries,
+			Unmatched: importer.Unmatched(entries),
+			Errors:    append(rowErrs, checkErrs...),
+		}
+		for _, e := range entries {
+			if e.Duplicate {
+				preview.Skip++
+			} else {
+				preview.Create++
+			}
+		}
+		writeJSON(w, http.StatusOK, preview)
+		return
+	}
+
+	actor := audit.FromContext(r.Context())
+	now := time.Now()
+	imp := models.Import{
+		UserID:     profile.ID,
+		Format:     format,
+		WeightUnit: weightUnit,
+		Status:     models.ImportPending,
+		Source:     data,
+		ActorType:  actor.Type,
+		ActorID:    actor.ID,
+		RequestID:  requestid.FromContext(r.Context()),
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	if _, err := h.DB.Model(&imp).Insert(); err != nil {
+		h.writeError(w, r, err, "Failed to create import")
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/v1/imports/%d", imp.ID))
+	writeJSON(w, http.StatusAccepted, imp)
+}
+
+// GetImport reports the progress of an import and the rows it could not
+// import so far.
+func (h *Handler) GetImport(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		h.Logger.Error("Invalid ID format", zap.String("id", vars["id"]))
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidRequest, "Invalid ID format")
+		return
+	}
+
+	imp := &models.Import{ID: id}
+	err = h.DB.Model(imp).ExcludeColumn("source").WherePK().Select()
+	if err != nil {
+		if err == pg.ErrNoRows {
+			problem.Error(w, r, http.StatusNotFound, problem.CodeNotFound, "Import not found")
+			return
+		}
+		h.writeError(w, r, err, "Failed to get import")
+		return
+	}
+	writeJSON(w, http.StatusOK, imp)
+}
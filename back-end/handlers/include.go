@@ -0,0 +1,131 @@
+// handlers/include.go
+package handlers
+
+import (
+	"net/http"
+	"strings"
+
+	"back-end/models"
+	"back-end/planner"
+	"back-end/schedule"
+	"back-end/validate"
+
+	"github.com/go-pg/pg/v10"
+)
+
+// Related resources a profile read can embed with ?include=
+const (
+	includeTasks         = "tasks"
+	includeActivePlan    = "activePlan"
+	includeLatestMetrics = "latestMetrics"
+)
+
+// ProfileResource is a profile together with the related resources the
+// client asked to include. Resources not asked for are left out.
+type ProfileResource struct {
+	*models.UserProfile
+	Tasks *[]models.WorkoutTask `json:"tasks,omitempty"`
+	// ActivePlan is the session the profile's current tasks make up
+	ActivePlan *SessionPlan `json:"activePlan,omitempty"`
+	// LatestMetrics are the streak and adherence stats as of today
+	LatestMetrics *schedule.Stats `json:"latestMetrics,omitempty"`
+}
+
+type includes map[string]bool
+
+// requestedIncludes parses ?include=, writing a 422 response for unknown
+// names. Metrics are computed one profile at a time, so they can only be
+// included when reading a single profile.
+func requestedIncludes(w http.ResponseWriter, r *http.Request, single bool) (includes, bool) {
+	include := includes{}
+	raw := r.URL.Query().Get("include")
+	if raw == "" {
+		return include, true
+	}
+
+	var v validate.Validator
+	for _, name := range strings.Split(raw, ",") {
+		switch name {
+		case includeTasks, includeActivePlan:
+		case includeLatestMetrics:
+			if !single {
+				v.Add("include", validate.CodeNotAllowed, "latestMetrics can only be included when reading one profile")
+				continue
+			}
+		default:
+			v.Add("include", validate.CodeNotAllowed, "include must name tasks, activePlan or latestMetrics")
+			continue
+		}
+		include[name] = true
+	}
+	if err := v.Err(); err != nil {
+		writeInvalid(w, r, err)
+		return nil, false
+	}
+	return include, true
+}
+
+// profileResources wraps profiles and loads what include asks for. The
+// related rows of all profiles are read with one query per kind of
+// resource rather than one per profile.
+func (h *Handler) profileResources(profiles []models.UserProfile, include includes) ([]ProfileResource, error) {
+	resources := make([]ProfileResource, len(profiles))
+	ids := make([]int, len(profiles))
+	for i := range profiles {
+		resources[i].UserProfile = &profiles[i]
+		ids[i] = profiles[i].ID
+	}
+	if len(include) == 0 || len(profiles) == 0 {
+		return resources, nil
+	}
+
+	tasksByUser := make(map[int][]models.WorkoutTask)
+	if include[includeTasks] || include[includeActivePlan] {
+		var tasks []models.WorkoutTask
+		err := h.DB.Model(&tasks).
+			Where("user_id IN (?)", pg.In(ids)).
+			Where("source = ?", models.TaskSourcePlan).
+			Order("user_id", "position", "id").
+			Select()
+		if err != nil {
+			return nil, err
+		}
+		for _, task := range tasks {
+			tasksByUser[task.UserID] = append(tasksByUser[task.UserID], task)
+		}
+	}
+
+	groupsByUser := make(map[int][]models.WorkoutGroup)
+	if include[includeActivePlan] {
+		var groups []models.WorkoutGroup
+		if err := h.DB.Model(&groups).Where("user_id IN (?)", pg.In(ids)).Select(); err != nil {
+			return nil, err
+		}
+		for _, group := range groups {
+			groupsByUser[group.UserID] = append(groupsByUser[group.UserID], group)
+		}
+	}
+
+	for i := range resources {
+		res := &resources[i]
+		if include[includeTasks] {
+			tasks := tasksByUser[res.ID]
+			if tasks == nil {
+				tasks = []models.WorkoutTask{}
+			}
+			res.Tasks = &tasks
+		}
+		if include[includeActivePlan] {
+			plan := newSessionPlan(res.UserProfile, planner.Blocks(tasksByUser[res.ID], groupsByUser[res.ID]))
+			res.ActivePlan = &plan
+		}
+		if include[includeLatestMetrics] {
+			stats, err := schedule.ComputeStats(h.DB, res.ID, res.Today(), defaultStatsDays)
+			if err != nil {
+				return nil, err
+			}
+			res.LatestMetrics = &stats
+		}
+	}
+	return resources, nil
+}
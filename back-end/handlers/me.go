@@ -0,0 +1,177 @@
+// handlers/me.go
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"back-end/audit"
+	"back-end/erasure"
+	"back-end/export"
+	"back-end/models"
+	"back-end/problem"
+	"back-end/requestid"
+
+	"github.com/go-pg/pg/v10"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+// exportLinkLifetime bounds how long a download link works. A new link
+// can be fetched until the archive itself expires.
+const exportLinkLifetime = time.Hour
+
+// currentUserID returns the auth user id of the token the request was
+// authenticated with, writing a 401 when there is none.
+func currentUserID(w http.ResponseWriter, r *http.Request) (string, bool) {
+	actor := audit.FromContext(r.Context())
+	if actor.ID == "" {
+		problem.Error(w, r, http.StatusUnauthorized, problem.CodeUnauthorized, "Unauthorized")
+		return "", false
+	}
+	return actor.ID, true
+}
+
+// RequestMyExport starts building an archive of the user's data. The
+// archive is built in the background; poll the Location until it is ready
+// and has a download link. A pending export is returned as is.
+func (h *Handler) RequestMyExport(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+	profile, ok := h.findProfileByUserId(w, r, userID)
+	if !ok {
+		return
+	}
+
+	// The partial unique index lets one pending export exist per user, so
+	// concurrent requests insert at most one and all return it
+	e := models.DataExport{UserID: profile.ID, Status: models.ExportPending, CreatedAt: time.Now()}
+	res, err := h.DB.Model(&e).
+		OnConflict("(user_id) WHERE status = 'pending' DO NOTHING").
+		Insert()
+	if err == nil && res.RowsAffected() == 0 {
+		// The pending export is the user's latest, unless it finished
+		// since, in which case the finished one is returned
+		e = models.DataExport{}
+		err = h.DB.Model(&e).
+			ExcludeColumn("archive").
+			Where("user_id = ?", profile.ID).
+			Order("id DESC").
+			Limit(1).
+			Select()
+	}
+	if err != nil {
+		h.writeError(w, r, err, "Failed to request data export")
+		return
+	}
+
+	w.Header().Set("Location", fmt.Sprintf("/v1/me/exports/%d", e.ID))
+	writeJSON(w, http.StatusAccepted, e)
+}
+
+// GetMyExport reports the status of one of the user's exports, with a
+// fresh signed download link once it is ready.
+func (h *Handler) GetMyExport(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+	profile, ok := h.findProfileByUserId(w, r, userID)
+	if !ok {
+		return
+	}
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		h.Logger.Error("Invalid ID format", zap.String("id", vars["id"]))
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidRequest, "Invalid ID format")
+		return
+	}
+
+	e := &models.DataExport{ID: id}
+	err = h.DB.Model(e).ExcludeColumn("archive").WherePK().Where("user_id = ?", profile.ID).Select()
+	if err != nil {
+		if err == pg.ErrNoRows {
+			problem.Error(w, r, http.StatusNotFound, problem.CodeNotFound, "Data export not found")
+			return
+		}
+		h.writeError(w, r, err, "Failed to get data export")
+		return
+	}
+
+	if e.Status == models.ExportReady {
+		if len(h.ExportKey) == 0 {
+			h.Logger.Error("Export signing key not set")
+			problem.Error(w, r, http.StatusInternalServerError, problem.CodeInternal, "Server configuration error")
+			return
+		}
+		expires := time.Now().Add(exportLinkLifetime)
+		if e.ExpiresAt != nil && e.ExpiresAt.Before(expires) {
+			expires = *e.ExpiresAt
+		}
+		e.DownloadURL = fmt.Sprintf("%s/v1/exports/%d/download?expires=%d&signature=%s",
+			baseURL(r), e.ID, expires.Unix(), export.Sign(h.ExportKey, e.ID, expires))
+	}
+	writeJSON(w, http.StatusOK, e)
+}
+
+// DownloadExport serves an export archive. The signed link stands in for
+// authentication, so it can be opened in a browser.
+func (h *Handler) DownloadExport(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		h.Logger.Error("Invalid ID format", zap.String("id", vars["id"]))
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidRequest, "Invalid ID format")
+		return
+	}
+	expiresUnix, err := strconv.ParseInt(r.URL.Query().Get("expires"), 10, 64)
+	if err != nil || len(h.ExportKey) == 0 || !export.Verify(h.ExportKey, id, time.Unix(expiresUnix, 0), r.URL.Query().Get("signature")) {
+		problem.Error(w, r, http.StatusForbidden, problem.CodeForbidden, "Download link is invalid or has expired")
+		return
+	}
+
+	e := &models.DataExport{ID: id}
+	err = h.DB.Model(e).WherePK().Where("status = ?", models.ExportReady).Where("expires_at > ?", time.Now()).Select()
+	if err != nil {
+		if err == pg.ErrNoRows {
+			problem.Error(w, r, http.StatusNotFound, problem.CodeNotFound, "Data export not found")
+			return
+		}
+		h.writeError(w, r, err, "Failed to get data export")
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/zip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="export-%d.zip"`, e.ID))
+	w.Header().Set("Cache-Control", "private, no-store")
+	w.Write(e.Archive)
+}
+
+// EraseMe erases the user's account: every row about them, then the auth
+// user itself. It answers 200 once both are done. When a step fails the
+// answer is 202 with the steps completed so far, and a background job
+// resumes the erasure from the failed step.
+func (h *Handler) EraseMe(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+
+	e, err := erasure.Request(r.Context(), h.DB, userID)
+	if err != nil {
+		h.writeError(w, r, err, "Failed to erase account")
+		return
+	}
+	logger := h.Logger.With(zap.String("request_id", requestid.FromContext(r.Context())))
+	if err := erasure.Run(r.Context(), h.DB, h.AuthAdmin, logger, e); err != nil {
+		writeJSON(w, http.StatusAccepted, e)
+		return
+	}
+	writeJSON(w, http.StatusOK, e)
+}
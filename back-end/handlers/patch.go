@@ -0,0 +1,106 @@
+// handlers/patch.go
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"mime"
+	"net/http"
+
+	"back-end/validate"
+)
+
+// Fields a full replacement must include
+var (
+	profileRequiredFields = []string{"age", "weight", "height", "fitnessLevel", "preferredWorkoutDuration", "workoutDaysPerWeek"}
+	taskRequiredFields    = []string{"name", "sets", "reps"}
+)
+
+// isMergePatch reports whether a PATCH body is a JSON merge patch. Plain
+// application/json is accepted as well.
+func isMergePatch(r *http.Request) bool {
+	ct := r.Header.Get("Content-Type")
+	if ct == "" {
+		return true
+	}
+	mediaType, _, err := mime.ParseMediaType(ct)
+	return err == nil && (mediaType == "application/merge-patch+json" || mediaType == "application/json")
+}
+
+// decodeReplacement decodes a PUT body into dst, rejecting bodies that
+// leave out or null any required field.
+func decodeReplacement(r *http.Request, dst interface{}, required []string) error {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		return errInvalidBody
+	}
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(body, &fields); err != nil {
+		return errInvalidBody
+	}
+
+	var v validate.Validator
+	for _, name := range required {
+		value, ok := fields[name]
+		v.Required(name, ok && string(value) != "null")
+	}
+	if err := v.Err(); err != nil {
+		return err
+	}
+	return decodeJSON(bytes.NewReader(body), dst)
+}
+
+// decodeMergePatch applies the RFC 7396 merge patch in the request body to
+// the JSON form of current and decodes the result into dst. It returns the
+// patch so callers can tell which fields the client sent.
+func decodeMergePatch(r *http.Request, current, dst interface{}) (map[string]interface{}, error) {
+	var patch map[string]interface{}
+	dec := json.NewDecoder(r.Body)
+	dec.UseNumber()
+	if err := dec.Decode(&patch); err != nil {
+		return nil, errInvalidBody
+	}
+
+	doc, err := json.Marshal(current)
+	if err != nil {
+		return nil, err
+	}
+	var target interface{}
+	dec = json.NewDecoder(bytes.NewReader(doc))
+	dec.UseNumber()
+	if err := dec.Decode(&target); err != nil {
+		return nil, err
+	}
+
+	merged, err := json.Marshal(mergePatch(target, patch))
+	if err != nil {
+		return nil, err
+	}
+	if err := decodeJSON(bytes.NewReader(merged), dst); err != nil {
+		return nil, err
+	}
+	return patch, nil
+}
+
+// mergePatch implements the RFC 7396 MergePatch algorithm: objects are
+// merged member by member, null removes a member and any other value
+// replaces the target.
+func mergePatch(target, patch interface{}) interface{} {
+	p, ok := patch.(map[string]interface{})
+	if !ok {
+		return patch
+	}
+	t, ok := target.(map[string]interface{})
+	if !ok {
+		t = make(map[string]interface{})
+	}
+	for name, value := range p {
+		if value == nil {
+			delete(t, name)
+			continue
+		}
+		t[name] = mergePatch(t[name], value)
+	}
+	return t
+}
@@ -0,0 +1,209 @@
+// handlers/pause.go
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"back-end/models"
+	"back-end/paging"
+	"back-end/problem"
+	"back-end/schedule"
+
+	"github.com/go-pg/pg/v10"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+// defaultStatsDays is the adherence window when ?days= is not given.
+const defaultStatsDays = 30
+
+var errPauseOverlap = errors.New("pause overlaps an existing pause")
+
+// CreatePlanPauseByUserId pauses the user's plan for a date range. Pending
+// occurrences from the start of the pause are regenerated so that paused
+// days get none and the rest of the program moves back.
+func (h *Handler) CreatePlanPauseByUserId(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	profile, ok := h.findProfileByUserId(w, r, vars["userId"])
+	if !ok {
+		return
+	}
+
+	var pause models.PlanPause
+	if err := decodeJSON(r.Body, &pause); err != nil {
+		writeInvalid(w, r, err)
+		return
+	}
+
+	today := profile.Today()
+	if pause.StartDate.IsZero() || pause.EndDate.IsZero() {
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidRequest, "startDate and endDate are required")
+		return
+	}
+	if pause.EndDate.Before(pause.StartDate) {
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidRequest, "endDate must not be before startDate")
+		return
+	}
+	if pause.StartDate.Before(today) {
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidRequest, "A pause cannot start in the past")
+		return
+	}
+
+	now := time.Now()
+	pause.ID = 0
+	pause.UserID = profile.ID
+	pause.CreatedAt = now
+	pause.UpdatedAt = now
+
+	err := h.DB.RunInTransaction(r.Context(), func(tx *pg.Tx) error {
+		overlaps, err := tx.Model((*models.PlanPause)(nil)).
+			Where("user_id = ?", profile.ID).
+			Where("start_date <= ? AND end_date >= ?", pause.EndDate, pause.StartDate).
+			Exists()
+		if err != nil {
+			return err
+		}
+		if overlaps {
+			return errPauseOverlap
+		}
+
+		if _, err := tx.Model(&pause).Insert(); err != nil {
+			return err
+		}
+		if err := schedule.SkipPaused(tx, &pause); err != nil {
+			return err
+		}
+		if err := schedule.ClearPending(tx, profile.ID, pause.StartDate); err != nil {
+			return err
+		}
+		return schedule.Refresh(tx, profile.ID)
+	})
+	if err != nil {
+		if err == errPauseOverlap {
+			problem.Error(w, r, http.StatusConflict, problem.CodeConflict, err.Error())
+			return
+		}
+		h.writeError(w, r, err, "Failed to create plan pause")
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, pause)
+}
+
+var pauseKeyset = paging.Asc(paging.DateColumn("start_date"), paging.IntColumn("id"))
+
+func (h *Handler) GetPlanPausesByUserId(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	profile, ok := h.findProfileByUserId(w, r, vars["userId"])
+	if !ok {
+		return
+	}
+
+	params, err := paging.FromRequest(r, pauseKeyset)
+	if err != nil {
+		writeInvalid(w, r, err)
+		return
+	}
+
+	var pauses []models.PlanPause
+	query := h.DB.Model(&pauses).Where("user_id = ?", profile.ID)
+	total, err := params.Total(query)
+	if err == nil {
+		err = params.Apply(query).Select()
+	}
+	if err != nil {
+		h.writeError(w, r, err, "Failed to list plan pauses")
+		return
+	}
+
+	pauses, next := paging.Trim(params, pauses, func(p *models.PlanPause) []string {
+		return []string{p.StartDate.String(), strconv.Itoa(p.ID)}
+	})
+	writePage(w, r, pauses, next, total)
+}
+
+// ResumePlanPause ends an active pause as of yesterday, or cancels a pause
+// that has not started yet. The program timeline is shortened to match.
+func (h *Handler) ResumePlanPause(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		h.Logger.Error("Invalid ID format", zap.String("id", vars["id"]))
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidRequest, "Invalid ID format")
+		return
+	}
+
+	pause := &models.PlanPause{ID: id}
+	err = h.DB.RunInTransaction(r.Context(), func(tx *pg.Tx) error {
+		if err := tx.Model(pause).WherePK().For("UPDATE").Select(); err != nil {
+			return err
+		}
+		today, err := schedule.Today(tx, pause.UserID)
+		if err != nil {
+			return err
+		}
+		if pause.EndDate.Before(today) {
+			// Already over, nothing to resume
+			return nil
+		}
+
+		if pause.StartDate.Before(today) {
+			pause.EndDate = today.AddDays(-1)
+			pause.UpdatedAt = time.Now()
+			if _, err := tx.Model(pause).Column("end_date", "updated_at").WherePK().Update(); err != nil {
+				return err
+			}
+		} else {
+			if _, err := tx.Model(pause).WherePK().Delete(); err != nil {
+				return err
+			}
+			pause.EndDate = pause.StartDate.AddDays(-1)
+		}
+		if err := schedule.ClearPending(tx, pause.UserID, today); err != nil {
+			return err
+		}
+		return schedule.Refresh(tx, pause.UserID)
+	})
+	if err != nil {
+		if err == pg.ErrNoRows {
+			problem.Error(w, r, http.StatusNotFound, problem.CodeNotFound, "Plan pause not found")
+			return
+		}
+		h.writeError(w, r, err, "Failed to resume plan")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, pause)
+}
+
+// GetStatsByUserId returns the user's streaks and adherence, with paused
+// days excluded.
+func (h *Handler) GetStatsByUserId(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	profile, ok := h.findProfileByUserId(w, r, vars["userId"])
+	if !ok {
+		return
+	}
+
+	days := defaultStatsDays
+	if s := r.URL.Query().Get("days"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > schedule.MaxWindowDays {
+			problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidRequest, fmt.Sprintf("days must be between 1 and %d", schedule.MaxWindowDays))
+			return
+		}
+		days = n
+	}
+
+	stats, err := schedule.ComputeStats(h.DB, profile.ID, profile.Today(), days)
+	if err != nil {
+		h.writeError(w, r, err, "Failed to compute stats")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, stats)
+}
@@ -0,0 +1,56 @@
+// handlers/response.go
+package handlers
+
+import (
+	"encoding/json"
+	"net/http"
+	"reflect"
+
+	"back-end/fields"
+	"back-end/paging"
+	"back-end/problem"
+	"back-end/requestid"
+
+	"go.uber.org/zap"
+)
+
+// writeJSON sends v as a JSON response with the given status.
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeError answers with the problem err maps to, such as 404 for a
+// missing row or 409 for a unique violation. Any other error is logged
+// under msg and answered with a 500 whose detail is msg, so database
+// errors never reach the client.
+func (h *Handler) writeError(w http.ResponseWriter, r *http.Request, err error, msg string) {
+	if p := problem.From(err); p != nil {
+		problem.Write(w, r, p)
+		return
+	}
+	h.Logger.Error(msg, zap.Error(err), zap.String("request_id", requestid.FromContext(r.Context())))
+	problem.Error(w, r, http.StatusInternalServerError, problem.CodeInternal, msg)
+}
+
+// writePage sends one page of a list in the paging envelope, with a Link
+// header to the next page when there is one.
+func writePage(w http.ResponseWriter, r *http.Request, data interface{}, next string, total *int) {
+	if next != "" {
+		w.Header().Set("Link", paging.Link(r, next))
+	}
+	writeJSON(w, http.StatusOK, paging.NewPage(data, next, total))
+}
+
+// requestedFields returns the fields asked for with ?fields=, checked
+// against the JSON fields of sample, the resource being read. It writes a
+// 422 response if a name is unknown.
+func requestedFields(w http.ResponseWriter, r *http.Request, sample interface{}) (fields.Selection, bool) {
+	selection, err := fields.Parse(r.URL.Query().Get("fields"), reflect.TypeOf(sample))
+	if err != nil {
+		writeInvalid(w, r, err)
+		return nil, false
+	}
+	return selection, true
+}
@@ -0,0 +1,197 @@
+// handlers/schedule.go
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"back-end/models"
+	"back-end/paging"
+	"back-end/problem"
+	"back-end/schedule"
+
+	"github.com/go-pg/pg/v10"
+	"github.com/go-pg/pg/v10/orm"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+// defaultScheduleDays is the window returned when no range is given.
+const defaultScheduleDays = 7
+
+// parseDateRange reads ?from= and ?to= (YYYY-MM-DD), defaulting to the week
+// starting today.
+func parseDateRange(r *http.Request, today models.Date) (models.Date, models.Date, error) {
+	from, to := today, today.AddDays(defaultScheduleDays-1)
+
+	var err error
+	if v := r.URL.Query().Get("from"); v != "" {
+		if from, err = models.ParseDate(v); err != nil {
+			return from, to, err
+		}
+		to = from.AddDays(defaultScheduleDays - 1)
+	}
+	if v := r.URL.Query().Get("to"); v != "" {
+		if to, err = models.ParseDate(v); err != nil {
+			return from, to, err
+		}
+	}
+
+	if to.Before(from) {
+		return from, to, fmt.Errorf("to must not be before from")
+	}
+	if to.Sub(from.Time) > schedule.MaxWindowDays*24*time.Hour {
+		return from, to, fmt.Errorf("date range must not exceed %d days", schedule.MaxWindowDays)
+	}
+	return from, to, nil
+}
+
+// GetScheduleByUserId returns the user's task occurrences within a date
+// range, expanding recurring tasks as needed.
+func (h *Handler) GetScheduleByUserId(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	profile, ok := h.findProfileByUserId(w, r, vars["userId"])
+	if !ok {
+		return
+	}
+
+	from, to, err := parseDateRange(r, profile.Today())
+	if err != nil {
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidRequest, err.Error())
+		return
+	}
+
+	occurrences, err := schedule.Occurrences(h.DB, profile.ID, from, to)
+	if err != nil {
+		h.writeError(w, r, err, "Failed to load schedule")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, occurrences)
+}
+
+func (h *Handler) CompleteOccurrence(w http.ResponseWriter, r *http.Request) {
+	h.setOccurrenceStatus(w, r, models.OccurrenceCompleted)
+}
+
+func (h *Handler) SkipOccurrence(w http.ResponseWriter, r *http.Request) {
+	h.setOccurrenceStatus(w, r, models.OccurrenceSkipped)
+}
+
+// setOccurrenceStatus records the outcome of an occurrence. Completing the
+// only occurrence of a non-recurring task also completes the task.
+func (h *Handler) setOccurrenceStatus(w http.ResponseWriter, r *http.Request, status string) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		h.Logger.Error("Invalid ID format", zap.String("id", vars["id"]))
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidRequest, "Invalid ID format")
+		return
+	}
+
+	occurrence := &models.TaskOccurrence{ID: id}
+	err = h.DB.RunInTransaction(r.Context(), func(tx *pg.Tx) error {
+		if err := tx.Model(occurrence).WherePK().Relation("Task").Select(); err != nil {
+			return err
+		}
+		return applyOccurrenceStatus(tx, occurrence, status)
+	})
+	if err != nil {
+		if err == pg.ErrNoRows {
+			problem.Error(w, r, http.StatusNotFound, problem.CodeNotFound, "Occurrence not found")
+			return
+		}
+		h.writeError(w, r, err, "Failed to update occurrence")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, occurrence)
+}
+
+// applyOccurrenceStatus saves the status of an occurrence loaded with its
+// task, completing a non-recurring task along with its only occurrence.
+func applyOccurrenceStatus(db orm.DB, occurrence *models.TaskOccurrence, status string) error {
+	now := time.Now()
+	occurrence.Status = status
+	occurrence.UpdatedAt = now
+	occurrence.CompletedAt = nil
+	if status == models.OccurrenceCompleted {
+		occurrence.CompletedAt = &now
+	}
+	_, err := db.Model(occurrence).
+		Column("status", "completed_at", "updated_at").
+		WherePK().
+		Returning("version").
+		Update()
+	if err != nil {
+		return err
+	}
+
+	if occurrence.Task != nil && occurrence.Task.Recurrence == "" {
+		occurrence.Task.Completed = status == models.OccurrenceCompleted
+		occurrence.Task.UpdatedAt = now
+		_, err = db.Model(occurrence.Task).
+			Column("completed", "updated_at").
+			WherePK().
+			Returning("version").
+			Update()
+	}
+	return err
+}
+
+// RescheduleMissedByUserId applies the user's missed workout policy now
+// instead of waiting for the background job.
+func (h *Handler) RescheduleMissedByUserId(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	profile, ok := h.findProfileByUserId(w, r, vars["userId"])
+	if !ok {
+		return
+	}
+
+	events, err := schedule.Reschedule(r.Context(), h.DB, profile, profile.Today())
+	if err != nil {
+		h.writeError(w, r, err, "Failed to reschedule missed workouts")
+		return
+	}
+
+	if events == nil {
+		events = []models.RescheduleEvent{}
+	}
+	writeJSON(w, http.StatusOK, events)
+}
+
+var rescheduleEventKeyset = paging.Desc(paging.TimeColumn("created_at"), paging.IntColumn("id"))
+
+// GetRescheduleEventsByUserId lists what the rescheduler has skipped or
+// moved for the user, newest first.
+func (h *Handler) GetRescheduleEventsByUserId(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	profile, ok := h.findProfileByUserId(w, r, vars["userId"])
+	if !ok {
+		return
+	}
+
+	params, err := paging.FromRequest(r, rescheduleEventKeyset)
+	if err != nil {
+		writeInvalid(w, r, err)
+		return
+	}
+
+	var events []models.RescheduleEvent
+	query := h.DB.Model(&events).Where("user_id = ?", profile.ID)
+	total, err := params.Total(query)
+	if err == nil {
+		err = params.Apply(query).Select()
+	}
+	if err != nil {
+		h.writeError(w, r, err, "Failed to list reschedule events")
+		return
+	}
+
+	events, next := paging.Trim(params, events, func(e *models.RescheduleEvent) []string {
+		return []string{e.CreatedAt.Format(time.RFC3339Nano), strconv.Itoa(e.ID)}
+	})
+	writePage(w, r, events, next, total)
+}
@@ -0,0 +1,76 @@
+package handlers_test
+
+import (
+	"context"
+	"net/http"
+	"testing"
+
+	"back-end/schedule"
+)
+
+func TestBackdatedTaskHasNoMissedHistory(t *testing.T) {
+	db, api := testServer(t)
+	profile := createProfile(t, db, api)
+
+	today := profile.Today()
+	start := today.AddDays(-60)
+	call(t, api, "POST", "/v1/tasks", map[string]interface{}{
+		"userId":       profile.ID,
+		"name":         "Plank",
+		"sets":         3,
+		"reps":         1,
+		"scheduledFor": start,
+		"recurrence":   "FREQ=DAILY",
+	}, nil, http.StatusCreated, nil)
+
+	past, err := schedule.LoadOccurrences(db, profile.ID, start, today.AddDays(-1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(past) != 0 {
+		t.Fatalf("task starting 60 days ago has %d past occurrences", len(past))
+	}
+	upcoming, err := schedule.LoadOccurrences(db, profile.ID, today, today.AddDays(6))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(upcoming) != 7 {
+		t.Fatalf("got %d occurrences in the coming week, want 7", len(upcoming))
+	}
+
+	// The rescheduler finds nothing to move either
+	events, err := schedule.Reschedule(context.Background(), db, &profile, today)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 0 {
+		t.Fatalf("rescheduler acted on %d occurrences", len(events))
+	}
+
+	stats, err := schedule.ComputeStats(db, profile.ID, today, 30)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats.Adherence.Missed != 0 {
+		t.Fatalf("stats count %d missed workouts", stats.Adherence.Missed)
+	}
+	if stats.Adherence.Scheduled != 0 {
+		t.Fatalf("stats count %d scheduled workouts before the task existed", stats.Adherence.Scheduled)
+	}
+}
+
+func TestStatsDays(t *testing.T) {
+	db, api := testServer(t)
+	profile := createProfile(t, db, api)
+
+	path := "/v1/profiles/user/" + profile.UserID + "/stats"
+	for query, want := range map[string]int{
+		"":           http.StatusOK,
+		"?days=7":    http.StatusOK,
+		"?days=7abc": http.StatusBadRequest,
+		"?days=abc":  http.StatusBadRequest,
+		"?days=0":    http.StatusBadRequest,
+	} {
+		call(t, api, "GET", path+query, nil, nil, want, nil)
+	}
+}
@@ -0,0 +1,105 @@
+package handlers_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"testing"
+
+	"back-end/app"
+	"back-end/handlers"
+	"back-end/models"
+
+	"github.com/go-pg/pg/v10"
+	"github.com/google/uuid"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+// testServer serves the API on the database TEST_DATABASE_URL names,
+// migrated like on start-up. Tests that need Postgres are skipped without
+// one.
+func testServer(t *testing.T) (*pg.DB, http.Handler) {
+	t.Helper()
+	url := os.Getenv("TEST_DATABASE_URL")
+	if url == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+	opt, err := pg.ParseURL(url)
+	if err != nil {
+		t.Fatalf("TEST_DATABASE_URL: %v", err)
+	}
+	db := pg.Connect(opt)
+	t.Cleanup(func() { db.Close() })
+
+	files, err := filepath.Glob("../db/migrations/*.sql")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(files)
+	for _, file := range files {
+		migration, err := os.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := db.Exec(string(migration)); err != nil {
+			t.Fatalf("%s: %v", file, err)
+		}
+	}
+
+	router := mux.NewRouter()
+	app.RegisterRoutes(router, &handlers.Handler{DB: db, Logger: zap.NewNop()})
+	return db, router
+}
+
+// call sends a request to the API, decodes a JSON response into out when
+// it is set and fails the test unless the status is want.
+func call(t *testing.T, h http.Handler, method, path string, body interface{}, header http.Header, want int, out interface{}) *httptest.ResponseRecorder {
+	t.Helper()
+	var b bytes.Buffer
+	if body != nil {
+		if err := json.NewEncoder(&b).Encode(body); err != nil {
+			t.Fatal(err)
+		}
+	}
+	r := httptest.NewRequest(method, path, &b)
+	r.Header.Set("Content-Type", "application/json")
+	for name, values := range header {
+		r.Header[name] = values
+	}
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, r)
+	if w.Code != want {
+		t.Fatalf("%s %s: status %d, want %d: %s", method, path, w.Code, want, w.Body.String())
+	}
+	if out != nil {
+		if err := json.Unmarshal(w.Body.Bytes(), out); err != nil {
+			t.Fatalf("%s %s: %v", method, path, err)
+		}
+	}
+	return w
+}
+
+// createProfile creates a profile for a new auth user, removed again when
+// the test ends.
+func createProfile(t *testing.T, db *pg.DB, api http.Handler) models.UserProfile {
+	t.Helper()
+	var profile models.UserProfile
+	call(t, api, "POST", "/v1/profiles", map[string]interface{}{
+		"user_id":                  uuid.NewString(),
+		"age":                      30,
+		"weight":                   70,
+		"height":                   175,
+		"fitnessLevel":             "intermediate",
+		"preferredWorkoutDuration": 45,
+		"workoutDaysPerWeek":       4,
+	}, nil, http.StatusCreated, &profile)
+	t.Cleanup(func() {
+		db.Model(&models.UserProfile{ID: profile.ID}).WherePK().ForceDelete()
+	})
+	return profile
+}
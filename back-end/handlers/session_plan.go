@@ -0,0 +1,244 @@
+// handlers/session_plan.go
+package handlers
+
+import (
+	"net/http"
+	"time"
+
+	"back-end/models"
+	"back-end/planner"
+
+	"github.com/go-pg/pg/v10"
+	"github.com/gorilla/mux"
+)
+
+type SessionPlan struct {
+	Blocks                   []planner.Block  `json:"blocks"`
+	Estimate                 planner.Estimate `json:"estimate"`
+	PreferredWorkoutDuration int              `json:"preferredWorkoutDuration"`
+	FitsPreferredDuration    bool             `json:"fitsPreferredDuration"`
+}
+
+type FitWorkoutRequest struct {
+	Tasks    []models.WorkoutTask `json:"tasks"`
+	Warmup   bool                 `json:"warmup"`
+	Cooldown bool                 `json:"cooldown"`
+}
+
+type GenerateWarmupRequest struct {
+	Warmup   bool `json:"warmup"`
+	Cooldown bool `json:"cooldown"`
+}
+
+// withWarmup surrounds the blocks with generated warm-up and cool-down
+// tasks as requested.
+func withWarmup(blocks []planner.Block, warmup, cooldown bool) []planner.Block {
+	var out []planner.Block
+	if warmup {
+		for _, task := range planner.GenerateWarmup(blocks) {
+			out = append(out, planner.Block{Tasks: []models.WorkoutTask{task}})
+		}
+	}
+	out = append(out, blocks...)
+	if cooldown {
+		for _, task := range planner.GenerateCooldown(blocks) {
+			out = append(out, planner.Block{Tasks: []models.WorkoutTask{task}})
+		}
+	}
+	return out
+}
+
+// loadSessionBlocks returns the profile's unfinished plan tasks and its
+// groups arranged in session order. Completed tasks, such as imported
+// history, are not part of the session.
+func (h *Handler) loadSessionBlocks(profileID int) ([]planner.Block, error) {
+	var tasks []models.WorkoutTask
+	err := h.DB.Model(&tasks).
+		Where("user_id = ?", profileID).
+		Where("source = ?", models.TaskSourcePlan).
+		Where("completed = FALSE").
+		Select()
+	if err != nil {
+		return nil, err
+	}
+	var groups []models.WorkoutGroup
+	if err := h.DB.Model(&groups).Where("user_id = ?", profileID).Select(); err != nil {
+		return nil, err
+	}
+	return planner.Blocks(tasks, groups), nil
+}
+
+// GetSessionPlanByUserId returns the user's session in order together with
+// its estimated duration.
+func (h *Handler) GetSessionPlanByUserId(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	profile, ok := h.findProfileByUserId(w, r, vars["userId"])
+	if !ok {
+		return
+	}
+
+	blocks, err := h.loadSessionBlocks(profile.ID)
+	if err != nil {
+		h.writeError(w, r, err, "Failed to load session")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, newSessionPlan(profile, blocks))
+}
+
+// newSessionPlan estimates the blocks and checks them against the
+// profile's preferred workout duration.
+func newSessionPlan(profile *models.UserProfile, blocks []planner.Block) SessionPlan {
+	est := planner.EstimateSession(blocks, planner.DefaultOptions)
+	return SessionPlan{
+		Blocks:                   blocks,
+		Estimate:                 est,
+		PreferredWorkoutDuration: profile.PreferredWorkoutDuration,
+		FitsPreferredDuration:    profile.PreferredWorkoutDuration <= 0 || est.TotalMinutes <= profile.PreferredWorkoutDuration,
+	}
+}
+
+// FitGeneratedWorkout estimates a generated workout and, when it runs over
+// the user's PreferredWorkoutDuration, trims accessory work or pairs it
+// into supersets until it fits. Nothing is persisted.
+func (h *Handler) FitGeneratedWorkout(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	profile, ok := h.findProfileByUserId(w, r, vars["userId"])
+	if !ok {
+		return
+	}
+
+	var req FitWorkoutRequest
+	if err := decodeJSON(r.Body, &req); err != nil {
+		writeInvalid(w, r, err)
+		return
+	}
+
+	blocks := make([]planner.Block, len(req.Tasks))
+	for i, task := range req.Tasks {
+		task.UserID = profile.ID
+		task.Category = models.TaskCategoryExercise
+		blocks[i] = planner.Block{Tasks: []models.WorkoutTask{task}}
+	}
+	blocks = withWarmup(blocks, req.Warmup, req.Cooldown)
+
+	writeJSON(w, http.StatusOK, planner.Fit(blocks, profile.PreferredWorkoutDuration, planner.DefaultOptions))
+}
+
+// GenerateSessionWarmupByUserId replaces the user's generated warm-up and
+// cool-down tasks with fresh ones derived from the current session, the
+// unfinished plan tasks. The warm-up is placed before the session and the
+// cool-down after; completed tasks keep their positions.
+func (h *Handler) GenerateSessionWarmupByUserId(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	profile, ok := h.findProfileByUserId(w, r, vars["userId"])
+	if !ok {
+		return
+	}
+
+	var req GenerateWarmupRequest
+	if err := decodeJSON(r.Body, &req); err != nil {
+		writeInvalid(w, r, err)
+		return
+	}
+
+	err := h.DB.RunInTransaction(r.Context(), func(tx *pg.Tx) error {
+		if err := lockTaskOrder(tx, profile.ID); err != nil {
+			return err
+		}
+
+		// Generated tasks are replaced rather than kept in the trash
+		var replaced []models.WorkoutTask
+		_, err := tx.Model(&replaced).
+			Where("user_id = ?", profile.ID).
+			Where("category IN (?)", pg.In([]string{models.TaskCategoryWarmup, models.TaskCategoryCooldown})).
+			Where("completed = FALSE").
+			Returning("*").
+			ForceDelete()
+		if err != nil {
+			return err
+		}
+		if err := auditTasks(tx, models.AuditDelete, replaced, replaced); err != nil {
+			return err
+		}
+
+		var tasks []models.WorkoutTask
+		err = tx.Model(&tasks).
+			Where("user_id = ?", profile.ID).
+			Where("source = ?", models.TaskSourcePlan).
+			Where("completed = FALSE").
+			Order("position ASC", "id ASC").
+			For("UPDATE").
+			Select()
+		if err != nil {
+			return err
+		}
+
+		// The warm-up takes the session's place and the session moves down
+		start := 0
+		if len(tasks) > 0 {
+			start = tasks[0].Position
+		} else if start, err = nextTaskPosition(tx, profile.ID); err != nil {
+			return err
+		}
+
+		session := planner.Blocks(tasks, nil)
+		var warmup, cooldown []models.WorkoutTask
+		if req.Warmup {
+			warmup = planner.GenerateWarmup(session)
+		}
+		if req.Cooldown {
+			cooldown = planner.GenerateCooldown(session)
+		}
+
+		now := time.Now()
+		insert := func(task models.WorkoutTask, position int) error {
+			task.UserID = profile.ID
+			task.Position = position
+			task.Source = models.TaskSourcePlan
+			task.CreatedAt = now
+			task.UpdatedAt = now
+			if _, err := tx.Model(&task).Insert(); err != nil {
+				return err
+			}
+			return auditTask(tx, models.AuditCreate, nil, &task)
+		}
+
+		for i, task := range warmup {
+			if err := insert(task, start+i); err != nil {
+				return err
+			}
+		}
+		for i, task := range tasks {
+			position := start + len(warmup) + i
+			if task.Position == position {
+				continue
+			}
+			before := task
+			_, err := tx.Model(&task).
+				Set("position = ?", position).
+				Set("updated_at = ?", now).
+				WherePK().
+				Returning("*").
+				Update()
+			if err != nil {
+				return err
+			}
+			if err := auditTask(tx, models.AuditReorder, &before, &task); err != nil {
+				return err
+			}
+		}
+		for i, task := range cooldown {
+			if err := insert(task, start+len(warmup)+len(tasks)+i); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		h.writeError(w, r, err, "Failed to generate warm-up")
+		return
+	}
+
+	h.GetSessionPlanByUserId(w, r)
+}
@@ -0,0 +1,592 @@
+// handlers/sync.go
+package handlers
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"back-end/models"
+	"back-end/problem"
+	"back-end/schedule"
+	"back-end/units"
+	"back-end/validate"
+
+	"github.com/go-pg/pg/v10"
+)
+
+const (
+	// syncLockClass is the first key of the per-user advisory lock taken
+	// by the sync_track trigger. It must match migration 011.
+	syncLockClass = 7001
+
+	defaultSyncLimit = 500
+	maxSyncLimit     = 1000
+	maxSyncMutations = 500
+)
+
+// Mutation operations and result statuses
+const (
+	SyncOpUpsert = "upsert"
+	SyncOpDelete = "delete"
+
+	SyncApplied  = "applied"
+	SyncConflict = "conflict"
+	SyncRejected = "rejected"
+)
+
+type SyncChanges struct {
+	Profiles    []models.UserProfile    `json:"profiles"`
+	Tasks       []models.WorkoutTask    `json:"tasks"`
+	Groups      []models.WorkoutGroup   `json:"groups"`
+	Occurrences []models.TaskOccurrence `json:"occurrences"`
+	Deleted     []models.SyncTombstone  `json:"deleted"`
+}
+
+type SyncPullResponse struct {
+	Cursor  string      `json:"cursor"`
+	HasMore bool        `json:"hasMore"`
+	Changes SyncChanges `json:"changes"`
+}
+
+// SyncMutation is one offline change. Version is the version of the record
+// the client edited; it is ignored when creating a record (ID 0).
+type SyncMutation struct {
+	ClientID string          `json:"clientId,omitempty"`
+	Entity   string          `json:"entity"`
+	Op       string          `json:"op"`
+	ID       int             `json:"id,omitempty"`
+	Version  int             `json:"version,omitempty"`
+	Data     json.RawMessage `json:"data,omitempty"`
+}
+
+type SyncPushRequest struct {
+	Mutations []SyncMutation `json:"mutations"`
+}
+
+// SyncResult reports the outcome of one mutation. Record holds the server
+// copy after an applied upsert, or the current server copy on a conflict.
+// Deleted is set on a conflict with a record that no longer exists.
+type SyncResult struct {
+	ClientID string          `json:"clientId,omitempty"`
+	Entity   string          `json:"entity"`
+	ID       int             `json:"id,omitempty"`
+	Status   string          `json:"status"`
+	Version  int             `json:"version,omitempty"`
+	Error    string          `json:"error,omitempty"`
+	Errors   validate.Errors `json:"errors,omitempty"`
+	Deleted  bool            `json:"deleted,omitempty"`
+	Record   interface{}     `json:"record,omitempty"`
+}
+
+type SyncPushResponse struct {
+	Results []SyncResult `json:"results"`
+}
+
+// syncHead returns the highest sequence value every change of the user up
+// to which has been committed. Taking the user's sync lock exclusively
+// waits out writers that already hold a sequence value; later writers get
+// a higher one.
+func (h *Handler) syncHead(r *http.Request, userID int) (int64, error) {
+	var head int64
+	err := h.DB.RunInTransaction(r.Context(), func(tx *pg.Tx) error {
+		if _, err := tx.Exec("SELECT pg_advisory_xact_lock(?, ?)", syncLockClass, userID); err != nil {
+			return err
+		}
+		_, err := tx.QueryOne(pg.Scan(&head), "SELECT CASE WHEN is_called THEN last_value ELSE 0 END FROM sync_seq")
+		return err
+	})
+	return head, err
+}
+
+// GetMySyncChanges returns the signed-in user's records created, updated or
+// deleted since the given cursor, oldest first. Without a cursor every
+// record is returned. When hasMore is set the client should pull again
+// with the returned cursor.
+func (h *Handler) GetMySyncChanges(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+	profile, ok := h.findProfileByUserId(w, r, userID)
+	if !ok {
+		return
+	}
+
+	var since int64
+	if s := r.URL.Query().Get("since"); s != "" {
+		n, err := strconv.ParseInt(s, 10, 64)
+		if err != nil || n < 0 {
+			problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidRequest, "Invalid sync cursor")
+			return
+		}
+		since = n
+	}
+	limit := defaultSyncLimit
+	if s := r.URL.Query().Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 1 || n > maxSyncLimit {
+			problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidRequest, fmt.Sprintf("limit must be between 1 and %d", maxSyncLimit))
+			return
+		}
+		limit = n
+	}
+
+	head, err := h.syncHead(r, profile.ID)
+	if err != nil {
+		h.writeError(w, r, err, "Failed to sync")
+		return
+	}
+	if since > head {
+		// The cursor was not issued by this server; start over
+		problem.Error(w, r, http.StatusGone, problem.CodeGone, "Sync cursor is no longer valid, sync again without one")
+		return
+	}
+
+	// Find the page boundary across all synced tables
+	var seqs []int64
+	_, err = h.DB.Query(&seqs, `
+		SELECT sync_seq FROM (
+			SELECT sync_seq FROM user_profiles WHERE id = ?0
+			UNION ALL SELECT sync_seq FROM workout_tasks WHERE user_id = ?0
+			UNION ALL SELECT sync_seq FROM workout_groups WHERE user_id = ?0
+			UNION ALL SELECT sync_seq FROM task_occurrences WHERE user_id = ?0
+			UNION ALL SELECT sync_seq FROM sync_tombstones WHERE user_id = ?0
+		) AS changes
+		WHERE sync_seq > ?1 AND sync_seq <= ?2
+		ORDER BY sync_seq ASC
+		LIMIT ?3`, profile.ID, since, head, limit+1)
+	if err != nil {
+		h.writeError(w, r, err, "Failed to sync")
+		return
+	}
+
+	resp := SyncPullResponse{Cursor: strconv.FormatInt(head, 10)}
+	upper := head
+	if len(seqs) > limit {
+		upper = seqs[limit-1]
+		resp.Cursor = strconv.FormatInt(upper, 10)
+		resp.HasMore = true
+	}
+
+	changes := &resp.Changes
+	queries := []struct {
+		model interface{}
+		owner string
+	}{
+		{&changes.Profiles, "id"},
+		{&changes.Tasks, "user_id"},
+		{&changes.Groups, "user_id"},
+		{&changes.Occurrences, "user_id"},
+		{&changes.Deleted, "user_id"},
+	}
+	for _, q := range queries {
+		err := h.DB.Model(q.model).
+			Where("? = ?", pg.Ident(q.owner), profile.ID).
+			Where("sync_seq > ? AND sync_seq <= ?", since, upper).
+			Order("sync_seq ASC").
+			Select()
+		if err != nil {
+			h.writeError(w, r, err, "Failed to sync")
+			return
+		}
+	}
+	// Synced measurements are always metric so clients can store them as is
+	for i := range changes.Profiles {
+		renderProfile(&changes.Profiles[i], units.Metric)
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// PushMySyncChanges applies a batch of offline mutations in order.
+// Updates and deletes only apply when the client edited the current
+// version of a record; otherwise the result is a conflict carrying the
+// server's copy for the client to resolve and resend.
+func (h *Handler) PushMySyncChanges(w http.ResponseWriter, r *http.Request) {
+	userID, ok := currentUserID(w, r)
+	if !ok {
+		return
+	}
+	profile, ok := h.findProfileByUserId(w, r, userID)
+	if !ok {
+		return
+	}
+
+	var req SyncPushRequest
+	if err := decodeJSON(r.Body, &req); err != nil {
+		writeInvalid(w, r, err)
+		return
+	}
+	if len(req.Mutations) > maxSyncMutations {
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidRequest, fmt.Sprintf("At most %d mutations can be sent at once", maxSyncMutations))
+		return
+	}
+
+	var resp SyncPushResponse
+	err := h.DB.RunInTransaction(r.Context(), func(tx *pg.Tx) error {
+		resp.Results = make([]SyncResult, 0, len(req.Mutations))
+		s := &syncApplier{tx: tx, profile: profile}
+		for _, m := range req.Mutations {
+			res, err := s.apply(m)
+			if err != nil {
+				return err
+			}
+			resp.Results = append(resp.Results, res)
+		}
+		return nil
+	})
+	if err != nil {
+		h.writeError(w, r, err, "Failed to sync")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, resp)
+}
+
+// decodeData decodes the data of a mutation like a request body.
+func decodeData(m SyncMutation, dst interface{}) error {
+	err := decodeJSON(bytes.NewReader(m.Data), dst)
+	if err == errInvalidBody {
+		return rejectf("invalid %s data", m.Entity)
+	}
+	return err
+}
+
+// syncApplier applies one user's mutations inside a transaction.
+type syncApplier struct {
+	tx      *pg.Tx
+	profile *models.UserProfile
+}
+
+// errRejected marks a mutation the server refuses; its message is
+// returned to the client.
+type errRejected struct{ msg string }
+
+func (e errRejected) Error() string { return e.msg }
+
+func rejectf(format string, args ...interface{}) error {
+	return errRejected{fmt.Sprintf(format, args...)}
+}
+
+func (s *syncApplier) apply(m SyncMutation) (SyncResult, error) {
+	res := SyncResult{ClientID: m.ClientID, Entity: m.Entity, ID: m.ID}
+	if m.Op != SyncOpUpsert && m.Op != SyncOpDelete {
+		res.Status, res.Error = SyncRejected, "op must be upsert or delete"
+		return res, nil
+	}
+
+	var err error
+	switch m.Entity {
+	case models.SyncEntityTask:
+		err = s.task(m, &res)
+	case models.SyncEntityGroup:
+		err = s.group(m, &res)
+	case models.SyncEntityProfile:
+		err = s.userProfile(m, &res)
+	case models.SyncEntityOccurrence:
+		err = s.occurrence(m, &res)
+	default:
+		err = rejectf("unsupported entity %q", m.Entity)
+	}
+
+	var rejected errRejected
+	if errors.As(err, &rejected) {
+		res.Status, res.Error, res.Record = SyncRejected, rejected.msg, nil
+		return res, nil
+	}
+	var invalid validate.Errors
+	if errors.As(err, &invalid) {
+		res.Status, res.Error, res.Errors, res.Record = SyncRejected, "validation failed", invalid, nil
+		return res, nil
+	}
+	return res, err
+}
+
+// load locks the user's record with the given id. It reports false when
+// the record does not exist.
+func (s *syncApplier) load(model interface{}, id int, relation string) (bool, error) {
+	q := s.tx.Model(model).Where("?TableAlias.id = ?", id)
+	if relation != "" {
+		q = q.Relation(relation).For("UPDATE OF ?TableAlias")
+	} else {
+		q = q.For("UPDATE")
+	}
+	if _, ok := model.(*models.UserProfile); ok {
+		q = q.Where("?TableAlias.id = ?", s.profile.ID)
+	} else {
+		q = q.Where("?TableAlias.user_id = ?", s.profile.ID)
+	}
+	err := q.Select()
+	if err == pg.ErrNoRows {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// checkVersion loads the record a mutation edits and reports whether it
+// may be applied, filling in a conflict result when it may not. A delete
+// of a record that is already gone succeeds.
+func (s *syncApplier) checkVersion(m SyncMutation, res *SyncResult, model interface{}, version func() int, relation string) (bool, error) {
+	found, err := s.load(model, m.ID, relation)
+	if err != nil {
+		return false, err
+	}
+	if !found {
+		if m.Op == SyncOpDelete {
+			res.Status = SyncApplied
+		} else {
+			res.Status, res.Deleted = SyncConflict, true
+		}
+		return false, nil
+	}
+	if version() != m.Version {
+		res.Status, res.Version, res.Record = SyncConflict, version(), model
+		return false, nil
+	}
+	return true, nil
+}
+
+func (s *syncApplier) remove(model interface{}, res *SyncResult) error {
+	if _, err := s.tx.Model(model).WherePK().Delete(); err != nil {
+		return err
+	}
+	res.Status = SyncApplied
+	return nil
+}
+
+func (s *syncApplier) applied(res *SyncResult, id, version int, record interface{}) {
+	res.Status, res.ID, res.Version, res.Record = SyncApplied, id, version, record
+}
+
+func (s *syncApplier) task(m SyncMutation, res *SyncResult) error {
+	existing := &models.WorkoutTask{}
+	if m.ID != 0 {
+		ok, err := s.checkVersion(m, res, existing, func() int { return existing.Version }, "")
+		if err != nil || !ok {
+			return err
+		}
+		if m.Op == SyncOpDelete {
+			// Deleted tasks go to the trash, as through the API
+			if _, err := trashTask(s.tx, existing.ID, existing.Version); err != nil {
+				return err
+			}
+			res.Status = SyncApplied
+			return nil
+		}
+	} else if m.Op == SyncOpDelete {
+		return rejectf("id is required to delete a task")
+	}
+
+	var task models.WorkoutTask
+	if err := decodeData(m, &task); err != nil {
+		return err
+	}
+	if task.Category == "" {
+		task.Category = existing.Category
+	}
+	if task.Category == "" {
+		task.Category = models.TaskCategoryExercise
+	}
+	task.UserID = s.profile.ID
+	if err := validateTask(&task); err != nil {
+		return err
+	}
+	if task.GroupID != nil {
+		exists, err := s.tx.Model((*models.WorkoutGroup)(nil)).
+			Where("id = ?", *task.GroupID).
+			Where("user_id = ?", s.profile.ID).
+			Exists()
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return rejectf("workout group %d not found", *task.GroupID)
+		}
+	}
+
+	now := time.Now()
+	task.UpdatedAt = now
+	if m.ID == 0 {
+		position, err := nextTaskPosition(s.tx, s.profile.ID)
+		if err != nil {
+			return err
+		}
+		task.ID = 0
+		task.Version = 0
+		task.Source = models.TaskSourcePlan
+		task.Position = position
+		task.CreatedAt = now
+		if _, err := s.tx.Model(&task).Insert(); err != nil {
+			return err
+		}
+		if err := auditTask(s.tx, models.AuditCreate, nil, &task); err != nil {
+			return err
+		}
+		if err := schedule.RefreshTask(s.tx, &task); err != nil {
+			return err
+		}
+		s.applied(res, task.ID, task.Version, &task)
+		return nil
+	}
+
+	// Positions only change through the reorder endpoint
+	task.ID = existing.ID
+	task.Source = existing.Source
+	task.Position = existing.Position
+	task.CreatedAt = existing.CreatedAt
+	if _, err := s.tx.Model(&task).WherePK().Returning("version").Update(); err != nil {
+		return err
+	}
+	if err := auditTask(s.tx, models.AuditUpdate, existing, &task); err != nil {
+		return err
+	}
+	if scheduleChanged(existing, &task) {
+		if err := schedule.ClearFuture(s.tx, task.ID, s.profile.Today()); err != nil {
+			return err
+		}
+		if err := schedule.RefreshTask(s.tx, &task); err != nil {
+			return err
+		}
+	}
+	s.applied(res, task.ID, task.Version, &task)
+	return nil
+}
+
+func (s *syncApplier) group(m SyncMutation, res *SyncResult) error {
+	existing := &models.WorkoutGroup{}
+	if m.ID != 0 {
+		ok, err := s.checkVersion(m, res, existing, func() int { return existing.Version }, "")
+		if err != nil || !ok {
+			return err
+		}
+		if m.Op == SyncOpDelete {
+			return s.remove(existing, res)
+		}
+	} else if m.Op == SyncOpDelete {
+		return rejectf("id is required to delete a group")
+	}
+
+	var group models.WorkoutGroup
+	if err := decodeData(m, &group); err != nil {
+		return err
+	}
+
+	// Membership is managed through the tasks themselves
+	group.UserID = s.profile.ID
+	group.Tasks = nil
+	group.ApplyDefaults()
+	if err := group.Validate(); err != nil {
+		return err
+	}
+
+	now := time.Now()
+	group.UpdatedAt = now
+	if m.ID == 0 {
+		group.ID = 0
+		group.Version = 0
+		group.CompletedRounds = 0
+		group.CreatedAt = now
+		if _, err := s.tx.Model(&group).Insert(); err != nil {
+			return err
+		}
+		s.applied(res, group.ID, group.Version, &group)
+		return nil
+	}
+
+	group.ID = existing.ID
+	group.CompletedRounds = existing.CompletedRounds
+	group.CreatedAt = existing.CreatedAt
+	if _, err := s.tx.Model(&group).WherePK().Returning("version").Update(); err != nil {
+		return err
+	}
+	s.applied(res, group.ID, group.Version, &group)
+	return nil
+}
+
+func (s *syncApplier) userProfile(m SyncMutation, res *SyncResult) error {
+	if m.Op == SyncOpDelete {
+		return rejectf("profiles cannot be deleted through sync")
+	}
+	if m.ID == 0 {
+		m.ID = s.profile.ID
+		res.ID = m.ID
+	}
+	if m.ID != s.profile.ID {
+		return rejectf("profile %d not found", m.ID)
+	}
+
+	existing := &models.UserProfile{}
+	ok, err := s.checkVersion(m, res, existing, func() int { return existing.Version }, "")
+	if err != nil || !ok {
+		if existing.ID != 0 {
+			renderProfile(existing, units.Metric)
+		}
+		return err
+	}
+
+	var profile models.UserProfile
+	if err := decodeData(m, &profile); err != nil {
+		return err
+	}
+	profile.ID = existing.ID
+	profile.UserID = existing.UserID
+	profile.CreatedAt = existing.CreatedAt
+	profile.UpdatedAt = time.Now()
+	profile.PreserveSettings(existing)
+	if err := profile.NormalizeUnits(); err != nil {
+		return err
+	}
+	if err := profile.Validate(); err != nil {
+		return err
+	}
+
+	if _, err := s.tx.Model(&profile).WherePK().Returning("version").Update(); err != nil {
+		return err
+	}
+	if err := auditProfile(s.tx, models.AuditUpdate, existing, &profile); err != nil {
+		return err
+	}
+	// Later mutations in the batch see the new settings
+	*s.profile = profile
+	result := profile
+	renderProfile(&result, units.Metric)
+	s.applied(res, profile.ID, profile.Version, &result)
+	return nil
+}
+
+func (s *syncApplier) occurrence(m SyncMutation, res *SyncResult) error {
+	if m.Op == SyncOpDelete {
+		return rejectf("occurrences cannot be deleted, skip them instead")
+	}
+	if m.ID == 0 {
+		return rejectf("id is required to update an occurrence")
+	}
+
+	var data struct {
+		Status string `json:"status"`
+	}
+	if err := decodeData(m, &data); err != nil {
+		return err
+	}
+	var v validate.Validator
+	v.OneOf("status", data.Status, models.OccurrenceScheduled, models.OccurrenceCompleted, models.OccurrenceSkipped)
+	if err := v.Err(); err != nil {
+		return err
+	}
+
+	occurrence := &models.TaskOccurrence{}
+	ok, err := s.checkVersion(m, res, occurrence, func() int { return occurrence.Version }, "Task")
+	if err != nil || !ok {
+		return err
+	}
+	if err := applyOccurrenceStatus(s.tx, occurrence, data.Status); err != nil {
+		return err
+	}
+	s.applied(res, occurrence.ID, occurrence.Version, occurrence)
+	return nil
+}
@@ -0,0 +1,255 @@
+// handlers/task_batch.go
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"back-end/models"
+	"back-end/problem"
+	"back-end/requestid"
+	"back-end/validate"
+
+	"github.com/go-pg/pg/v10"
+	"go.uber.org/zap"
+)
+
+// maxBatchOperations caps the operations of one batch request.
+const maxBatchOperations = 100
+
+// Batch operations
+const (
+	batchCreate   = "create"
+	batchUpdate   = "update"
+	batchComplete = "complete"
+	batchDelete   = "delete"
+)
+
+type TaskBatchRequest struct {
+	// Atomic applies every operation or none of them. Set it to false to
+	// have each operation succeed or fail on its own. Defaults to true.
+	Atomic     *bool                `json:"atomic"`
+	Operations []TaskBatchOperation `json:"operations"`
+}
+
+// TaskBatchOperation is one change in a batch. Update replaces the task
+// like PUT does. Version stands in for If-Match and is required by
+// update and delete; complete accepts it but does not need it.
+type TaskBatchOperation struct {
+	Op      string              `json:"op"`
+	ID      int                 `json:"id,omitempty"`
+	Version int                 `json:"version,omitempty"`
+	Task    *models.WorkoutTask `json:"task,omitempty"`
+}
+
+// TaskBatchResult is the outcome of one operation, with the status code
+// the single-task endpoint would have answered.
+type TaskBatchResult struct {
+	Index  int                 `json:"index"`
+	Status int                 `json:"status"`
+	Task   *models.WorkoutTask `json:"task,omitempty"`
+	Error  *problem.Problem    `json:"error,omitempty"`
+}
+
+type TaskBatchResponse struct {
+	Results []TaskBatchResult `json:"results"`
+}
+
+// validate checks the shape of the operation before anything runs.
+func (op *TaskBatchOperation) validate() error {
+	var v validate.Validator
+	switch op.Op {
+	case batchCreate:
+		v.Required("task", op.Task != nil)
+	case batchUpdate:
+		v.Required("id", op.ID > 0)
+		v.Required("version", op.Version > 0)
+		v.Required("task", op.Task != nil)
+	case batchComplete:
+		v.Required("id", op.ID > 0)
+	case batchDelete:
+		v.Required("id", op.ID > 0)
+		v.Required("version", op.Version > 0)
+	default:
+		v.Add("op", validate.CodeNotAllowed, "op must be one of %s, %s, %s, %s", batchCreate, batchUpdate, batchComplete, batchDelete)
+	}
+	if op.Task != nil && op.Op != batchCreate && op.Op != batchUpdate {
+		v.Add("task", validate.CodeNotAllowed, "task is only sent with create and update")
+	}
+	return v.Err()
+}
+
+// BatchWorkoutTasks applies many task changes in one request, such as all
+// the exercises of a generated workout. By default the batch runs in one
+// transaction and the first failing operation rolls back the rest; with
+// "atomic": false every operation is committed or rejected on its own
+// and the response reports each outcome.
+func (h *Handler) BatchWorkoutTasks(w http.ResponseWriter, r *http.Request) {
+	var req TaskBatchRequest
+	if err := decodeJSON(r.Body, &req); err != nil {
+		writeInvalid(w, r, err)
+		return
+	}
+
+	var v validate.Validator
+	if v.Required("operations", len(req.Operations) > 0) && len(req.Operations) > maxBatchOperations {
+		v.Add("operations", validate.CodeTooMany, "operations must have at most %d items", maxBatchOperations)
+	}
+	for i := range req.Operations {
+		v.Nested(fmt.Sprintf("operations[%d]", i), req.Operations[i].validate())
+	}
+	if err := v.Err(); err != nil {
+		writeInvalid(w, r, err)
+		return
+	}
+
+	if req.Atomic == nil || *req.Atomic {
+		h.runAtomicBatch(w, r, req.Operations)
+		return
+	}
+
+	results := make([]TaskBatchResult, len(req.Operations))
+	for i := range req.Operations {
+		var result TaskBatchResult
+		err := h.DB.RunInTransaction(r.Context(), func(tx *pg.Tx) error {
+			var err error
+			result, err = applyTaskOperation(tx, &req.Operations[i])
+			return err
+		})
+		if err != nil {
+			result = TaskBatchResult{Error: h.batchProblem(r, err)}
+			result.Status = result.Error.Status
+		}
+		result.Index = i
+		results[i] = result
+	}
+	writeJSON(w, http.StatusOK, TaskBatchResponse{Results: results})
+}
+
+// runAtomicBatch applies the operations in one transaction. A failure is
+// answered with the failing operation's problem, its field errors named
+// after the operation.
+func (h *Handler) runAtomicBatch(w http.ResponseWriter, r *http.Request, ops []TaskBatchOperation) {
+	results := make([]TaskBatchResult, 0, len(ops))
+	failed := -1
+	err := h.DB.RunInTransaction(r.Context(), func(tx *pg.Tx) error {
+		for i := range ops {
+			result, err := applyTaskOperation(tx, &ops[i])
+			if err != nil {
+				failed = i
+				return err
+			}
+			result.Index = i
+			results = append(results, result)
+		}
+		return nil
+	})
+	if err == nil {
+		writeJSON(w, http.StatusOK, TaskBatchResponse{Results: results})
+		return
+	}
+	if failed < 0 {
+		h.writeError(w, r, err, "Failed to apply task batch")
+		return
+	}
+
+	p := *h.batchProblem(r, err)
+	p.Detail = fmt.Sprintf("Operation %d failed, no operations were applied: %s", failed, p.Detail)
+	if p.Errors != nil {
+		var v validate.Validator
+		v.Nested(fmt.Sprintf("operations[%d]", failed), p.Errors)
+		p.Errors = v.Err().(validate.Errors)
+	}
+	problem.Write(w, r, &p)
+}
+
+// batchProblem describes why an operation failed. Errors without a
+// client-facing meaning are logged and reported as internal errors.
+func (h *Handler) batchProblem(r *http.Request, err error) *problem.Problem {
+	if errors.Is(err, errVersionChanged) {
+		return problem.New(http.StatusPreconditionFailed, problem.CodeVersionMismatch, "Record has been modified, fetch it again and retry")
+	}
+	if p := problem.From(err); p != nil {
+		return p
+	}
+	h.Logger.Error("Failed to apply task batch operation", zap.Error(err), zap.String("request_id", requestid.FromContext(r.Context())))
+	return problem.New(http.StatusInternalServerError, problem.CodeInternal, "Failed to apply operation")
+}
+
+// applyTaskOperation runs one operation in tx, with the same rules as the
+// single-task endpoints.
+func applyTaskOperation(tx *pg.Tx, op *TaskBatchOperation) (TaskBatchResult, error) {
+	switch op.Op {
+	case batchCreate:
+		task := *op.Task
+		if err := createTask(tx, &task); err != nil {
+			return TaskBatchResult{}, err
+		}
+		return TaskBatchResult{Status: http.StatusCreated, Task: &task}, nil
+
+	case batchUpdate:
+		existing := &models.WorkoutTask{ID: op.ID}
+		if err := tx.Model(existing).WherePK().Select(); err != nil {
+			return TaskBatchResult{}, err
+		}
+		if existing.Version != op.Version {
+			return TaskBatchResult{}, errVersionChanged
+		}
+		task := *op.Task
+		if task.Category == "" {
+			task.Category = models.TaskCategoryExercise
+		}
+		if err := updateTask(tx, models.AuditUpdate, existing, &task); err != nil {
+			return TaskBatchResult{}, err
+		}
+		return TaskBatchResult{Status: http.StatusOK, Task: &task}, nil
+
+	case batchComplete:
+		task := &models.WorkoutTask{ID: op.ID}
+		if err := tx.Model(task).WherePK().For("UPDATE").Select(); err != nil {
+			return TaskBatchResult{}, err
+		}
+		if op.Version > 0 && task.Version != op.Version {
+			return TaskBatchResult{}, errVersionChanged
+		}
+		before := *task
+		_, err := tx.Model(task).
+			Set("completed = ?", true).
+			Set("updated_at = ?", time.Now()).
+			WherePK().
+			Returning("*").
+			Update()
+		if err != nil {
+			return TaskBatchResult{}, err
+		}
+		if err := auditTask(tx, models.AuditUpdate, &before, task); err != nil {
+			return TaskBatchResult{}, err
+		}
+		return TaskBatchResult{Status: http.StatusOK, Task: task}, nil
+
+	default:
+		trashed, err := trashTask(tx, op.ID, op.Version)
+		if err != nil {
+			return TaskBatchResult{}, err
+		}
+		if !trashed {
+			return TaskBatchResult{}, missingOrChanged(tx, op.ID)
+		}
+		return TaskBatchResult{Status: http.StatusNoContent}, nil
+	}
+}
+
+// missingOrChanged tells apart a task that does not exist from one whose
+// version moved on, after a conditional write matched no row.
+func missingOrChanged(tx *pg.Tx, id int) error {
+	exists, err := tx.Model((*models.WorkoutTask)(nil)).Where("id = ?", id).Exists()
+	if err != nil {
+		return err
+	}
+	if !exists {
+		return pg.ErrNoRows
+	}
+	return errVersionChanged
+}
@@ -0,0 +1,288 @@
+// handlers/trash.go
+package handlers
+
+import (
+	"net/http"
+	"strconv"
+	"time"
+
+	"back-end/models"
+	"back-end/paging"
+	"back-end/problem"
+	"back-end/schedule"
+
+	"github.com/go-pg/pg/v10"
+	"github.com/go-pg/pg/v10/orm"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+// trashTask moves a task at the given version to the trash. Its
+// occurrences are kept for a restore; reads join them to live tasks, so
+// they drop out of schedules and stats meanwhile. It reports false when
+// the task is not at that version or already trashed.
+func trashTask(db orm.DB, taskID, version int) (bool, error) {
+	task := &models.WorkoutTask{ID: taskID}
+	err := db.Model(task).WherePK().Where("version = ?", version).For("UPDATE").Select()
+	if err != nil {
+		if err == pg.ErrNoRows {
+			return false, nil
+		}
+		return false, err
+	}
+	before := *task
+	if _, err := db.Model(task).WherePK().Returning("*").Delete(); err != nil {
+		return false, err
+	}
+	if err := auditTask(db, models.AuditDelete, &before, task); err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// trashProfile moves a profile at its current version to the trash along
+// with its tasks. The tasks share the profile's deleted_at, so restoring
+// the profile brings back exactly the tasks trashed with it.
+func (h *Handler) trashProfile(r *http.Request, profile *models.UserProfile) (bool, error) {
+	trashed := false
+	err := h.DB.RunInTransaction(r.Context(), func(tx *pg.Tx) error {
+		before := models.UserProfile{ID: profile.ID}
+		err := tx.Model(&before).WherePK().Where("version = ?", profile.Version).For("UPDATE").Select()
+		if err != nil {
+			if err == pg.ErrNoRows {
+				return nil
+			}
+			return err
+		}
+		trashed = true
+
+		*profile = before
+		if _, err := tx.Model(profile).WherePK().Returning("*").Delete(); err != nil {
+			return err
+		}
+		if err := auditProfile(tx, models.AuditDelete, &before, profile); err != nil {
+			return err
+		}
+
+		var tasks []models.WorkoutTask
+		err = tx.Model(&tasks).Where("user_id = ?", profile.ID).For("UPDATE").Select()
+		if err != nil {
+			return err
+		}
+		var trashedTasks []models.WorkoutTask
+		_, err = tx.Model(&trashedTasks).
+			Set("deleted_at = ?", profile.DeletedAt).
+			Where("user_id = ?", profile.ID).
+			Returning("*").
+			Update()
+		if err != nil {
+			return err
+		}
+		return auditTasks(tx, models.AuditDelete, tasks, trashedTasks)
+	})
+	return trashed && err == nil, err
+}
+
+// auditTasks records a write to many tasks, matching each task after the
+// write to its copy from before.
+func auditTasks(db orm.DB, action string, before, after []models.WorkoutTask) error {
+	byID := make(map[int]*models.WorkoutTask, len(before))
+	for i := range before {
+		byID[before[i].ID] = &before[i]
+	}
+	for i := range after {
+		if err := auditTask(db, action, byID[after[i].ID], &after[i]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Trashed tasks are listed most recently deleted first
+var trashKeyset = paging.Desc(paging.TimeColumn("deleted_at"), paging.IntColumn("id"))
+
+// GetTrashByUserId lists the user's trashed tasks. They are purged once
+// they have been in the trash for the retention period.
+func (h *Handler) GetTrashByUserId(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	profile, ok := h.findProfileByUserId(w, r, vars["userId"])
+	if !ok {
+		return
+	}
+
+	params, err := paging.FromRequest(r, trashKeyset)
+	if err != nil {
+		writeInvalid(w, r, err)
+		return
+	}
+
+	var tasks []models.WorkoutTask
+	query := h.DB.Model(&tasks).Deleted().Where("user_id = ?", profile.ID)
+	total, err := params.Total(query)
+	if err == nil {
+		err = params.Apply(query).Select()
+	}
+	if err != nil {
+		h.writeError(w, r, err, "Failed to list trash")
+		return
+	}
+
+	tasks, next := paging.Trim(params, tasks, func(t *models.WorkoutTask) []string {
+		return []string{t.DeletedAt.Format(time.RFC3339Nano), strconv.Itoa(t.ID)}
+	})
+	writePage(w, r, tasks, next, total)
+}
+
+// RestoreWorkoutTask takes a task out of the trash. It goes back at the
+// end of the user's task list, since other tasks may have taken its place,
+// and its schedule is materialized up to where it moved while trashed.
+func (h *Handler) RestoreWorkoutTask(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		h.Logger.Error("Invalid ID format", zap.String("id", vars["id"]))
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidRequest, "Invalid ID format")
+		return
+	}
+
+	task := &models.WorkoutTask{ID: id}
+	err = h.DB.RunInTransaction(r.Context(), func(tx *pg.Tx) error {
+		if err := tx.Model(task).Deleted().WherePK().For("UPDATE").Select(); err != nil {
+			return err
+		}
+		before := *task
+		// Tasks of a trashed profile come back with the profile
+		exists, err := tx.Model((*models.UserProfile)(nil)).Where("id = ?", task.UserID).Exists()
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return problem.New(http.StatusConflict, problem.CodeConflict, "The task's profile is in the trash, restore the profile instead")
+		}
+
+		// Imported tasks are not part of the plan's order
+		position := task.Position
+		if task.Source == models.TaskSourcePlan {
+			if position, err = nextTaskPosition(tx, task.UserID); err != nil {
+				return err
+			}
+		}
+		_, err = tx.Model(task).
+			Set("deleted_at = NULL").
+			Set("position = ?", position).
+			Set("updated_at = ?", time.Now()).
+			Deleted().
+			WherePK().
+			Returning("*").
+			Update()
+		if err != nil {
+			return err
+		}
+		if err := auditTask(tx, models.AuditRestore, &before, task); err != nil {
+			return err
+		}
+		return schedule.RefreshTask(tx, task)
+	})
+	if err != nil {
+		if err == pg.ErrNoRows {
+			problem.Error(w, r, http.StatusNotFound, problem.CodeNotFound, "Workout task not found in trash")
+			return
+		}
+		h.writeError(w, r, err, "Failed to restore workout task")
+		return
+	}
+
+	setETag(w, task.Version)
+	writeJSON(w, http.StatusOK, task)
+}
+
+// RestoreUserProfile takes a profile out of the trash together with the
+// tasks trashed with it.
+func (h *Handler) RestoreUserProfile(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		h.Logger.Error("Invalid profile ID", zap.Error(err))
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidRequest, "Invalid profile ID")
+		return
+	}
+	h.restoreProfile(w, r, "id", id)
+}
+
+func (h *Handler) RestoreUserProfileByUserId(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	h.restoreProfile(w, r, "user_id", vars["userId"])
+}
+
+func (h *Handler) restoreProfile(w http.ResponseWriter, r *http.Request, column string, value interface{}) {
+	unitSystem, ok := requestedUnits(w, r)
+	if !ok {
+		return
+	}
+
+	var profile models.UserProfile
+	err := h.DB.RunInTransaction(r.Context(), func(tx *pg.Tx) error {
+		err := tx.Model(&profile).
+			Deleted().
+			Where("? = ?", pg.Ident(column), value).
+			For("UPDATE").
+			Select()
+		if err != nil {
+			return err
+		}
+		before := profile
+
+		var tasks []models.WorkoutTask
+		err = tx.Model(&tasks).
+			Deleted().
+			Where("user_id = ?", profile.ID).
+			Where("deleted_at = ?", profile.DeletedAt).
+			For("UPDATE").
+			Select()
+		if err != nil {
+			return err
+		}
+		var restoredTasks []models.WorkoutTask
+		_, err = tx.Model(&restoredTasks).
+			Set("deleted_at = NULL").
+			Deleted().
+			Where("user_id = ?", profile.ID).
+			Where("deleted_at = ?", profile.DeletedAt).
+			Returning("*").
+			Update()
+		if err != nil {
+			return err
+		}
+		if err := auditTasks(tx, models.AuditRestore, tasks, restoredTasks); err != nil {
+			return err
+		}
+
+		profile.UpdatedAt = time.Now()
+		_, err = tx.Model(&profile).
+			Set("deleted_at = NULL").
+			Set("updated_at = ?", profile.UpdatedAt).
+			Deleted().
+			WherePK().
+			Returning("*").
+			Update()
+		if err != nil {
+			return err
+		}
+		if err := auditProfile(tx, models.AuditRestore, &before, &profile); err != nil {
+			return err
+		}
+		return schedule.Refresh(tx, profile.ID)
+	})
+	if err != nil {
+		if err == pg.ErrNoRows {
+			problem.Error(w, r, http.StatusNotFound, problem.CodeNotFound, "User profile not found in trash")
+			return
+		}
+		h.writeError(w, r, err, "Failed to restore user profile")
+		return
+	}
+
+	setETag(w, profile.Version)
+	renderProfile(&profile, unitSystem)
+	writeJSON(w, http.StatusOK, profile)
+}
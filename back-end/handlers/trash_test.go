@@ -0,0 +1,63 @@
+package handlers_test
+
+import (
+	"fmt"
+	"net/http"
+	"testing"
+
+	"back-end/models"
+	"back-end/schedule"
+)
+
+func TestRestoredTaskIsScheduledAgain(t *testing.T) {
+	db, api := testServer(t)
+	profile := createProfile(t, db, api)
+
+	today := profile.Today()
+	var task models.WorkoutTask
+	w := call(t, api, "POST", "/v1/tasks", map[string]interface{}{
+		"userId":       profile.ID,
+		"name":         "Back Squat",
+		"sets":         5,
+		"reps":         5,
+		"scheduledFor": today,
+		"recurrence":   "FREQ=DAILY",
+	}, nil, http.StatusCreated, &task)
+
+	week := func() []models.TaskOccurrence {
+		t.Helper()
+		occurrences, err := schedule.LoadOccurrences(db, profile.ID, today, today.AddDays(6))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return occurrences
+	}
+	before := week()
+	if len(before) != 7 {
+		t.Fatalf("got %d occurrences of a daily task in a week, want 7", len(before))
+	}
+
+	taskPath := fmt.Sprintf("/v1/tasks/%d", task.ID)
+	call(t, api, "DELETE", taskPath, nil, http.Header{"If-Match": {w.Header().Get("ETag")}}, http.StatusOK, nil)
+	if got := week(); len(got) != 0 {
+		t.Fatalf("trashed task still has %d occurrences", len(got))
+	}
+
+	call(t, api, "POST", taskPath+"/restore", nil, nil, http.StatusOK, nil)
+	after := week()
+	if len(after) != len(before) {
+		t.Fatalf("restored task has %d occurrences, want %d", len(after), len(before))
+	}
+	for i, o := range after {
+		// The same occurrences come back, so calendar events keep their UIDs
+		if o.ID != before[i].ID || o.Status != models.OccurrenceScheduled {
+			t.Errorf("occurrence %d is %+v, want %+v", i, o, before[i])
+		}
+	}
+
+	var occurrences []models.TaskOccurrence
+	call(t, api, "GET", "/v1/profiles/user/"+profile.UserID+"/schedule", nil, nil, http.StatusOK, &occurrences)
+	if len(occurrences) != len(before) {
+		t.Fatalf("schedule lists %d occurrences, want %d", len(occurrences), len(before))
+	}
+}
@@ -2,13 +2,16 @@
 package handlers
 
 import (
-	"encoding/json"
 	"fmt"
 	"net/http"
 	"strconv"
 	"time"
 
 	"back-end/models"
+	"back-end/paging"
+	"back-end/problem"
+	"back-end/units"
+	"back-end/validate"
 
 	"github.com/go-pg/pg/v10"
 	"github.com/gorilla/mux"
@@ -16,42 +19,128 @@ import (
 )
 
 func (h *Handler) CreateUserProfile(w http.ResponseWriter, r *http.Request) {
+	unitSystem, ok := requestedUnits(w, r)
+	if !ok {
+		return
+	}
+
 	var profile models.UserProfile
-	if err := json.NewDecoder(r.Body).Decode(&profile); err != nil {
-		h.Logger.Error("Failed to decode request body", zap.Error(err))
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	if err := decodeJSON(r.Body, &profile); err != nil {
+		writeInvalid(w, r, err)
+		return
+	}
+	if err := prepareNewProfile(&profile); err != nil {
+		writeInvalid(w, r, err)
 		return
 	}
 
-	// Check if user_id already exists
-	exists, err := h.DB.Model(&models.UserProfile{}).
-		Where("user_id = ?", profile.UserID).
-		Exists()
+	// The UNIQUE constraint on user_id decides between concurrent signups;
+	// the loser gets a 409 naming the field
+	err := h.DB.RunInTransaction(r.Context(), func(tx *pg.Tx) error {
+		if _, err := tx.Model(&profile).Insert(); err != nil {
+			return err
+		}
+		return auditProfile(tx, models.AuditCreate, nil, &profile)
+	})
 	if err != nil {
-		h.Logger.Error("Failed to check user_id existence", zap.Error(err))
-		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		h.writeError(w, r, err, "Failed to create user profile")
 		return
 	}
-	if exists {
-		h.Logger.Error("User profile already exists", zap.String("user_id", profile.UserID))
-		http.Error(w, "User profile already exists for this user_id", http.StatusConflict)
+
+	setETag(w, profile.Version)
+	renderProfile(&profile, unitSystem)
+	writeJSON(w, http.StatusCreated, profile)
+}
+
+// CreateOrGetUserProfileByUserId creates the profile of an auth user unless
+// one exists, in which case the existing profile is returned unchanged
+// with 200. Onboarding can retry it safely.
+func (h *Handler) CreateOrGetUserProfileByUserId(w http.ResponseWriter, r *http.Request) {
+	unitSystem, ok := requestedUnits(w, r)
+	if !ok {
 		return
 	}
 
-	profile.CreatedAt = time.Now()
-	profile.UpdatedAt = time.Now()
+	vars := mux.Vars(r)
+	userId := vars["userId"]
 
-	if _, err := h.DB.Model(&profile).Insert(); err != nil {
-		h.Logger.Error("Failed to create user profile", zap.Error(err))
-		http.Error(w, "Failed to create user profile", http.StatusInternalServerError)
+	var profile models.UserProfile
+	if err := decodeJSON(r.Body, &profile); err != nil {
+		writeInvalid(w, r, err)
+		return
+	}
+	if profile.UserID != "" && profile.UserID != userId {
+		writeInvalid(w, r, validate.Field("user_id", validate.CodeInvalid, "user_id must match the user in the URL"))
+		return
+	}
+	profile.UserID = userId
+	if err := prepareNewProfile(&profile); err != nil {
+		writeInvalid(w, r, err)
 		return
 	}
 
-	w.WriteHeader(http.StatusCreated)
-	json.NewEncoder(w).Encode(profile)
+	created := false
+	err := h.DB.RunInTransaction(r.Context(), func(tx *pg.Tx) error {
+		res, err := tx.Model(&profile).OnConflict("(user_id) DO NOTHING").Insert()
+		if err != nil || res.RowsAffected() == 0 {
+			return err
+		}
+		created = true
+		return auditProfile(tx, models.AuditCreate, nil, &profile)
+	})
+	if err != nil {
+		h.writeError(w, r, err, "Failed to create user profile")
+		return
+	}
+	status := http.StatusCreated
+	if !created {
+		profile = models.UserProfile{}
+		if err := h.DB.Model(&profile).Where("user_id = ?", userId).Select(); err != nil {
+			if err == pg.ErrNoRows {
+				// The conflicting profile is in the trash
+				problem.Error(w, r, http.StatusConflict, problem.CodeConflict, "The user's profile is in the trash, restore it instead")
+				return
+			}
+			h.writeError(w, r, err, "Failed to get user profile")
+			return
+		}
+		status = http.StatusOK
+	}
+
+	setETag(w, profile.Version)
+	renderProfile(&profile, unitSystem)
+	writeJSON(w, status, profile)
+}
+
+// prepareNewProfile fills in the defaults of a profile about to be created
+// and validates it.
+func prepareNewProfile(profile *models.UserProfile) error {
+	profile.ApplySettingDefaults()
+	if err := profile.NormalizeUnits(); err != nil {
+		return err
+	}
+	if err := profile.Validate(); err != nil {
+		return err
+	}
+	profile.CreatedAt = time.Now()
+	profile.UpdatedAt = time.Now()
+	return nil
 }
 
 func (h *Handler) GetUserProfile(w http.ResponseWriter, r *http.Request) {
+	unitSystem, ok := requestedUnits(w, r)
+	if !ok {
+		return
+	}
+	include, ok := requestedIncludes(w, r, true)
+	if !ok {
+		return
+	}
+	selection, ok := requestedFields(w, r, ProfileResource{})
+	if !ok {
+		return
+	}
+
 	vars := mux.Vars(r)
 	id := vars["id"]
 
@@ -60,88 +149,116 @@ func (h *Handler) GetUserProfile(w http.ResponseWriter, r *http.Request) {
 	if err != nil {
 		if err == pg.ErrNoRows {
 			h.Logger.Error("User profile not found", zap.String("id", id))
-			http.Error(w, "User profile not found", http.StatusNotFound)
+			problem.Error(w, r, http.StatusNotFound, problem.CodeNotFound, "User profile not found")
 			return
 		}
-		h.Logger.Error("Failed to get user profile", zap.Error(err))
-		http.Error(w, "Failed to get user profile", http.StatusInternalServerError)
+		h.writeError(w, r, err, "Failed to get user profile")
 		return
 	}
 
-	json.NewEncoder(w).Encode(profile)
+	if notModified(w, r, profile.Version) {
+		return
+	}
+	renderProfile(&profile, unitSystem)
+	resources, err := h.profileResources([]models.UserProfile{profile}, include)
+	if err != nil {
+		h.writeError(w, r, err, "Failed to load included resources")
+		return
+	}
+	writeJSON(w, http.StatusOK, selection.Apply(resources[0]))
 }
 
+// Profiles are listed in id order
+var profileKeyset = paging.Asc(paging.IntColumn("id"))
+
 func (h *Handler) ListUserProfiles(w http.ResponseWriter, r *http.Request) {
-	var profiles []models.UserProfile
+	unitSystem, ok := requestedUnits(w, r)
+	if !ok {
+		return
+	}
+	include, ok := requestedIncludes(w, r, false)
+	if !ok {
+		return
+	}
+	selection, ok := requestedFields(w, r, ProfileResource{})
+	if !ok {
+		return
+	}
 
-	query := h.DB.Model(&profiles)
+	params, err := paging.FromRequest(r, profileKeyset)
+	if err != nil {
+		writeInvalid(w, r, err)
+		return
+	}
 
-	// Add pagination
-	limit := 10
-	if r.URL.Query().Get("limit") != "" {
-		fmt.Sscanf(r.URL.Query().Get("limit"), "%d", &limit)
+	var profiles []models.UserProfile
+	query := h.DB.Model(&profiles)
+	total, err := params.Total(query)
+	if err == nil {
+		err = params.Apply(query).Select()
 	}
-	offset := 0
-	if r.URL.Query().Get("offset") != "" {
-		fmt.Sscanf(r.URL.Query().Get("offset"), "%d", &offset)
+	if err != nil {
+		h.writeError(w, r, err, "Failed to list user profiles")
+		return
 	}
-	query.Limit(limit).Offset(offset)
 
-	err := query.Select()
+	profiles, next := paging.Trim(params, profiles, func(p *models.UserProfile) []string {
+		return []string{strconv.Itoa(p.ID)}
+	})
+	for i := range profiles {
+		renderProfile(&profiles[i], unitSystem)
+	}
+	resources, err := h.profileResources(profiles, include)
 	if err != nil {
-		h.Logger.Error("Failed to list user profiles", zap.Error(err))
-		http.Error(w, "Failed to list user profiles", http.StatusInternalServerError)
+		h.writeError(w, r, err, "Failed to load included resources")
 		return
 	}
-
-	json.NewEncoder(w).Encode(profiles)
+	writePage(w, r, selection.Apply(resources), next, total)
 }
 
+// UpdateUserProfile replaces a profile. Every required field must be sent;
+// settings left out are reset to their defaults.
 func (h *Handler) UpdateUserProfile(w http.ResponseWriter, r *http.Request) {
+	unitSystem, ok := requestedUnits(w, r)
+	if !ok {
+		return
+	}
+
 	vars := mux.Vars(r)
 	id, err := strconv.Atoi(vars["id"])
 	if err != nil {
 		h.Logger.Error("Invalid profile ID", zap.Error(err))
-		http.Error(w, "Invalid profile ID", http.StatusBadRequest)
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidRequest, "Invalid profile ID")
 		return
 	}
 
-	// First, get the existing profile
-	existingProfile := &models.UserProfile{ID: id}
-	err = h.DB.Model(existingProfile).WherePK().Select()
-	if err != nil {
-		if err == pg.ErrNoRows {
-			http.Error(w, "Profile not found", http.StatusNotFound)
-			return
-		}
-		h.Logger.Error("Failed to fetch profile", zap.Error(err))
-		http.Error(w, "Failed to fetch profile", http.StatusInternalServerError)
+	existingProfile, ok := h.findProfileForWrite(w, r, "id", id)
+	if !ok {
 		return
 	}
+	h.replaceProfile(w, r, existingProfile, unitSystem)
+}
 
-	// Decode the update request
-	var updatedProfile models.UserProfile
-	if err := json.NewDecoder(r.Body).Decode(&updatedProfile); err != nil {
-		h.Logger.Error("Failed to decode request body", zap.Error(err))
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+// PatchUserProfile applies a JSON merge patch to a profile.
+func (h *Handler) PatchUserProfile(w http.ResponseWriter, r *http.Request) {
+	unitSystem, ok := requestedUnits(w, r)
+	if !ok {
 		return
 	}
 
-	// Preserve the original ID, user_id, and created_at
-	updatedProfile.ID = id
-	updatedProfile.UserID = existingProfile.UserID
-	updatedProfile.CreatedAt = existingProfile.CreatedAt
-	updatedProfile.UpdatedAt = time.Now()
-
-	// Update the profile
-	_, err = h.DB.Model(&updatedProfile).WherePK().Update()
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
 	if err != nil {
-		h.Logger.Error("Failed to update user profile", zap.Error(err))
-		http.Error(w, "Failed to update user profile", http.StatusInternalServerError)
+		h.Logger.Error("Invalid profile ID", zap.Error(err))
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidRequest, "Invalid profile ID")
 		return
 	}
 
-	json.NewEncoder(w).Encode(updatedProfile)
+	existingProfile, ok := h.findProfileForWrite(w, r, "id", id)
+	if !ok {
+		return
+	}
+	h.patchProfile(w, r, existingProfile, unitSystem)
 }
 
 func (h *Handler) DeleteUserProfile(w http.ResponseWriter, r *http.Request) {
@@ -152,37 +269,55 @@ func (h *Handler) DeleteUserProfile(w http.ResponseWriter, r *http.Request) {
 	id, err := strconv.Atoi(idStr)
 	if err != nil {
 		h.Logger.Error("Invalid profile ID", zap.Error(err))
-		http.Error(w, "Invalid profile ID", http.StatusBadRequest)
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidRequest, "Invalid profile ID")
 		return
 	}
 
-	var profile models.UserProfile
-	profile.ID = id
+	profile := &models.UserProfile{ID: id}
+	if err := h.DB.Model(profile).Column("id", "version").WherePK().Select(); err != nil {
+		if err == pg.ErrNoRows {
+			problem.Error(w, r, http.StatusNotFound, problem.CodeNotFound, "User profile not found")
+			return
+		}
+		h.writeError(w, r, err, "Failed to delete user profile")
+		return
+	}
+	if !requireIfMatch(w, r, profile.Version) {
+		return
+	}
 
-	res, err := h.DB.Model(&profile).WherePK().Delete()
+	trashed, err := h.trashProfile(r, profile)
 	if err != nil {
-		h.Logger.Error("Failed to delete user profile", zap.Error(err))
-		http.Error(w, "Failed to delete user profile", http.StatusInternalServerError)
+		h.writeError(w, r, err, "Failed to delete user profile")
 		return
 	}
 
-	if res.RowsAffected() == 0 {
-		http.Error(w, "User profile not found", http.StatusNotFound)
+	if !trashed {
+		writeVersionChanged(w, r, 0)
 		return
 	}
 
-	// Set response header to application/json
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	
 	// Return success message
 	response := map[string]string{
-		"message": fmt.Sprintf("User profile with ID %d has been successfully deleted", id),
+		"message": fmt.Sprintf("User profile with ID %d has been moved to the trash", id),
 	}
-	json.NewEncoder(w).Encode(response)
+	writeJSON(w, http.StatusOK, response)
 }
 
 func (h *Handler) GetUserProfileByUserId(w http.ResponseWriter, r *http.Request) {
+	unitSystem, ok := requestedUnits(w, r)
+	if !ok {
+		return
+	}
+	include, ok := requestedIncludes(w, r, true)
+	if !ok {
+		return
+	}
+	selection, ok := requestedFields(w, r, ProfileResource{})
+	if !ok {
+		return
+	}
+
 	vars := mux.Vars(r)
 	userId := vars["userId"]
 
@@ -191,84 +326,216 @@ func (h *Handler) GetUserProfileByUserId(w http.ResponseWriter, r *http.Request)
 	if err != nil {
 		if err == pg.ErrNoRows {
 			h.Logger.Error("User profile not found", zap.String("userId", userId))
-			http.Error(w, "User profile not found", http.StatusNotFound)
+			problem.Error(w, r, http.StatusNotFound, problem.CodeNotFound, "User profile not found")
 			return
 		}
-		h.Logger.Error("Failed to get user profile", zap.Error(err))
-		http.Error(w, "Failed to get user profile", http.StatusInternalServerError)
+		h.writeError(w, r, err, "Failed to get user profile")
 		return
 	}
 
-	json.NewEncoder(w).Encode(profile)
+	if notModified(w, r, profile.Version) {
+		return
+	}
+	renderProfile(&profile, unitSystem)
+	resources, err := h.profileResources([]models.UserProfile{profile}, include)
+	if err != nil {
+		h.writeError(w, r, err, "Failed to load included resources")
+		return
+	}
+	writeJSON(w, http.StatusOK, selection.Apply(resources[0]))
 }
 
 func (h *Handler) UpdateUserProfileByUserId(w http.ResponseWriter, r *http.Request) {
+	unitSystem, ok := requestedUnits(w, r)
+	if !ok {
+		return
+	}
+
+	vars := mux.Vars(r)
+	existingProfile, ok := h.findProfileForWrite(w, r, "user_id", vars["userId"])
+	if !ok {
+		return
+	}
+	h.replaceProfile(w, r, existingProfile, unitSystem)
+}
+
+func (h *Handler) PatchUserProfileByUserId(w http.ResponseWriter, r *http.Request) {
+	unitSystem, ok := requestedUnits(w, r)
+	if !ok {
+		return
+	}
+
+	vars := mux.Vars(r)
+	existingProfile, ok := h.findProfileForWrite(w, r, "user_id", vars["userId"])
+	if !ok {
+		return
+	}
+	h.patchProfile(w, r, existingProfile, unitSystem)
+}
+
+func (h *Handler) DeleteUserProfileByUserId(w http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 	userId := vars["userId"]
 
-	// First, get the existing profile
-	existingProfile := &models.UserProfile{}
-	err := h.DB.Model(existingProfile).Where("user_id = ?", userId).Select()
+	profile, ok := h.findProfileByUserId(w, r, userId)
+	if !ok {
+		return
+	}
+	if !requireIfMatch(w, r, profile.Version) {
+		return
+	}
+
+	trashed, err := h.trashProfile(r, profile)
 	if err != nil {
-		if err == pg.ErrNoRows {
-			http.Error(w, "Profile not found", http.StatusNotFound)
-			return
-		}
-		h.Logger.Error("Failed to fetch profile", zap.Error(err))
-		http.Error(w, "Failed to fetch profile", http.StatusInternalServerError)
+		h.writeError(w, r, err, "Failed to delete user profile")
 		return
 	}
 
-	// Decode the update request
-	var updatedProfile models.UserProfile
-	if err := json.NewDecoder(r.Body).Decode(&updatedProfile); err != nil {
-		h.Logger.Error("Failed to decode request body", zap.Error(err))
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+	if !trashed {
+		writeVersionChanged(w, r, 0)
 		return
 	}
 
-	// Preserve the original ID, user_id, and created_at
-	updatedProfile.ID = existingProfile.ID
-	updatedProfile.UserID = existingProfile.UserID
-	updatedProfile.CreatedAt = existingProfile.CreatedAt
-	updatedProfile.UpdatedAt = time.Now()
+	// Return success message
+	response := map[string]string{
+		"message": fmt.Sprintf("User profile with user_id %s has been moved to the trash", userId),
+	}
+	writeJSON(w, http.StatusOK, response)
+}
 
-	// Update the profile
-	_, err = h.DB.Model(&updatedProfile).Where("user_id = ?", userId).Update()
+// findProfileByUserId loads the profile for an auth user id, writing the
+// error response itself when it cannot.
+func (h *Handler) findProfileByUserId(w http.ResponseWriter, r *http.Request, userId string) (*models.UserProfile, bool) {
+	var profile models.UserProfile
+	err := h.DB.Model(&profile).Where("user_id = ?", userId).Select()
 	if err != nil {
-		h.Logger.Error("Failed to update user profile", zap.Error(err))
-		http.Error(w, "Failed to update user profile", http.StatusInternalServerError)
-		return
+		if err == pg.ErrNoRows {
+			h.Logger.Error("User profile not found", zap.String("userId", userId))
+			problem.Error(w, r, http.StatusNotFound, problem.CodeNotFound, "User profile not found")
+			return nil, false
+		}
+		h.writeError(w, r, err, "Failed to get user profile")
+		return nil, false
 	}
+	return &profile, true
+}
 
-	json.NewEncoder(w).Encode(updatedProfile)
+// requestedUnits returns the unit system asked for with ?units=, writing a
+// 400 response if it is unknown. An empty result means each profile's own.
+func requestedUnits(w http.ResponseWriter, r *http.Request) (string, bool) {
+	system := r.URL.Query().Get("units")
+	if system != "" && !units.IsValidSystem(system) {
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidRequest, "units must be metric or imperial")
+		return "", false
+	}
+	return system, true
 }
 
-func (h *Handler) DeleteUserProfileByUserId(w http.ResponseWriter, r *http.Request) {
-	vars := mux.Vars(r)
-	userId := vars["userId"]
+// renderProfile converts the profile's measurements into the requested
+// unit system, or the profile's preferred one.
+func renderProfile(profile *models.UserProfile, unitSystem string) {
+	if unitSystem == "" {
+		unitSystem = profile.UnitSystem
+	}
+	profile.RenderUnits(unitSystem)
+}
 
+// findProfileForWrite loads the profile about to be changed and checks the
+// client's If-Match against it, writing the error response itself.
+func (h *Handler) findProfileForWrite(w http.ResponseWriter, r *http.Request, column string, value interface{}) (*models.UserProfile, bool) {
 	var profile models.UserProfile
-	res, err := h.DB.Model(&profile).Where("user_id = ?", userId).Delete()
+	err := h.DB.Model(&profile).Where("? = ?", pg.Ident(column), value).Select()
 	if err != nil {
-		h.Logger.Error("Failed to delete user profile", zap.Error(err))
-		http.Error(w, "Failed to delete user profile", http.StatusInternalServerError)
+		if err == pg.ErrNoRows {
+			problem.Error(w, r, http.StatusNotFound, problem.CodeNotFound, "Profile not found")
+			return nil, false
+		}
+		h.writeError(w, r, err, "Failed to fetch profile")
+		return nil, false
+	}
+	if !requireIfMatch(w, r, profile.Version) {
+		return nil, false
+	}
+	return &profile, true
+}
+
+func (h *Handler) replaceProfile(w http.ResponseWriter, r *http.Request, existing *models.UserProfile, unitSystem string) {
+	var updatedProfile models.UserProfile
+	if err := decodeReplacement(r, &updatedProfile, profileRequiredFields); err != nil {
+		writeInvalid(w, r, err)
 		return
 	}
+	updatedProfile.ApplySettingDefaults()
+	h.saveProfile(w, r, models.AuditUpdate, existing, &updatedProfile, unitSystem)
+}
 
-	if res.RowsAffected() == 0 {
-		http.Error(w, "User profile not found", http.StatusNotFound)
+func (h *Handler) patchProfile(w http.ResponseWriter, r *http.Request, existing *models.UserProfile, unitSystem string) {
+	if !isMergePatch(r) {
+		problem.Error(w, r, http.StatusUnsupportedMediaType, problem.CodeUnsupportedMediaType, "PATCH expects application/merge-patch+json")
 		return
 	}
 
-	// Set response header to application/json
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(http.StatusOK)
-	
-	// Return success message
-	response := map[string]string{
-		"message": fmt.Sprintf("User profile with user_id %s has been successfully deleted", userId),
+	// Patch the metric form; values sent without a unit are metric, as in
+	// a full update
+	current := *existing
+	var updatedProfile models.UserProfile
+	patch, err := decodeMergePatch(r, &current, &updatedProfile)
+	if err != nil {
+		writeInvalid(w, r, err)
+		return
+	}
+	for value, unit := range map[string]string{"weight": "weightUnit", "height": "heightUnit"} {
+		if _, ok := patch[unit]; ok {
+			if _, ok := patch[value]; !ok {
+				writeInvalid(w, r, validate.Field(unit, validate.CodeRequired, "%s must be sent together with %s", unit, value))
+				return
+			}
+		}
 	}
-	json.NewEncoder(w).Encode(response)
+	h.saveProfile(w, r, models.AuditUpdate, existing, &updatedProfile, unitSystem)
 }
 
+// saveProfile validates and writes an updated profile, as long as nobody
+// changed it since existing was read, and records the write under action.
+func (h *Handler) saveProfile(w http.ResponseWriter, r *http.Request, action string, existing, updatedProfile *models.UserProfile, unitSystem string) {
+	// Preserve the original ID, user_id, and created_at
+	updatedProfile.ID = existing.ID
+	updatedProfile.UserID = existing.UserID
+	updatedProfile.CreatedAt = existing.CreatedAt
+	updatedProfile.UpdatedAt = time.Now()
+	if err := updatedProfile.NormalizeUnits(); err != nil {
+		writeInvalid(w, r, err)
+		return
+	}
+	if err := updatedProfile.Validate(); err != nil {
+		writeInvalid(w, r, err)
+		return
+	}
+
+	err := h.DB.RunInTransaction(r.Context(), func(tx *pg.Tx) error {
+		res, err := tx.Model(updatedProfile).
+			WherePK().
+			Where("version = ?", existing.Version).
+			Returning("version").
+			Update()
+		if err != nil {
+			return err
+		}
+		if res.RowsAffected() == 0 {
+			return errVersionChanged
+		}
+		return auditProfile(tx, action, existing, updatedProfile)
+	})
+	if err != nil {
+		if err == errVersionChanged {
+			writeVersionChanged(w, r, 0)
+			return
+		}
+		h.writeError(w, r, err, "Failed to update user profile")
+		return
+	}
+
+	setETag(w, updatedProfile.Version)
+	renderProfile(updatedProfile, unitSystem)
+	writeJSON(w, http.StatusOK, updatedProfile)
+}
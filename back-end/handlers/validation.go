@@ -0,0 +1,75 @@
+// handlers/validation.go
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+
+	"back-end/models"
+	"back-end/problem"
+	"back-end/schedule"
+	"back-end/validate"
+)
+
+// errInvalidBody is returned for request bodies that are not valid JSON.
+var errInvalidBody = errors.New("Invalid request body")
+
+// decodeJSON decodes a request body into dst. Unknown fields and values of
+// the wrong type are reported as field errors; malformed JSON returns
+// errInvalidBody.
+func decodeJSON(body io.Reader, dst interface{}) error {
+	dec := json.NewDecoder(body)
+	dec.DisallowUnknownFields()
+	err := dec.Decode(dst)
+	if err == nil {
+		return nil
+	}
+
+	var typeErr *json.UnmarshalTypeError
+	if errors.As(err, &typeErr) && typeErr.Field != "" {
+		return validate.Field(typeErr.Field, validate.CodeType, "%s must be a %s", typeErr.Field, jsonType(typeErr.Type.Kind().String()))
+	}
+	if name, ok := strings.CutPrefix(err.Error(), "json: unknown field "); ok {
+		name = strings.Trim(name, `"`)
+		return validate.Field(name, validate.CodeUnknownField, "%s is not a known field", name)
+	}
+	return errInvalidBody
+}
+
+// jsonType names a Go kind the way a JSON client would.
+func jsonType(kind string) string {
+	switch {
+	case strings.HasPrefix(kind, "int"), strings.HasPrefix(kind, "uint"), strings.HasPrefix(kind, "float"):
+		return "number"
+	case kind == "bool":
+		return "boolean"
+	case kind == "slice", kind == "array":
+		return "array"
+	case kind == "struct", kind == "map", kind == "ptr":
+		return "object"
+	}
+	return kind
+}
+
+// writeInvalid answers a request that failed decoding or validation: 422
+// with the field errors, or 400 for anything else.
+func writeInvalid(w http.ResponseWriter, r *http.Request, err error) {
+	if p := problem.From(err); p != nil {
+		problem.Write(w, r, p)
+		return
+	}
+	problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidRequest, err.Error())
+}
+
+// validateTask checks a task, including its recurrence rule.
+func validateTask(task *models.WorkoutTask) error {
+	var v validate.Validator
+	v.Merge("", task.Validate())
+	if err := schedule.ValidateTask(task); err != nil {
+		v.Add("recurrence", validate.CodeInvalid, "%s", err.Error())
+	}
+	return v.Err()
+}
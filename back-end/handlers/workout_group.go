@@ -0,0 +1,290 @@
+// handlers/workout_group.go
+package handlers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"back-end/models"
+	"back-end/paging"
+	"back-end/problem"
+	"back-end/schedule"
+
+	"github.com/go-pg/pg/v10"
+	"github.com/go-pg/pg/v10/orm"
+	"github.com/gorilla/mux"
+	"go.uber.org/zap"
+)
+
+func orderGroupTasks(q *orm.Query) (*orm.Query, error) {
+	return q.Order("group_position ASC", "id ASC"), nil
+}
+
+func (h *Handler) CreateWorkoutGroup(w http.ResponseWriter, r *http.Request) {
+	var group models.WorkoutGroup
+	if err := decodeJSON(r.Body, &group); err != nil {
+		writeInvalid(w, r, err)
+		return
+	}
+
+	group.ApplyDefaults()
+	for i := range group.Tasks {
+		group.Tasks[i].UserID = group.UserID
+		group.Tasks[i].Category = models.TaskCategoryExercise
+	}
+	if err := group.Validate(); err != nil {
+		writeInvalid(w, r, err)
+		return
+	}
+
+	now := time.Now()
+	group.CompletedRounds = 0
+	group.CreatedAt = now
+	group.UpdatedAt = now
+
+	// Insert the group and any inline tasks together so the member order
+	// given by the client is never partially applied
+	err := h.DB.RunInTransaction(r.Context(), func(tx *pg.Tx) error {
+		tasks := group.Tasks
+		group.Tasks = nil
+		if _, err := tx.Model(&group).Insert(); err != nil {
+			return err
+		}
+		position, err := nextTaskPosition(tx, group.UserID)
+		if err != nil {
+			return err
+		}
+		for i := range tasks {
+			tasks[i].ID = 0
+			tasks[i].Position = position + i
+			tasks[i].UserID = group.UserID
+			tasks[i].GroupID = &group.ID
+			tasks[i].GroupPosition = i
+			tasks[i].Category = models.TaskCategoryExercise
+			tasks[i].Source = models.TaskSourcePlan
+			tasks[i].CreatedAt = now
+			tasks[i].UpdatedAt = now
+			if _, err := tx.Model(&tasks[i]).Insert(); err != nil {
+				return err
+			}
+			if err := auditTask(tx, models.AuditCreate, nil, &tasks[i]); err != nil {
+				return err
+			}
+			if err := schedule.RefreshTask(tx, &tasks[i]); err != nil {
+				return err
+			}
+		}
+		group.Tasks = tasks
+		return nil
+	})
+	if err != nil {
+		h.writeError(w, r, err, "Failed to create workout group")
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, group)
+}
+
+func (h *Handler) GetWorkoutGroup(w http.ResponseWriter, r *http.Request) {
+	selection, ok := requestedFields(w, r, models.WorkoutGroup{})
+	if !ok {
+		return
+	}
+
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		h.Logger.Error("Invalid ID format", zap.String("id", vars["id"]))
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidRequest, "Invalid ID format")
+		return
+	}
+
+	group := &models.WorkoutGroup{ID: id}
+	err = h.DB.Model(group).WherePK().Relation("Tasks", orderGroupTasks).Select()
+	if err != nil {
+		if err == pg.ErrNoRows {
+			problem.Error(w, r, http.StatusNotFound, problem.CodeNotFound, "Workout group not found")
+			return
+		}
+		h.writeError(w, r, err, "Failed to get workout group")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, selection.Apply(group))
+}
+
+func (h *Handler) UpdateWorkoutGroup(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		h.Logger.Error("Invalid ID format", zap.String("id", vars["id"]))
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidRequest, "Invalid ID format")
+		return
+	}
+
+	// First fetch the existing group
+	existingGroup := &models.WorkoutGroup{ID: id}
+	err = h.DB.Model(existingGroup).WherePK().Select()
+	if err != nil {
+		if err == pg.ErrNoRows {
+			problem.Error(w, r, http.StatusNotFound, problem.CodeNotFound, "Workout group not found")
+			return
+		}
+		h.writeError(w, r, err, "Failed to fetch workout group")
+		return
+	}
+
+	// Decode the update request
+	var updatedGroup models.WorkoutGroup
+	if err := decodeJSON(r.Body, &updatedGroup); err != nil {
+		writeInvalid(w, r, err)
+		return
+	}
+
+	// Preserve the ID, user_id, progress and created_at. Membership is
+	// managed through the tasks themselves.
+	updatedGroup.ID = id
+	updatedGroup.UserID = existingGroup.UserID
+	updatedGroup.CompletedRounds = existingGroup.CompletedRounds
+	updatedGroup.Tasks = nil
+	updatedGroup.CreatedAt = existingGroup.CreatedAt
+	updatedGroup.UpdatedAt = time.Now()
+
+	updatedGroup.ApplyDefaults()
+	if err := updatedGroup.Validate(); err != nil {
+		writeInvalid(w, r, err)
+		return
+	}
+
+	if _, err := h.DB.Model(&updatedGroup).WherePK().Returning("version").Update(); err != nil {
+		h.writeError(w, r, err, "Failed to update workout group")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, updatedGroup)
+}
+
+func (h *Handler) DeleteWorkoutGroup(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		h.Logger.Error("Invalid group ID", zap.Error(err))
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidRequest, "Invalid group ID")
+		return
+	}
+
+	// Member tasks are kept and become standalone (ON DELETE SET NULL)
+	group := &models.WorkoutGroup{ID: id}
+	res, err := h.DB.Model(group).WherePK().Delete()
+	if err != nil {
+		h.writeError(w, r, err, "Failed to delete workout group")
+		return
+	}
+
+	if res.RowsAffected() == 0 {
+		problem.Error(w, r, http.StatusNotFound, problem.CodeNotFound, "Workout group not found")
+		return
+	}
+
+	response := map[string]string{
+		"message": fmt.Sprintf("Workout group with ID %d has been successfully deleted", id),
+	}
+	writeJSON(w, http.StatusOK, response)
+}
+
+// LogWorkoutGroupRound records one completed round of a group. Once the
+// final round is logged every task in the group is marked completed.
+func (h *Handler) LogWorkoutGroupRound(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		h.Logger.Error("Invalid ID format", zap.String("id", vars["id"]))
+		problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidRequest, "Invalid ID format")
+		return
+	}
+
+	group := &models.WorkoutGroup{ID: id}
+	err = h.DB.RunInTransaction(r.Context(), func(tx *pg.Tx) error {
+		if err := tx.Model(group).WherePK().For("UPDATE").Select(); err != nil {
+			return err
+		}
+		if group.Done() {
+			return nil
+		}
+
+		now := time.Now()
+		group.CompletedRounds++
+		group.UpdatedAt = now
+		if _, err := tx.Model(group).Column("completed_rounds", "updated_at").WherePK().Returning("version").Update(); err != nil {
+			return err
+		}
+
+		if group.Done() {
+			_, err := tx.Model((*models.WorkoutTask)(nil)).
+				Set("completed = ?", true).
+				Set("updated_at = ?", now).
+				Where("group_id = ?", group.ID).
+				Update()
+			return err
+		}
+		return nil
+	})
+	if err != nil {
+		if err == pg.ErrNoRows {
+			problem.Error(w, r, http.StatusNotFound, problem.CodeNotFound, "Workout group not found")
+			return
+		}
+		h.writeError(w, r, err, "Failed to log workout group round")
+		return
+	}
+
+	if err := h.DB.Model(group).WherePK().Relation("Tasks", orderGroupTasks).Select(); err != nil {
+		h.writeError(w, r, err, "Failed to get workout group")
+		return
+	}
+
+	writeJSON(w, http.StatusOK, group)
+}
+
+var groupKeyset = paging.Asc(paging.IntColumn("position"), paging.IntColumn("id"))
+
+func (h *Handler) GetWorkoutGroupsByUserId(w http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+	userId := vars["userId"]
+
+	profile, ok := h.findProfileByUserId(w, r, userId)
+	if !ok {
+		return
+	}
+
+	params, err := paging.FromRequest(r, groupKeyset)
+	if err != nil {
+		writeInvalid(w, r, err)
+		return
+	}
+	selection, ok := requestedFields(w, r, models.WorkoutGroup{})
+	if !ok {
+		return
+	}
+
+	// Groups come back in position order with their members in group order
+	var groups []models.WorkoutGroup
+	query := h.DB.Model(&groups).
+		Where("user_id = ?", profile.ID).
+		Relation("Tasks", orderGroupTasks)
+	total, err := params.Total(query)
+	if err == nil {
+		err = params.Apply(query).Select()
+	}
+	if err != nil {
+		h.writeError(w, r, err, "Failed to list workout groups")
+		return
+	}
+
+	groups, next := paging.Trim(params, groups, func(g *models.WorkoutGroup) []string {
+		return []string{strconv.Itoa(g.Position), strconv.Itoa(g.ID)}
+	})
+	writePage(w, r, selection.Apply(groups), next, total)
+}
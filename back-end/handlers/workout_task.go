@@ -1,48 +1,123 @@
 package handlers
 
 import (
-	"encoding/json"
+	"errors"
 	"fmt"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
+	"back-end/catalog"
+	"back-end/filter"
 	"back-end/models"
+	"back-end/paging"
+	"back-end/problem"
+	"back-end/schedule"
 
 	"github.com/go-pg/pg/v10"
+	"github.com/go-pg/pg/v10/orm"
 	"github.com/gorilla/mux"
 	"go.uber.org/zap"
 )
 
 func (h *Handler) CreateWorkoutTask(w http.ResponseWriter, r *http.Request) {
     var task models.WorkoutTask
-    if err := json.NewDecoder(r.Body).Decode(&task); err != nil {
-        h.Logger.Error("Failed to decode request body", zap.Error(err))
-        http.Error(w, "Invalid request body", http.StatusBadRequest)
+    if err := decodeJSON(r.Body, &task); err != nil {
+        writeInvalid(w, r, err)
         return
     }
 
+    err := h.DB.RunInTransaction(r.Context(), func(tx *pg.Tx) error {
+        return createTask(tx, &task)
+    })
+    if err != nil {
+        h.writeError(w, r, err, "Failed to create workout task")
+        return
+    }
+
+    setETag(w, task.Version)
+    writeJSON(w, http.StatusCreated, task)
+}
+
+// createTask validates a new task and inserts it after the user's
+// existing tasks, recording it in the task's history.
+func createTask(db orm.DB, task *models.WorkoutTask) error {
+    if task.Category == "" {
+        task.Category = models.TaskCategoryExercise
+    }
+    // Only imports add history; tasks created here are part of the plan
+    task.Source = models.TaskSourcePlan
+    if err := validateTask(task); err != nil {
+        return err
+    }
+
     task.CreatedAt = time.Now()
     task.UpdatedAt = time.Now()
 
-    if _, err := h.DB.Model(&task).Insert(); err != nil {
-        h.Logger.Error("Failed to create workout task", zap.Error(err))
-        http.Error(w, "Failed to create workout task", http.StatusInternalServerError)
-        return
+    // New tasks are appended after the user's existing tasks
+    position, err := nextTaskPosition(db, task.UserID)
+    if err != nil {
+        return err
+    }
+    task.Position = position
+
+    if _, err := db.Model(task).Insert(); err != nil {
+        return err
     }
+    if err := auditTask(db, models.AuditCreate, nil, task); err != nil {
+        return err
+    }
+    return schedule.RefreshTask(db, task)
+}
 
-    w.WriteHeader(http.StatusCreated)
-    json.NewEncoder(w).Encode(task)
+func scheduleChanged(before, after *models.WorkoutTask) bool {
+    if before.Recurrence != after.Recurrence {
+        return true
+    }
+    if (before.ScheduledFor == nil) != (after.ScheduledFor == nil) {
+        return true
+    }
+    return before.ScheduledFor != nil && !before.ScheduledFor.Equal(after.ScheduledFor.Time)
+}
+
+// nextTaskPosition returns the position after the user's last plan task.
+// db must be a transaction: the user's task order stays locked until it
+// ends, so concurrent creates do not take the same position.
+func nextTaskPosition(db orm.DB, userID int) (int, error) {
+    if err := lockTaskOrder(db, userID); err != nil {
+        return 0, err
+    }
+    var position int
+    err := db.Model((*models.WorkoutTask)(nil)).
+        ColumnExpr("COALESCE(MAX(position) + 1, 0)").
+        Where("user_id = ?", userID).
+        Where("source = ?", models.TaskSourcePlan).
+        Select(pg.Scan(&position))
+    return position, err
+}
+
+// lockTaskOrder serializes the writes that assign positions to a user's
+// plan tasks by locking the user's profile row until the transaction ends.
+// The lock does not block inserts that only reference the profile.
+func lockTaskOrder(db orm.DB, userID int) error {
+    _, err := db.Exec("SELECT 1 FROM user_profiles WHERE id = ? FOR NO KEY UPDATE", userID)
+    return err
 }
 
 func (h *Handler) GetWorkoutTask(w http.ResponseWriter, r *http.Request) {
+    selection, ok := requestedFields(w, r, models.WorkoutTask{})
+    if !ok {
+        return
+    }
+
     vars := mux.Vars(r)
     idStr := vars["id"]
     
     id, err := strconv.Atoi(idStr)
     if err != nil {
         h.Logger.Error("Invalid ID format", zap.String("id", idStr))
-        http.Error(w, "Invalid ID format", http.StatusBadRequest)
+        problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidRequest, "Invalid ID format")
         return
     }
 
@@ -51,93 +126,278 @@ func (h *Handler) GetWorkoutTask(w http.ResponseWriter, r *http.Request) {
     if err != nil {
         if err == pg.ErrNoRows {
             h.Logger.Error("Workout task not found", zap.Int("id", id))
-            http.Error(w, "Workout task not found", http.StatusNotFound)
+            problem.Error(w, r, http.StatusNotFound, problem.CodeNotFound, "Workout task not found")
             return
         }
-        h.Logger.Error("Failed to get workout task", zap.Error(err))
-        http.Error(w, "Failed to get workout task", http.StatusInternalServerError)
+        h.writeError(w, r, err, "Failed to get workout task")
         return
     }
 
-    json.NewEncoder(w).Encode(task)
+    if notModified(w, r, task.Version) {
+        return
+    }
+    writeJSON(w, http.StatusOK, selection.Apply(task))
 }
 
-func (h *Handler) ListWorkoutTasks(w http.ResponseWriter, r *http.Request) {
-    var tasks []models.WorkoutTask
-    query := h.DB.Model(&tasks)
+// Tasks are listed per user in their explicit order unless the client
+// sorts them with ?sort=
+var (
+    taskKeyset     = paging.Asc(paging.IntColumn("user_id"), paging.IntColumn("position"), paging.IntColumn("id"))
+    userTaskKeyset = paging.Asc(paging.IntColumn("position"), paging.IntColumn("id"))
+)
+
+// taskNameSQL normalizes a task name the way catalog.Normalize does, so
+// tasks named "Push-Ups" match the catalog's "push ups".
+const taskNameSQL = `trim(regexp_replace(regexp_replace(lower(name), '''', '', 'g'), '[^a-z0-9()]+', ' ', 'g'))`
+
+// taskFilters are the filters and sorts of the task lists. There is no
+// plan entity; tasks are planned in groups, so ?groupId= selects a plan.
+var taskFilters = filter.Spec{
+    Fields: []filter.Field{
+        {Param: "completed", Column: "completed", Type: filter.Bool},
+        {Param: "category", Column: "category", Allowed: []string{models.TaskCategoryExercise, models.TaskCategoryWarmup, models.TaskCategoryCooldown}},
+        {Param: "source", Column: "source", Allowed: []string{models.TaskSourcePlan, models.TaskSourceImport}},
+        {Param: "scheduledFrom", Column: "scheduled_for", Type: filter.Date, Op: filter.Gte},
+        {Param: "scheduledTo", Column: "scheduled_for", Type: filter.Date, Op: filter.Lte},
+        {Param: "groupId", Column: "group_id", Type: filter.Int},
+        {Param: "name", Column: "name", Op: filter.Contains},
+        {Param: "q", Column: "search_vector", Op: filter.Search},
+        {Param: "exercise", Where: exerciseFilter},
+        {Param: "muscle", Where: muscleFilter},
+    },
+    Sorts: map[string]paging.Column{
+        "name":      paging.TextColumn("name"),
+        "sets":      paging.IntColumn("sets"),
+        "reps":      paging.IntColumn("reps"),
+        "position":  paging.IntColumn("position"),
+        "createdAt": paging.TimeColumn("created_at"),
+        "updatedAt": paging.TimeColumn("updated_at"),
+    },
+}
+
+// allTaskFilters also lets the list of every user's tasks pick one user.
+var allTaskFilters = taskFilters.With(filter.Field{Param: "userId", Column: "user_id", Type: filter.Int})
 
-    // Add pagination
-    limit := 10
-    if r.URL.Query().Get("limit") != "" {
-        fmt.Sscanf(r.URL.Query().Get("limit"), "%d", &limit)
+// exerciseFilter matches tasks named after a catalog exercise or one of
+// its aliases.
+func exerciseFilter(value string) (string, []interface{}, error) {
+    exercise, ok := catalog.Lookup(value)
+    if !ok {
+        return "", nil, errors.New("exercise is not in the catalog")
     }
-    offset := 0
-    if r.URL.Query().Get("offset") != "" {
-        fmt.Sscanf(r.URL.Query().Get("offset"), "%d", &offset)
+    return taskNameSQL + " IN (?)", []interface{}{pg.In(exercise.Names())}, nil
+}
+
+// muscleFilter matches tasks named after any catalog exercise training
+// the muscle group.
+func muscleFilter(value string) (string, []interface{}, error) {
+    exercises := catalog.ByMuscle(value)
+    if len(exercises) == 0 {
+        return "", nil, fmt.Errorf("muscle must be one of %s", strings.Join(catalog.MuscleGroups, ", "))
+    }
+    var names []string
+    for _, e := range exercises {
+        names = append(names, e.Names()...)
+    }
+    return taskNameSQL + " IN (?)", []interface{}{pg.In(names)}, nil
+}
+
+// taskCursorValues returns a task's value of each sortable column, in the
+// form a cursor stores it.
+var taskCursorValues = map[string]func(t *models.WorkoutTask) string{
+    "id":         func(t *models.WorkoutTask) string { return strconv.Itoa(t.ID) },
+    "user_id":    func(t *models.WorkoutTask) string { return strconv.Itoa(t.UserID) },
+    "position":   func(t *models.WorkoutTask) string { return strconv.Itoa(t.Position) },
+    "name":       func(t *models.WorkoutTask) string { return t.Name },
+    "sets":       func(t *models.WorkoutTask) string { return strconv.Itoa(t.Sets) },
+    "reps":       func(t *models.WorkoutTask) string { return strconv.Itoa(t.Reps) },
+    "created_at": func(t *models.WorkoutTask) string { return t.CreatedAt.Format(time.RFC3339Nano) },
+    "updated_at": func(t *models.WorkoutTask) string { return t.UpdatedAt.Format(time.RFC3339Nano) },
+}
+
+// listTasks serves a page of the tasks query selects, filtered and sorted
+// as the request asks.
+func (h *Handler) listTasks(w http.ResponseWriter, r *http.Request, spec filter.Spec, keyset paging.Keyset, query func(*[]models.WorkoutTask) *orm.Query) {
+    filters, err := spec.Parse(r.URL.Query(), paging.IntColumn("id"))
+    if err != nil {
+        writeInvalid(w, r, err)
+        return
+    }
+    if filters.Sort != nil {
+        keyset = filters.Sort
+    }
+    params, err := paging.FromRequest(r, keyset)
+    if err != nil {
+        writeInvalid(w, r, err)
+        return
+    }
+    selection, ok := requestedFields(w, r, models.WorkoutTask{})
+    if !ok {
+        return
     }
-    query.Limit(limit).Offset(offset)
 
-    err := query.Select()
+    var tasks []models.WorkoutTask
+    q := filters.Apply(query(&tasks))
+    // Imported history is listed only when asked for with ?source=
+    if r.URL.Query().Get("source") == "" {
+        q = q.Where("source = ?", models.TaskSourcePlan)
+    }
+    total, err := params.Total(q)
+    if err == nil {
+        err = params.Apply(q).Select()
+    }
     if err != nil {
-        h.Logger.Error("Failed to list workout tasks", zap.Error(err))
-        http.Error(w, "Failed to list workout tasks", http.StatusInternalServerError)
+        h.writeError(w, r, err, "Failed to list workout tasks")
         return
     }
 
-    json.NewEncoder(w).Encode(tasks)
+    tasks, next := paging.Trim(params, tasks, func(t *models.WorkoutTask) []string {
+        values := make([]string, len(keyset))
+        for i, c := range keyset {
+            values[i] = taskCursorValues[c.Name](t)
+        }
+        return values
+    })
+    writePage(w, r, selection.Apply(tasks), next, total)
+}
+
+func (h *Handler) ListWorkoutTasks(w http.ResponseWriter, r *http.Request) {
+    h.listTasks(w, r, allTaskFilters, taskKeyset, func(tasks *[]models.WorkoutTask) *orm.Query {
+        return h.DB.Model(tasks)
+    })
 }
 
+// UpdateWorkoutTask replaces a task. Name, sets and reps are required;
+// optional fields left out are cleared or reset to their defaults.
 func (h *Handler) UpdateWorkoutTask(w http.ResponseWriter, r *http.Request) {
+    existingTask, ok := h.findTaskForWrite(w, r)
+    if !ok {
+        return
+    }
+
+    var updatedTask models.WorkoutTask
+    if err := decodeReplacement(r, &updatedTask, taskRequiredFields); err != nil {
+        writeInvalid(w, r, err)
+        return
+    }
+    if updatedTask.Category == "" {
+        updatedTask.Category = models.TaskCategoryExercise
+    }
+    h.saveTask(w, r, models.AuditUpdate, existingTask, &updatedTask)
+}
+
+// PatchWorkoutTask applies a JSON merge patch to a task.
+func (h *Handler) PatchWorkoutTask(w http.ResponseWriter, r *http.Request) {
+    existingTask, ok := h.findTaskForWrite(w, r)
+    if !ok {
+        return
+    }
+    if !isMergePatch(r) {
+        problem.Error(w, r, http.StatusUnsupportedMediaType, problem.CodeUnsupportedMediaType, "PATCH expects application/merge-patch+json")
+        return
+    }
+
+    current := *existingTask
+    var updatedTask models.WorkoutTask
+    if _, err := decodeMergePatch(r, &current, &updatedTask); err != nil {
+        writeInvalid(w, r, err)
+        return
+    }
+    h.saveTask(w, r, models.AuditUpdate, existingTask, &updatedTask)
+}
+
+// findTaskForWrite loads the task named in the URL and checks the client's
+// If-Match against it, writing the error response itself.
+func (h *Handler) findTaskForWrite(w http.ResponseWriter, r *http.Request) (*models.WorkoutTask, bool) {
     vars := mux.Vars(r)
     idStr := vars["id"]
-    
+
     id, err := strconv.Atoi(idStr)
     if err != nil {
         h.Logger.Error("Invalid ID format", zap.String("id", idStr))
-        http.Error(w, "Invalid ID format", http.StatusBadRequest)
-        return
+        problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidRequest, "Invalid ID format")
+        return nil, false
     }
 
-    // First fetch the existing task
     existingTask := &models.WorkoutTask{ID: id}
     err = h.DB.Model(existingTask).WherePK().Select()
     if err != nil {
         if err == pg.ErrNoRows {
-            http.Error(w, "Workout task not found", http.StatusNotFound)
-            return
+            problem.Error(w, r, http.StatusNotFound, problem.CodeNotFound, "Workout task not found")
+            return nil, false
         }
-        h.Logger.Error("Failed to fetch workout task", zap.Error(err))
-        http.Error(w, "Failed to fetch workout task", http.StatusInternalServerError)
-        return
+        h.writeError(w, r, err, "Failed to fetch workout task")
+        return nil, false
     }
+    if !requireIfMatch(w, r, existingTask.Version) {
+        return nil, false
+    }
+    return existingTask, true
+}
 
-    // Decode the update request
-    var updatedTask models.WorkoutTask
-    if err := json.NewDecoder(r.Body).Decode(&updatedTask); err != nil {
-        h.Logger.Error("Failed to decode request body", zap.Error(err))
-        http.Error(w, "Invalid request body", http.StatusBadRequest)
+// saveTask validates and writes an updated task, as long as nobody changed
+// it since existingTask was read.
+func (h *Handler) saveTask(w http.ResponseWriter, r *http.Request, action string, existingTask, updatedTask *models.WorkoutTask) {
+    err := h.DB.RunInTransaction(r.Context(), func(tx *pg.Tx) error {
+        return updateTask(tx, action, existingTask, updatedTask)
+    })
+    if err != nil {
+        if err == errVersionChanged {
+            writeVersionChanged(w, r, 0)
+            return
+        }
+        h.writeError(w, r, err, "Failed to update workout task")
         return
     }
 
-    // Preserve the ID, user_id, and created_at
-    updatedTask.ID = id
+    setETag(w, updatedTask.Version)
+    writeJSON(w, http.StatusOK, updatedTask)
+}
+
+// updateTask validates updatedTask and writes it over existingTask, as
+// long as the stored task is still at existingTask's version. The write
+// is recorded in the task's history under action.
+func updateTask(db orm.DB, action string, existingTask, updatedTask *models.WorkoutTask) error {
+    // Preserve the ID, user_id, source, position, and created_at.
+    // Positions only change through the reorder endpoint.
+    updatedTask.ID = existingTask.ID
     updatedTask.UserID = existingTask.UserID
+    updatedTask.Source = existingTask.Source
+    updatedTask.Position = existingTask.Position
+    if err := validateTask(updatedTask); err != nil {
+        return err
+    }
     updatedTask.CreatedAt = existingTask.CreatedAt
     updatedTask.UpdatedAt = time.Now()
 
-    res, err := h.DB.Model(&updatedTask).WherePK().Update()
+    // Only update the version the client read
+    res, err := db.Model(updatedTask).
+        WherePK().
+        Where("version = ?", existingTask.Version).
+        Returning("version").
+        Update()
     if err != nil {
-        h.Logger.Error("Failed to update workout task", zap.Error(err))
-        http.Error(w, "Failed to update workout task", http.StatusInternalServerError)
-        return
+        return err
     }
-
     if res.RowsAffected() == 0 {
-        http.Error(w, "Workout task not found", http.StatusNotFound)
-        return
+        return errVersionChanged
+    }
+    if err := auditTask(db, action, existingTask, updatedTask); err != nil {
+        return err
     }
 
-    json.NewEncoder(w).Encode(updatedTask)
+    // Pending occurrences are regenerated when the schedule changes
+    if scheduleChanged(existingTask, updatedTask) {
+        today, err := schedule.Today(db, updatedTask.UserID)
+        if err != nil {
+            return err
+        }
+        if err := schedule.ClearFuture(db, updatedTask.ID, today); err != nil {
+            return err
+        }
+        return schedule.RefreshTask(db, updatedTask)
+    }
+    return nil
 }
 
 func (h *Handler) DeleteWorkoutTask(w http.ResponseWriter, r *http.Request) {
@@ -148,34 +408,44 @@ func (h *Handler) DeleteWorkoutTask(w http.ResponseWriter, r *http.Request) {
     id, err := strconv.Atoi(idStr)
     if err != nil {
         h.Logger.Error("Invalid task ID", zap.Error(err))
-        http.Error(w, "Invalid task ID", http.StatusBadRequest)
+        problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidRequest, "Invalid task ID")
         return
     }
 
-    var task models.WorkoutTask
-    task.ID = id
+    task := &models.WorkoutTask{ID: id}
+    if err := h.DB.Model(task).Column("id", "version").WherePK().Select(); err != nil {
+        if err == pg.ErrNoRows {
+            problem.Error(w, r, http.StatusNotFound, problem.CodeNotFound, "Workout task not found")
+            return
+        }
+        h.writeError(w, r, err, "Failed to delete workout task")
+        return
+    }
+    if !requireIfMatch(w, r, task.Version) {
+        return
+    }
 
-    res, err := h.DB.Model(&task).WherePK().Delete()
+    var trashed bool
+    err = h.DB.RunInTransaction(r.Context(), func(tx *pg.Tx) error {
+        var err error
+        trashed, err = trashTask(tx, task.ID, task.Version)
+        return err
+    })
     if err != nil {
-        h.Logger.Error("Failed to delete workout task", zap.Error(err))
-        http.Error(w, "Failed to delete workout task", http.StatusInternalServerError)
+        h.writeError(w, r, err, "Failed to delete workout task")
         return
     }
 
-    if res.RowsAffected() == 0 {
-        http.Error(w, "Workout task not found", http.StatusNotFound)
+    if !trashed {
+        writeVersionChanged(w, r, 0)
         return
     }
 
-    // Set response header to application/json
-    w.Header().Set("Content-Type", "application/json")
-    w.WriteHeader(http.StatusOK)
-    
     // Return success message
     response := map[string]string{
-        "message": fmt.Sprintf("Workout task with ID %d has been successfully deleted", id),
+        "message": fmt.Sprintf("Workout task with ID %d has been moved to the trash", id),
     }
-    json.NewEncoder(w).Encode(response)
+    writeJSON(w, http.StatusOK, response)
 }
 
 func (h *Handler) GetWorkoutTasksByUserId(w http.ResponseWriter, r *http.Request) {
@@ -188,38 +458,110 @@ func (h *Handler) GetWorkoutTasksByUserId(w http.ResponseWriter, r *http.Request
     if err != nil {
         if err == pg.ErrNoRows {
             h.Logger.Error("User profile not found", zap.String("userId", userId))
-            http.Error(w, "User profile not found", http.StatusNotFound)
+            problem.Error(w, r, http.StatusNotFound, problem.CodeNotFound, "User profile not found")
             return
         }
-        h.Logger.Error("Failed to get user profile", zap.Error(err))
-        http.Error(w, "Failed to get user profile", http.StatusInternalServerError)
+        h.writeError(w, r, err, "Failed to get user profile")
         return
     }
 
-    // Now get all tasks for this profile
-    var tasks []models.WorkoutTask
-    query := h.DB.Model(&tasks).Where("user_id = ?", profile.ID)
+    // Now list the tasks of this profile
+    h.listTasks(w, r, taskFilters, userTaskKeyset, func(tasks *[]models.WorkoutTask) *orm.Query {
+        return h.DB.Model(tasks).Where("user_id = ?", profile.ID)
+    })
+}
+
+type ReorderTasksRequest struct {
+    UserID  int   `json:"userId"`
+    TaskIDs []int `json:"taskIds"`
+}
 
-    // Add pagination
-    limit := 10
-    if r.URL.Query().Get("limit") != "" {
-        fmt.Sscanf(r.URL.Query().Get("limit"), "%d", &limit)
+var errStaleTaskOrder = errors.New("task list does not match the user's current tasks")
+
+// ReorderWorkoutTasks rewrites the positions of all of a user's plan tasks
+// from the full ordered id list. A list that is missing tasks or names
+// tasks the user no longer has is rejected as stale. Imported tasks are
+// not part of the order.
+func (h *Handler) ReorderWorkoutTasks(w http.ResponseWriter, r *http.Request) {
+    var req ReorderTasksRequest
+    if err := decodeJSON(r.Body, &req); err != nil {
+        writeInvalid(w, r, err)
+        return
     }
-    offset := 0
-    if r.URL.Query().Get("offset") != "" {
-        fmt.Sscanf(r.URL.Query().Get("offset"), "%d", &offset)
+
+    seen := make(map[int]bool, len(req.TaskIDs))
+    for _, id := range req.TaskIDs {
+        if seen[id] {
+            problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidRequest, fmt.Sprintf("Duplicate task ID %d", id))
+            return
+        }
+        seen[id] = true
     }
-    query.Limit(limit).Offset(offset)
 
-    // Add sorting by created_at in descending order (newest first)
-    query.Order("created_at DESC")
+    var tasks []models.WorkoutTask
+    err := h.DB.RunInTransaction(r.Context(), func(tx *pg.Tx) error {
+        // Concurrent creates and reorders of the user's tasks serialize
+        if err := lockTaskOrder(tx, req.UserID); err != nil {
+            return err
+        }
+        var current []models.WorkoutTask
+        err := tx.Model(&current).
+            Where("user_id = ?", req.UserID).
+            Where("source = ?", models.TaskSourcePlan).
+            For("UPDATE").
+            Select()
+        if err != nil {
+            return err
+        }
+
+        if len(current) != len(req.TaskIDs) {
+            return errStaleTaskOrder
+        }
+        for _, task := range current {
+            if !seen[task.ID] {
+                return errStaleTaskOrder
+            }
+        }
+
+        _, err = tx.Exec(`
+            UPDATE workout_tasks AS t
+            SET position = v.ord - 1, updated_at = ?
+            FROM unnest(?::int[]) WITH ORDINALITY AS v(id, ord)
+            WHERE t.id = v.id AND t.position <> v.ord - 1`, time.Now(), pg.Array(req.TaskIDs))
+        if err != nil {
+            return err
+        }
+
+        err = tx.Model(&tasks).
+            Where("user_id = ?", req.UserID).
+            Where("source = ?", models.TaskSourcePlan).
+            Order("position ASC", "id ASC").
+            Select()
+        if err != nil {
+            return err
+        }
 
-    err = query.Select()
+        // Only the tasks that moved were written
+        positions := make(map[int]int, len(current))
+        for _, task := range current {
+            positions[task.ID] = task.Position
+        }
+        var moved []models.WorkoutTask
+        for _, task := range tasks {
+            if task.Position != positions[task.ID] {
+                moved = append(moved, task)
+            }
+        }
+        return auditTasks(tx, models.AuditReorder, current, moved)
+    })
     if err != nil {
-        h.Logger.Error("Failed to list workout tasks", zap.Error(err))
-        http.Error(w, "Failed to list workout tasks", http.StatusInternalServerError)
+        if err == errStaleTaskOrder {
+            problem.Error(w, r, http.StatusConflict, problem.CodeConflict, "Task order is stale, reload the task list and try again")
+            return
+        }
+        h.writeError(w, r, err, "Failed to reorder workout tasks")
         return
     }
 
-    json.NewEncoder(w).Encode(tasks)
-} 
\ No newline at end of file
+    writeJSON(w, http.StatusOK, tasks)
+}
@@ -0,0 +1,105 @@
+// importer/create.go
+package importer
+
+import (
+	"time"
+
+	"back-end/audit"
+	"back-end/catalog"
+	"back-end/models"
+	"github.com/go-pg/pg/v10/orm"
+)
+
+// Check marks the entries the user already has a task for, with
+// the same name on the same day, as duplicates, so importing a file twice
+// creates nothing the second time. Entries whose task would not be valid
+// are left out and reported against their first row.
+func Check(db orm.DB, profile *models.UserProfile, format string, entries []Entry) ([]Entry, []models.ImportRowError, error) {
+	if len(entries) == 0 {
+		return nil, nil, nil
+	}
+	from, to := entries[0].Date, entries[0].Date
+	for _, e := range entries {
+		if e.Date.Before(from) {
+			from = e.Date
+		}
+		if to.Before(e.Date) {
+			to = e.Date
+		}
+	}
+	var existing []models.WorkoutTask
+	err := db.Model(&existing).
+		Column("name", "scheduled_for").
+		Where("user_id = ?", profile.ID).
+		Where("scheduled_for BETWEEN ? AND ?", from, to).
+		Select()
+	if err != nil {
+		return nil, nil, err
+	}
+	kept, errs := check(profile, format, entries, existing)
+	return kept, errs, nil
+}
+
+// check does the work of Check against the user's existing tasks within
+// the entries' dates.
+func check(profile *models.UserProfile, format string, entries []Entry, existing []models.WorkoutTask) ([]Entry, rowErrors) {
+	logged := make(map[string]bool, len(existing))
+	for _, t := range existing {
+		logged[t.ScheduledFor.String()+"|"+catalog.Normalize(t.Name)] = true
+	}
+
+	var kept []Entry
+	var errs rowErrors
+	for _, e := range entries {
+		task := e.Task(profile, format)
+		if err := task.Validate(); err != nil {
+			errs.add(e.Rows[0], "", "%s: %s", e.Exercise, err.Error())
+			continue
+		}
+		e.Duplicate = logged[e.Date.String()+"|"+catalog.Normalize(e.Name)]
+		kept = append(kept, e)
+	}
+	return kept, errs
+}
+
+// Create creates the completed task of a checked entry with its completed
+// occurrence. It reports false for a duplicate, which is skipped. The task
+// is marked as imported, which keeps it out of the plan and its order. Its
+// history names the actor and request in db's context.
+func Create(db orm.DB, profile *models.UserProfile, format string, e *Entry) (bool, error) {
+	if e.Duplicate {
+		return false, nil
+	}
+	task := e.Task(profile, format)
+	now := time.Now()
+	task.CreatedAt = now
+	task.UpdatedAt = now
+	if _, err := db.Model(&task).Insert(); err != nil {
+		return false, err
+	}
+
+	completedAt := e.Date.Time
+	occurrence := models.TaskOccurrence{
+		TaskID:       task.ID,
+		UserID:       profile.ID,
+		OccursOn:     e.Date,
+		ScheduledFor: e.Date,
+		Status:       models.OccurrenceCompleted,
+		CompletedAt:  &completedAt,
+		CreatedAt:    now,
+		UpdatedAt:    now,
+	}
+	if _, err := db.Model(&occurrence).Insert(); err != nil {
+		return false, err
+	}
+
+	err := audit.Record(db, audit.Event{
+		Action:   models.AuditCreate,
+		Entity:   models.AuditEntityTask,
+		EntityID: task.ID,
+		UserID:   task.UserID,
+		Version:  task.Version,
+		After:    &task,
+	})
+	return err == nil, err
+}
@@ -0,0 +1,101 @@
+// importer/generic.go
+package importer
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"back-end/models"
+)
+
+// Dates of generic files are ISO 8601 days or timestamps
+var genericDateLayouts = []string{"2006-01-02", time.RFC3339, "2006-01-02 15:04:05", "2006-01-02 15:04"}
+
+// Fields of a generic file, in the order a JSON row is read in
+var genericFields = []string{"date", "exercise", "sets", "reps", "weight", "weightUnit", "seconds"}
+
+// parseGenericCSV reads a CSV file with the columns date, exercise, sets,
+// reps, weight, weight_unit and seconds. Only date, exercise and reps are
+// required; a row without sets is one set.
+func parseGenericCSV(data []byte, weightUnit string) ([]Set, []models.ImportRowError, error) {
+	t, err := readTable(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	c := setColumns{weightUnit: weightUnit, dateLayouts: genericDateLayouts}
+	if c.date, err = t.require("date"); err != nil {
+		return nil, nil, err
+	}
+	if c.exercise, err = t.require("exercise", "exercise name", "name"); err != nil {
+		return nil, nil, err
+	}
+	if c.reps, err = t.require("reps"); err != nil {
+		return nil, nil, err
+	}
+	c.sets = t.column("sets")
+	c.weight = t.column("weight")
+	c.unit = t.column("weight_unit", "weightunit", "unit")
+	c.seconds = t.column("seconds", "duration_seconds")
+
+	var sets []Set
+	var errs rowErrors
+	for i, record := range t.records {
+		if s, ok := c.read(record, t.lines[i], &errs); ok {
+			sets = append(sets, s)
+		}
+	}
+	return sets, errs, nil
+}
+
+// parseGenericJSON reads a JSON array of objects with the fields of
+// genericFields. Numbers may be given as numbers or strings. Rows are
+// numbered from one in the order of the array.
+func parseGenericJSON(data []byte, weightUnit string) ([]Set, []models.ImportRowError, error) {
+	var rows []map[string]json.RawMessage
+	if err := json.Unmarshal(bytes.TrimPrefix(data, []byte("\xef\xbb\xbf")), &rows); err != nil {
+		return nil, nil, errors.New("the file is not a JSON array of objects")
+	}
+	c := setColumns{date: 0, exercise: 1, sets: 2, reps: 3, weight: 4, unit: 5, seconds: 6,
+		weightUnit: weightUnit, dateLayouts: genericDateLayouts}
+
+	var sets []Set
+	var errs rowErrors
+	for i, row := range rows {
+		before := len(errs)
+		record := make([]string, len(genericFields))
+		for j, name := range genericFields {
+			value, err := jsonValue(row[name])
+			if err != nil {
+				errs.add(i+1, name, "%s", err.Error())
+			}
+			record[j] = value
+		}
+		if len(errs) > before {
+			continue
+		}
+		if s, ok := c.read(record, i+1, &errs); ok {
+			sets = append(sets, s)
+		}
+	}
+	return sets, errs, nil
+}
+
+// jsonValue returns a string or number as text; a missing field or null
+// is empty.
+func jsonValue(raw json.RawMessage) (string, error) {
+	if len(raw) == 0 || string(raw) == "null" {
+		return "", nil
+	}
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return s, nil
+	}
+	var n json.Number
+	if err := json.Unmarshal(raw, &n); err == nil {
+		return n.String(), nil
+	}
+	return "", fmt.Errorf("%s is not a string or number", raw)
+}
@@ -0,0 +1,55 @@
+// importer/hevy.go
+package importer
+
+import (
+	"strings"
+	"time"
+
+	"back-end/models"
+	"back-end/units"
+)
+
+// Hevy writes dates like "5 Mar 2024, 18:02"; newer exports may use RFC
+// 3339 instead
+var hevyDateLayouts = []string{"2 Jan 2006, 15:04", time.RFC3339, "2006-01-02 15:04:05"}
+
+// parseHevy reads the CSV export of the Hevy app, one row per set. Warm-up
+// sets are left out. The header names the weight unit.
+func parseHevy(data []byte, weightUnit string) ([]Set, []models.ImportRowError, error) {
+	t, err := readTable(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	c := setColumns{sets: -1, unit: -1, weightUnit: weightUnit, dateLayouts: hevyDateLayouts}
+	if c.date, err = t.require("start_time"); err != nil {
+		return nil, nil, err
+	}
+	if c.exercise, err = t.require("exercise_title"); err != nil {
+		return nil, nil, err
+	}
+	if c.reps, err = t.require("reps"); err != nil {
+		return nil, nil, err
+	}
+	c.seconds = t.column("duration_seconds")
+	switch {
+	case t.column("weight_kg") >= 0:
+		c.weight, c.weightUnit = t.column("weight_kg"), units.Kilogram
+	case t.column("weight_lbs", "weight_lb") >= 0:
+		c.weight, c.weightUnit = t.column("weight_lbs", "weight_lb"), units.Pound
+	default:
+		c.weight = t.column("weight")
+	}
+	setType := t.column("set_type")
+
+	var sets []Set
+	var errs rowErrors
+	for i, record := range t.records {
+		if strings.EqualFold(field(record, setType), "warmup") {
+			continue
+		}
+		if s, ok := c.read(record, t.lines[i], &errs); ok {
+			sets = append(sets, s)
+		}
+	}
+	return sets, errs, nil
+}
@@ -0,0 +1,342 @@
+// importer/importer.go
+package importer
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"back-end/catalog"
+	"back-end/models"
+	"back-end/units"
+)
+
+// Supported file formats
+const (
+	FormatStrong = "strong"
+	FormatHevy   = "hevy"
+	FormatCSV    = "csv"
+	FormatJSON   = "json"
+)
+
+// Formats lists the supported formats.
+var Formats = []string{FormatStrong, FormatHevy, FormatCSV, FormatJSON}
+
+var formatNames = map[string]string{
+	FormatStrong: "Strong",
+	FormatHevy:   "Hevy",
+	FormatCSV:    "a CSV file",
+	FormatJSON:   "a JSON file",
+}
+
+// IsValidFormat reports whether files of format can be imported.
+func IsValidFormat(format string) bool {
+	_, ok := formatNames[format]
+	return ok
+}
+
+// Set is one logged set read from a file. Sets is more than one when a
+// row of a generic file stands for several identical sets.
+type Set struct {
+	Row      int
+	Date     models.Date
+	Exercise string
+	Sets     int
+	Reps     int
+	WeightKg float64
+	Seconds  int
+}
+
+// parser reads the sets of a file. Rows it cannot read are reported and
+// skipped; an error means the file as a whole cannot be read.
+type parser func(data []byte, weightUnit string) ([]Set, []models.ImportRowError, error)
+
+var parsers = map[string]parser{
+	FormatStrong: parseStrong,
+	FormatHevy:   parseHevy,
+	FormatCSV:    parseGenericCSV,
+	FormatJSON:   parseGenericJSON,
+}
+
+// Parse reads the sets of a file in the given format. weightUnit is the
+// unit of weights the file does not name a unit for, kilograms if empty.
+func Parse(format string, data []byte, weightUnit string) ([]Set, []models.ImportRowError, error) {
+	p, ok := parsers[format]
+	if !ok {
+		return nil, nil, fmt.Errorf("unsupported format %q", format)
+	}
+	return p(data, weightUnit)
+}
+
+// Entry is one task an import creates: an exercise done on one day, with
+// its sets merged. Name is the catalog name when the exercise matched.
+type Entry struct {
+	Date        models.Date `json:"date"`
+	Exercise    string      `json:"exercise"`
+	Name        string      `json:"name"`
+	Matched     bool        `json:"matched"`
+	Sets        int         `json:"sets"`
+	Reps        int         `json:"reps"`
+	TopWeightKg float64     `json:"topWeightKg,omitempty"`
+	Seconds     int         `json:"seconds,omitempty"`
+	Rows        []int       `json:"rows"`
+	// Duplicate marks an entry the user already has a task for
+	Duplicate bool `json:"duplicate,omitempty"`
+}
+
+// Plan merges sets into entries, in date order and then in the order the
+// exercises first appear. The heaviest set decides the entry's reps.
+func Plan(sets []Set) []Entry {
+	var entries []Entry
+	byKey := make(map[string]int)
+	for _, s := range sets {
+		name, matched := s.Exercise, false
+		if e, ok := Match(s.Exercise); ok {
+			name, matched = e.Name, true
+		}
+		key := s.Date.String() + "|" + catalog.Normalize(name)
+		i, ok := byKey[key]
+		if !ok {
+			i = len(entries)
+			byKey[key] = i
+			entries = append(entries, Entry{Date: s.Date, Exercise: s.Exercise, Name: name, Matched: matched})
+		}
+		e := &entries[i]
+		e.Sets += s.Sets
+		e.Rows = append(e.Rows, s.Row)
+		if s.WeightKg > e.TopWeightKg || e.Reps == 0 {
+			e.TopWeightKg = s.WeightKg
+			e.Reps = s.Reps
+		}
+		if s.Seconds > e.Seconds {
+			e.Seconds = s.Seconds
+		}
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return entries[i].Date.Before(entries[j].Date)
+	})
+	return entries
+}
+
+// Match finds the catalog exercise for a name from another app. Apps often
+// name the equipment in parentheses, as in "Bench Press (Barbell)"; when
+// the catalog has no alias for that form the bare name is tried.
+func Match(name string) (catalog.Exercise, bool) {
+	if e, ok := catalog.Lookup(name); ok {
+		return e, true
+	}
+	if i := strings.Index(name, "("); i > 0 {
+		return catalog.Lookup(name[:i])
+	}
+	return catalog.Exercise{}, false
+}
+
+// Unmatched returns the exercise names that did not match the catalog,
+// each once. Their tasks keep the name from the file.
+func Unmatched(entries []Entry) []string {
+	var names []string
+	seen := make(map[string]bool)
+	for _, e := range entries {
+		if !e.Matched && !seen[e.Name] {
+			seen[e.Name] = true
+			names = append(names, e.Name)
+		}
+	}
+	return names
+}
+
+// Task returns the completed task for the entry. Timed sets without reps
+// count as one rep each; their duration goes in the description with the
+// top set, in the profile's units.
+func (e *Entry) Task(profile *models.UserProfile, format string) models.WorkoutTask {
+	reps := e.Reps
+	if reps == 0 {
+		reps = 1
+	}
+	description := "Imported from " + formatNames[format] + "."
+	if e.TopWeightKg > 0 {
+		weight := units.Mass.FromCanonical(e.TopWeightKg, profile.UnitSystem)
+		description += fmt.Sprintf(" Top set: %d × %s %s.", e.Reps, strconv.FormatFloat(weight, 'f', -1, 64), units.Mass.Unit(profile.UnitSystem))
+	}
+	if e.Seconds > 0 {
+		description += fmt.Sprintf(" Longest set: %s.", time.Duration(e.Seconds)*time.Second)
+	}
+
+	date := e.Date
+	return models.WorkoutTask{
+		UserID:       profile.ID,
+		Name:         e.Name,
+		Sets:         e.Sets,
+		Reps:         reps,
+		Description:  description,
+		Completed:    true,
+		Category:     models.TaskCategoryExercise,
+		Source:       models.TaskSourceImport,
+		ScheduledFor: &date,
+	}
+}
+
+// table is a CSV file read by header name.
+type table struct {
+	columns map[string]int
+	records [][]string
+	lines   []int
+}
+
+// readTable reads a CSV file with a header row. The delimiter is a
+// semicolon when the header has more of them than commas, as in files
+// exported with some European locales.
+func readTable(data []byte) (*table, error) {
+	data = bytes.TrimPrefix(data, []byte("\xef\xbb\xbf"))
+	header, _, _ := bytes.Cut(data, []byte("\n"))
+	r := csv.NewReader(bytes.NewReader(data))
+	if bytes.Count(header, []byte(";")) > bytes.Count(header, []byte(",")) {
+		r.Comma = ';'
+	}
+	r.FieldsPerRecord = -1
+
+	names, err := r.Read()
+	if err != nil {
+		if err == io.EOF {
+			return nil, errors.New("the file is empty")
+		}
+		return nil, fmt.Errorf("the file is not valid CSV: %w", err)
+	}
+	t := &table{columns: make(map[string]int)}
+	for i, name := range names {
+		t.columns[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for {
+		record, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("the file is not valid CSV: %w", err)
+		}
+		line, _ := r.FieldPos(0)
+		t.records = append(t.records, record)
+		t.lines = append(t.lines, line)
+	}
+	return t, nil
+}
+
+// column returns the index of the first of names in the header, or -1.
+func (t *table) column(names ...string) int {
+	for _, name := range names {
+		if i, ok := t.columns[name]; ok {
+			return i
+		}
+	}
+	return -1
+}
+
+// require returns the index of a column the format cannot do without.
+func (t *table) require(names ...string) (int, error) {
+	i := t.column(names...)
+	if i < 0 {
+		return -1, fmt.Errorf("the file has no %q column", names[0])
+	}
+	return i, nil
+}
+
+// field returns the trimmed value of column i of a record, or "".
+func field(record []string, i int) string {
+	if i < 0 || i >= len(record) {
+		return ""
+	}
+	return strings.TrimSpace(record[i])
+}
+
+// rowErrors collects the errors of one file.
+type rowErrors []models.ImportRowError
+
+func (e *rowErrors) add(row int, field, format string, args ...interface{}) {
+	*e = append(*e, models.ImportRowError{Row: row, Field: field, Message: fmt.Sprintf(format, args...)})
+}
+
+// setColumns names the columns a set is read from, -1 for the ones the
+// file lacks. unit, when present, names each row's weight unit; weights
+// are otherwise in weightUnit.
+type setColumns struct {
+	date, exercise, sets, reps, weight, unit, seconds int
+
+	weightUnit  string
+	dateLayouts []string
+}
+
+// read reads the set in a record, reporting the row's errors.
+func (c setColumns) read(record []string, row int, errs *rowErrors) (Set, bool) {
+	before := len(*errs)
+	s := Set{Row: row, Exercise: field(record, c.exercise), Sets: 1}
+	if s.Exercise == "" {
+		errs.add(row, "exercise", "the exercise is missing")
+	}
+
+	date, ok := parseDate(field(record, c.date), c.dateLayouts)
+	if !ok {
+		errs.add(row, "date", "%q is not a date", field(record, c.date))
+	}
+	s.Date = date
+
+	if value := field(record, c.sets); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			errs.add(row, "sets", "%q is not a number of sets", value)
+		}
+		s.Sets = n
+	}
+	reps, ok := number(field(record, c.reps))
+	if !ok || reps != float64(int(reps)) {
+		errs.add(row, "reps", "%q is not a number of reps", field(record, c.reps))
+	}
+	s.Reps = int(reps)
+	seconds, ok := number(field(record, c.seconds))
+	if !ok {
+		errs.add(row, "seconds", "%q is not a duration in seconds", field(record, c.seconds))
+	}
+	s.Seconds = int(seconds)
+	if ok && s.Reps == 0 && s.Seconds == 0 {
+		errs.add(row, "reps", "the set has neither reps nor a duration")
+	}
+
+	weight, ok := number(field(record, c.weight))
+	if !ok {
+		errs.add(row, "weight", "%q is not a weight", field(record, c.weight))
+	}
+	unit := c.weightUnit
+	if value := field(record, c.unit); value != "" {
+		unit = strings.ToLower(value)
+	}
+	var err error
+	if s.WeightKg, err = units.Mass.ToCanonical(weight, unit); err != nil {
+		errs.add(row, "weight", "%s", err.Error())
+	}
+	return s, len(*errs) == before
+}
+
+// parseDate reads the day of a date or timestamp in one of layouts.
+func parseDate(value string, layouts []string) (models.Date, bool) {
+	for _, layout := range layouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return models.DateOf(t), true
+		}
+	}
+	return models.Date{}, false
+}
+
+// number parses an optional non-negative number, with a decimal point or
+// comma; empty is zero.
+func number(value string) (float64, bool) {
+	if value == "" {
+		return 0, true
+	}
+	n, err := strconv.ParseFloat(strings.Replace(value, ",", ".", 1), 64)
+	return n, err == nil && n >= 0
+}
@@ -0,0 +1,229 @@
+package importer
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"back-end/models"
+	"back-end/units"
+)
+
+func day(s string) models.Date {
+	t, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		panic(err)
+	}
+	return models.DateOf(t)
+}
+
+// lb returns pounds in kilograms.
+func lb(v float64) float64 {
+	kg, _ := units.Mass.ToCanonical(v, units.Pound)
+	return kg
+}
+
+func parse(t *testing.T, format, data, weightUnit string) ([]Set, []models.ImportRowError) {
+	t.Helper()
+	sets, errs, err := Parse(format, []byte(data), weightUnit)
+	if err != nil {
+		t.Fatalf("Parse: %v", err)
+	}
+	return sets, errs
+}
+
+func sameSets(t *testing.T, got, want []Set) {
+	t.Helper()
+	if len(got) != len(want) {
+		t.Fatalf("got %d sets, want %d: %+v", len(got), len(want), got)
+	}
+	for i := range want {
+		g, w := got[i], want[i]
+		if g.Row != w.Row || !g.Date.Equal(w.Date.Time) || g.Exercise != w.Exercise ||
+			g.Sets != w.Sets || g.Reps != w.Reps || g.WeightKg != w.WeightKg || g.Seconds != w.Seconds {
+			t.Errorf("set %d is %+v, want %+v", i, g, w)
+		}
+	}
+}
+
+func sameErrors(t *testing.T, got []models.ImportRowError, want map[int]string) {
+	t.Helper()
+	fields := make(map[int]string)
+	for _, e := range got {
+		fields[e.Row] = e.Field
+	}
+	if !reflect.DeepEqual(fields, want) {
+		t.Fatalf("got errors %+v, want rows and fields %v", got, want)
+	}
+}
+
+func TestParseStrong(t *testing.T) {
+	tests := []struct {
+		name       string
+		data       string
+		weightUnit string
+		want       []Set
+	}{
+		{
+			"kg header with a BOM and a warm-up set",
+			"\xef\xbb\xbfDate,Workout Name,Exercise Name,Set Order,Weight (kg),Reps,Seconds\n" +
+				"2024-03-05 18:02:11,Push,Bench Press (Barbell),W,40,10,0\n" +
+				"2024-03-05 18:02:11,Push,Bench Press (Barbell),1,80,5,0\n" +
+				"2024-03-05 18:02:11,Push,Plank,1,0,0,60\n",
+			"",
+			[]Set{
+				{Row: 3, Date: day("2024-03-05"), Exercise: "Bench Press (Barbell)", Sets: 1, Reps: 5, WeightKg: 80},
+				{Row: 4, Date: day("2024-03-05"), Exercise: "Plank", Sets: 1, Seconds: 60},
+			},
+		},
+		{
+			"lbs header with semicolons",
+			"Date;Exercise Name;Set Order;Weight (lbs);Reps\n" +
+				"2024-03-05 18:02;Deadlift;1;225;5\n",
+			"",
+			[]Set{{Row: 2, Date: day("2024-03-05"), Exercise: "Deadlift", Sets: 1, Reps: 5, WeightKg: lb(225)}},
+		},
+		{
+			"weight without a unit",
+			"Date,Exercise Name,Set Order,Weight,Reps\n" +
+				"2024-03-05 18:02:11,Deadlift,1,\"102,5\",5\n",
+			units.Pound,
+			[]Set{{Row: 2, Date: day("2024-03-05"), Exercise: "Deadlift", Sets: 1, Reps: 5, WeightKg: lb(102.5)}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sets, errs := parse(t, FormatStrong, tt.data, tt.weightUnit)
+			sameErrors(t, errs, map[int]string{})
+			sameSets(t, sets, tt.want)
+		})
+	}
+}
+
+func TestParseHevy(t *testing.T) {
+	data := "title,start_time,exercise_title,set_index,set_type,weight_lbs,reps,duration_seconds\n" +
+		"Legs,\"5 Mar 2024, 18:02\",Squat (Barbell),0,warmup,95,10,\n" +
+		"Legs,\"5 Mar 2024, 18:02\",Squat (Barbell),1,normal,225,5,\n" +
+		"Legs,2024-03-06T07:30:00Z,Squat (Barbell),0,normal,,,45\n"
+	sets, errs := parse(t, FormatHevy, data, "")
+	sameErrors(t, errs, map[int]string{})
+	sameSets(t, sets, []Set{
+		{Row: 3, Date: day("2024-03-05"), Exercise: "Squat (Barbell)", Sets: 1, Reps: 5, WeightKg: lb(225)},
+		{Row: 4, Date: day("2024-03-06"), Exercise: "Squat (Barbell)", Sets: 1, Seconds: 45},
+	})
+}
+
+func TestParseGenericCSV(t *testing.T) {
+	data := "Date,Exercise,Sets,Reps,Weight,Unit,Notes\n" +
+		"2024-03-05,Bench Press,3,5,80,,\n" +
+		"2024-03-05,Bench Press,1,8,135,lb,\"felt\nheavy\"\n" +
+		"yesterday,Deadlift,1,5,100,,\n" +
+		"2024-03-06,,1,5,100,,\n" +
+		"2024-03-06,Deadlift,1,5.5,100,,\n"
+	sets, errs := parse(t, FormatCSV, data, "")
+	// The quoted note spans two lines, so later rows are numbered by line
+	sameErrors(t, errs, map[int]string{5: "date", 6: "exercise", 7: "reps"})
+	sameSets(t, sets, []Set{
+		{Row: 2, Date: day("2024-03-05"), Exercise: "Bench Press", Sets: 3, Reps: 5, WeightKg: 80},
+		{Row: 3, Date: day("2024-03-05"), Exercise: "Bench Press", Sets: 1, Reps: 8, WeightKg: lb(135)},
+	})
+}
+
+func TestParseGenericJSON(t *testing.T) {
+	data := "\xef\xbb\xbf[" +
+		`{"date": "2024-03-05", "exercise": "Bench Press", "sets": 3, "reps": "5", "weight": "80,5", "weightUnit": null},` +
+		`{"date": "2024-03-05T18:00:00Z", "exercise": "Plank", "seconds": 60},` +
+		`{"date": "2024-03-05", "exercise": "Deadlift", "reps": [5]},` +
+		`{"date": "2024-03-05", "exercise": "Deadlift", "reps": 5, "weight": 100, "weightUnit": "stone"}` +
+		"]"
+	sets, errs := parse(t, FormatJSON, data, "")
+	sameErrors(t, errs, map[int]string{3: "reps", 4: "weight"})
+	sameSets(t, sets, []Set{
+		{Row: 1, Date: day("2024-03-05"), Exercise: "Bench Press", Sets: 3, Reps: 5, WeightKg: 80.5},
+		{Row: 2, Date: day("2024-03-05"), Exercise: "Plank", Sets: 1, Seconds: 60},
+	})
+}
+
+func TestParseRejectsFile(t *testing.T) {
+	tests := []struct {
+		name, format, data, want string
+	}{
+		{"empty", FormatStrong, "", "the file is empty"},
+		{"empty with a BOM", FormatCSV, "\xef\xbb\xbf", "the file is empty"},
+		{"missing column", FormatHevy, "start_time,reps\n", `the file has no "exercise_title" column`},
+		{"not an array", FormatJSON, `{"date": "2024-03-05"}`, "the file is not a JSON array of objects"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := Parse(tt.format, []byte(tt.data), "")
+			if err == nil || err.Error() != tt.want {
+				t.Fatalf("Parse = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		name string
+		want string
+		ok   bool
+	}{
+		{"Bench Press (Barbell)", "Bench Press", true},
+		{"back squat", "Barbell Squat", true},
+		{"Goblet Squat (Kettlebell)", "Goblet Squat", true},
+		{"Zercher Carry (Barbell)", "", false},
+	}
+	for _, tt := range tests {
+		e, ok := Match(tt.name)
+		if ok != tt.ok || e.Name != tt.want {
+			t.Errorf("Match(%q) = %q, %v, want %q, %v", tt.name, e.Name, ok, tt.want, tt.ok)
+		}
+	}
+}
+
+func TestPlan(t *testing.T) {
+	entries := Plan([]Set{
+		{Row: 2, Date: day("2024-03-06"), Exercise: "Zercher Carry", Sets: 1, Reps: 1, Seconds: 30},
+		{Row: 3, Date: day("2024-03-05"), Exercise: "Bench Press (Barbell)", Sets: 1, Reps: 8, WeightKg: 70},
+		{Row: 4, Date: day("2024-03-05"), Exercise: "Bench Press", Sets: 2, Reps: 5, WeightKg: 80},
+		{Row: 5, Date: day("2024-03-05"), Exercise: "Bench Press", Sets: 1, Reps: 10, WeightKg: 60},
+		{Row: 6, Date: day("2024-03-06"), Exercise: "Zercher Carry", Sets: 1, Reps: 1, Seconds: 45},
+	})
+	want := []Entry{
+		{Date: day("2024-03-05"), Exercise: "Bench Press (Barbell)", Name: "Bench Press", Matched: true,
+			Sets: 4, Reps: 5, TopWeightKg: 80, Rows: []int{3, 4, 5}},
+		{Date: day("2024-03-06"), Exercise: "Zercher Carry", Name: "Zercher Carry",
+			Sets: 2, Reps: 1, Seconds: 45, Rows: []int{2, 6}},
+	}
+	if !reflect.DeepEqual(entries, want) {
+		t.Fatalf("Plan = %+v, want %+v", entries, want)
+	}
+	if got := Unmatched(entries); !reflect.DeepEqual(got, []string{"Zercher Carry"}) {
+		t.Fatalf("Unmatched = %v", got)
+	}
+}
+
+func TestCheck(t *testing.T) {
+	profile := &models.UserProfile{ID: 7, UnitSystem: units.Metric}
+	logged := day("2024-03-05")
+	existing := []models.WorkoutTask{{Name: "bench press", ScheduledFor: &logged}}
+
+	entries := Plan([]Set{
+		{Row: 2, Date: day("2024-03-05"), Exercise: "Bench Press (Barbell)", Sets: 3, Reps: 5, WeightKg: 80},
+		{Row: 3, Date: day("2024-03-06"), Exercise: "Bench Press", Sets: 3, Reps: 5, WeightKg: 80},
+		{Row: 4, Date: day("2024-03-06"), Exercise: "Deadlift", Sets: 1, Reps: 1001, WeightKg: 100},
+	})
+	kept, errs := check(profile, FormatStrong, entries, existing)
+
+	if len(kept) != 2 {
+		t.Fatalf("kept %d entries, want 2: %+v", len(kept), kept)
+	}
+	if !kept[0].Duplicate || kept[1].Duplicate {
+		t.Fatalf("duplicates are %v, %v, want true, false", kept[0].Duplicate, kept[1].Duplicate)
+	}
+	if len(errs) != 1 || errs[0].Row != 4 || !strings.HasPrefix(errs[0].Message, "Deadlift: ") {
+		t.Fatalf("got errors %+v, want one for the deadlift on row 4", errs)
+	}
+}
@@ -0,0 +1,55 @@
+// importer/strong.go
+package importer
+
+import (
+	"strings"
+
+	"back-end/models"
+	"back-end/units"
+)
+
+// Strong exports the date with or without seconds
+var strongDateLayouts = []string{"2006-01-02 15:04:05", "2006-01-02 15:04"}
+
+// parseStrong reads the CSV export of the Strong app, one row per set.
+// Warm-up sets, marked W in the Set Order column, are left out. Newer
+// exports name the weight unit in the header; older ones do not, and
+// their weights are in weightUnit.
+func parseStrong(data []byte, weightUnit string) ([]Set, []models.ImportRowError, error) {
+	t, err := readTable(data)
+	if err != nil {
+		return nil, nil, err
+	}
+	c := setColumns{sets: -1, unit: -1, weightUnit: weightUnit, dateLayouts: strongDateLayouts}
+	if c.date, err = t.require("date"); err != nil {
+		return nil, nil, err
+	}
+	if c.exercise, err = t.require("exercise name"); err != nil {
+		return nil, nil, err
+	}
+	if c.reps, err = t.require("reps"); err != nil {
+		return nil, nil, err
+	}
+	c.seconds = t.column("seconds")
+	switch {
+	case t.column("weight (kg)") >= 0:
+		c.weight, c.weightUnit = t.column("weight (kg)"), units.Kilogram
+	case t.column("weight (lbs)", "weight (lb)") >= 0:
+		c.weight, c.weightUnit = t.column("weight (lbs)", "weight (lb)"), units.Pound
+	default:
+		c.weight = t.column("weight")
+	}
+	order := t.column("set order")
+
+	var sets []Set
+	var errs rowErrors
+	for i, record := range t.records {
+		if strings.EqualFold(field(record, order), "W") {
+			continue
+		}
+		if s, ok := c.read(record, t.lines[i], &errs); ok {
+			sets = append(sets, s)
+		}
+	}
+	return sets, errs, nil
+}
@@ -0,0 +1,26 @@
+// jobs/erasure.go
+package jobs
+
+import (
+	"context"
+
+	"back-end/erasure"
+	"back-end/models"
+)
+
+// ResumeErasures retries the account erasures that stopped at a failed
+// step. Each erasure logs its own failure and the rest still run.
+func (r *Runner) ResumeErasures(ctx context.Context) error {
+	var erasures []models.AccountErasure
+	err := r.DB.ModelContext(ctx, &erasures).
+		Where("completed_at IS NULL").
+		Order("id ASC").
+		Select()
+	if err != nil {
+		return err
+	}
+	for i := range erasures {
+		erasure.Run(ctx, r.DB, r.AuthAdmin, r.Logger, &erasures[i])
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+// jobs/export.go
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"back-end/export"
+	"back-end/models"
+
+	"github.com/go-pg/pg/v10"
+	"go.uber.org/zap"
+)
+
+// ExportRetention is how long a built archive can be downloaded.
+const ExportRetention = 7 * 24 * time.Hour
+
+// BuildExports builds the archives of pending exports, one at a time and
+// oldest first. Other instances skip the export being built.
+func (r *Runner) BuildExports(ctx context.Context) error {
+	for {
+		built, err := r.buildNextExport(ctx)
+		if err != nil || !built {
+			return err
+		}
+	}
+}
+
+func (r *Runner) buildNextExport(ctx context.Context) (bool, error) {
+	built := false
+	err := r.DB.RunInTransaction(ctx, func(tx *pg.Tx) error {
+		var e models.DataExport
+		err := tx.Model(&e).
+			ExcludeColumn("archive").
+			Where("status = ?", models.ExportPending).
+			Order("id ASC").
+			Limit(1).
+			For("UPDATE SKIP LOCKED").
+			Select()
+		if err != nil {
+			if err == pg.ErrNoRows {
+				return nil
+			}
+			return err
+		}
+		built = true
+
+		// A failed query aborts the transaction; the savepoint keeps it
+		// usable for recording the failure
+		if _, err := tx.Exec("SAVEPOINT build_export"); err != nil {
+			return err
+		}
+		var profile models.UserProfile
+		err = tx.Model(&profile).Where("id = ?", e.UserID).Select()
+		if err == nil {
+			e.Archive, err = export.Build(tx, &profile)
+		}
+		if err != nil {
+			if _, rbErr := tx.Exec("ROLLBACK TO SAVEPOINT build_export"); rbErr != nil {
+				return rbErr
+			}
+		}
+		now := time.Now()
+		e.CompletedAt = &now
+		if err != nil {
+			// The export is reported as failed rather than retried forever
+			r.Logger.Error("Failed to build data export", zap.Int("export_id", e.ID), zap.Error(err))
+			e.Status = models.ExportFailed
+			e.Error = "The archive could not be built, request a new export"
+		} else {
+			expires := now.Add(ExportRetention)
+			e.Status = models.ExportReady
+			e.ExpiresAt = &expires
+		}
+		_, err = tx.Model(&e).WherePK().Update()
+		return err
+	})
+	return built, err
+}
+
+// PurgeExports deletes archives that can no longer be downloaded.
+func (r *Runner) PurgeExports(ctx context.Context) error {
+	res, err := r.DB.ModelContext(ctx, (*models.DataExport)(nil)).
+		Where("expires_at < ?", time.Now()).
+		Delete()
+	if err != nil {
+		return err
+	}
+	if n := res.RowsAffected(); n > 0 {
+		r.Logger.Info("Purged data exports", zap.Int("count", n))
+	}
+	return nil
+}
@@ -0,0 +1,26 @@
+// jobs/idempotency.go
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"back-end/models"
+
+	"go.uber.org/zap"
+)
+
+// PurgeIdempotencyKeys deletes stored responses whose replay window has
+// passed.
+func (r *Runner) PurgeIdempotencyKeys(ctx context.Context) error {
+	res, err := r.DB.ModelContext(ctx, (*models.IdempotencyKey)(nil)).
+		Where("expires_at < ?", time.Now()).
+		Delete()
+	if err != nil {
+		return err
+	}
+	if n := res.RowsAffected(); n > 0 {
+		r.Logger.Info("Purged idempotency keys", zap.Int("count", n))
+	}
+	return nil
+}
@@ -0,0 +1,146 @@
+// jobs/import.go
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"back-end/audit"
+	"back-end/importer"
+	"back-end/models"
+	"back-end/requestid"
+
+	"github.com/go-pg/pg/v10"
+	"go.uber.org/zap"
+)
+
+// importBatchSize is how many tasks an import creates per transaction.
+// Progress is saved after every batch, so an interrupted import resumes
+// after the last one.
+const importBatchSize = 100
+
+// RunImports works through unfinished imports, oldest first, a batch at a
+// time. Other instances skip the import whose batch is being created.
+func (r *Runner) RunImports(ctx context.Context) error {
+	for {
+		var next models.Import
+		err := r.DB.ModelContext(ctx, &next).
+			Column("id", "actor_type", "actor_id", "request_id").
+			Where("status IN (?, ?)", models.ImportPending, models.ImportRunning).
+			Order("id ASC").
+			Limit(1).
+			Select()
+		if err != nil {
+			if err == pg.ErrNoRows {
+				return nil
+			}
+			return err
+		}
+
+		// The tasks are recorded in their history as created by whoever
+		// asked for the import
+		importCtx := audit.NewContext(requestid.NewContext(ctx, next.RequestID), audit.Actor{Type: next.ActorType, ID: next.ActorID})
+		ran, err := r.runImportBatch(importCtx, next.ID)
+		if err != nil {
+			r.failImport(ctx, next.ID, err)
+			continue
+		}
+		if !ran {
+			return nil
+		}
+	}
+}
+
+// runImportBatch creates the next batch of tasks of an import. It reports
+// false when another instance holds the import.
+func (r *Runner) runImportBatch(ctx context.Context, id int) (bool, error) {
+	ran := false
+	err := r.DB.RunInTransaction(ctx, func(tx *pg.Tx) error {
+		imp := models.Import{ID: id}
+		err := tx.Model(&imp).
+			WherePK().
+			Where("status IN (?, ?)", models.ImportPending, models.ImportRunning).
+			For("UPDATE SKIP LOCKED").
+			Select()
+		if err != nil {
+			if err == pg.ErrNoRows {
+				return nil
+			}
+			return err
+		}
+		ran = true
+
+		var profile models.UserProfile
+		if err := tx.Model(&profile).Where("id = ?", imp.UserID).Select(); err != nil {
+			return err
+		}
+		// The file was parsed when the import was requested, so it does
+		// not fail as a whole here
+		sets, rowErrs, err := importer.Parse(imp.Format, imp.Source, imp.WeightUnit)
+		if err != nil {
+			return err
+		}
+		entries := importer.Plan(sets)
+		if imp.Status == models.ImportPending {
+			imp.Status = models.ImportRunning
+			imp.Total = len(entries)
+			imp.Errors = rowErrs
+		}
+
+		end := imp.Processed + importBatchSize
+		if end > len(entries) {
+			end = len(entries)
+		}
+		batch, checkErrs, err := importer.Check(tx, &profile, imp.Format, entries[imp.Processed:end])
+		if err != nil {
+			return err
+		}
+		imp.Errors = append(imp.Errors, checkErrs...)
+		for i := range batch {
+			created, err := importer.Create(tx, &profile, imp.Format, &batch[i])
+			if err != nil {
+				return err
+			}
+			if created {
+				imp.Created++
+			} else {
+				imp.Skipped++
+			}
+		}
+
+		imp.Processed = end
+		imp.UpdatedAt = time.Now()
+		if imp.Processed == imp.Total {
+			// The file is not kept once the tasks are created
+			imp.Status = models.ImportCompleted
+			imp.Source = nil
+			imp.CompletedAt = &imp.UpdatedAt
+			r.Logger.Info("Import completed",
+				zap.Int("import_id", imp.ID),
+				zap.Int("created", imp.Created),
+				zap.Int("skipped", imp.Skipped),
+				zap.Int("errors", len(imp.Errors)))
+		}
+		_, err = tx.Model(&imp).WherePK().Update()
+		return err
+	})
+	return ran, err
+}
+
+// failImport stops an import whose batch failed, rather than retrying it
+// forever. The tasks of earlier batches stay.
+func (r *Runner) failImport(ctx context.Context, id int, cause error) {
+	r.Logger.Error("Failed to run import", zap.Int("import_id", id), zap.Error(cause))
+	now := time.Now()
+	_, err := r.DB.ModelContext(ctx, (*models.Import)(nil)).
+		Set("status = ?", models.ImportFailed).
+		Set("failure = ?", "The import stopped, request a new import to add the remaining tasks").
+		Set("source = NULL").
+		Set("updated_at = ?", now).
+		Set("completed_at = ?", now).
+		Where("id = ?", id).
+		Update()
+	if err != nil {
+		r.Logger.Error("Failed to mark import as failed", zap.Int("import_id", id), zap.Error(err))
+	}
+}
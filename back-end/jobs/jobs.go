@@ -0,0 +1,55 @@
+// jobs/jobs.go
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"back-end/authadmin"
+
+	"github.com/go-pg/pg/v10"
+	"go.uber.org/zap"
+)
+
+// Runner runs the background jobs of the API process.
+type Runner struct {
+	DB        *pg.DB
+	Logger    *zap.Logger
+	AuthAdmin authadmin.Client
+}
+
+// Every calls fn once immediately and then on every tick of interval until
+// ctx is cancelled. Errors are logged and do not stop the schedule.
+func (r *Runner) Every(ctx context.Context, name string, interval time.Duration, fn func(context.Context) error) {
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			start := time.Now()
+			if err := fn(ctx); err != nil {
+				r.Logger.Error("Job failed", zap.String("job", name), zap.Error(err))
+			} else {
+				r.Logger.Info("Job completed", zap.String("job", name), zap.Duration("duration", time.Since(start)))
+			}
+
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
+}
+
+// Start schedules every background job.
+func (r *Runner) Start(ctx context.Context) {
+	r.Every(ctx, "materialize_occurrences", 24*time.Hour, r.MaterializeOccurrences)
+	r.Every(ctx, "reschedule_missed", time.Hour, r.RescheduleMissed)
+	r.Every(ctx, "purge_idempotency_keys", time.Hour, r.PurgeIdempotencyKeys)
+	r.Every(ctx, "purge_trash", time.Hour, r.PurgeTrash)
+	r.Every(ctx, "build_exports", time.Minute, r.BuildExports)
+	r.Every(ctx, "purge_exports", time.Hour, r.PurgeExports)
+	r.Every(ctx, "resume_erasures", 15*time.Minute, r.ResumeErasures)
+	r.Every(ctx, "run_imports", time.Minute, r.RunImports)
+}
@@ -0,0 +1,37 @@
+// jobs/materialize.go
+package jobs
+
+import (
+	"context"
+
+	"back-end/models"
+	"back-end/schedule"
+
+	"go.uber.org/zap"
+)
+
+// MaterializeOccurrences moves every user's materialized window forward
+// as days pass. Writes that change a schedule materialize it themselves,
+// so schedules, feeds and stats only read.
+func (r *Runner) MaterializeOccurrences(ctx context.Context) error {
+	var userIDs []int
+	err := r.DB.ModelContext(ctx, (*models.WorkoutTask)(nil)).
+		ColumnExpr("DISTINCT user_id").
+		Where("scheduled_for IS NOT NULL").
+		Where("source = ?", models.TaskSourcePlan).
+		Select(&userIDs)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range userIDs {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err := schedule.Refresh(r.DB, id); err != nil {
+			// One user's failure should not hold up everyone else
+			r.Logger.Error("Failed to materialize occurrences", zap.Int("profile_id", id), zap.Error(err))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+// jobs/reschedule.go
+package jobs
+
+import (
+	"context"
+
+	"back-end/models"
+	"back-end/schedule"
+
+	"go.uber.org/zap"
+)
+
+// RescheduleMissed applies each user's missed workout policy. It runs
+// hourly, so a day counts as missed shortly after it ends in the user's
+// timezone.
+func (r *Runner) RescheduleMissed(ctx context.Context) error {
+	var profiles []models.UserProfile
+	err := r.DB.Model(&profiles).
+		Where("id IN (SELECT DISTINCT user_id FROM workout_tasks WHERE scheduled_for IS NOT NULL)").
+		Select()
+	if err != nil {
+		return err
+	}
+
+	for i := range profiles {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		events, err := schedule.Reschedule(ctx, r.DB, &profiles[i], profiles[i].Today())
+		if err != nil {
+			// One user's failure should not hold up everyone else
+			r.Logger.Error("Failed to reschedule missed workouts",
+				zap.Int("profile_id", profiles[i].ID),
+				zap.Error(err),
+			)
+			continue
+		}
+		if len(events) > 0 {
+			r.Logger.Info("Rescheduled missed workouts",
+				zap.Int("profile_id", profiles[i].ID),
+				zap.String("policy", profiles[i].MissedWorkoutPolicy),
+				zap.Int("changes", len(events)),
+			)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,43 @@
+// jobs/trash.go
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"back-end/models"
+
+	"go.uber.org/zap"
+)
+
+// TrashRetention is how long deleted profiles and tasks can be restored.
+const TrashRetention = 30 * 24 * time.Hour
+
+// PurgeTrash permanently deletes profiles and tasks that have been in the
+// trash longer than TrashRetention. Deleting a profile removes the rest
+// of its data through the foreign keys.
+func (r *Runner) PurgeTrash(ctx context.Context) error {
+	cutoff := time.Now().Add(-TrashRetention)
+
+	profiles, err := r.DB.ModelContext(ctx, (*models.UserProfile)(nil)).
+		Deleted().
+		Where("deleted_at < ?", cutoff).
+		ForceDelete()
+	if err != nil {
+		return err
+	}
+	tasks, err := r.DB.ModelContext(ctx, (*models.WorkoutTask)(nil)).
+		Deleted().
+		Where("deleted_at < ?", cutoff).
+		ForceDelete()
+	if err != nil {
+		return err
+	}
+
+	if profiles.RowsAffected() > 0 || tasks.RowsAffected() > 0 {
+		r.Logger.Info("Purged trash",
+			zap.Int("profiles", profiles.RowsAffected()),
+			zap.Int("tasks", tasks.RowsAffected()))
+	}
+	return nil
+}
@@ -2,13 +2,21 @@
 package main
 
 import (
+	"context"
 	"crypto/tls"
 	"fmt"
 	"net/http"
 	"os"
+	"path/filepath"
+	"sort"
+	// Profiles name IANA timezones; bundle the database in case the host
+	// has none
+	_ "time/tzdata"
 
 	"back-end/app"
+	"back-end/authadmin"
 	"back-end/handlers"
+	"back-end/jobs"
 
 	"github.com/go-pg/pg/v10"
 	"github.com/gorilla/mux"
@@ -17,15 +25,25 @@ import (
 )
 
 type App struct {
-	Router *mux.Router
-	db     *pg.DB
-	logger *zap.Logger
+	Router    *mux.Router
+	db        *pg.DB
+	logger    *zap.Logger
+	authAdmin authadmin.Client
 }
 
 func initLogger() (*zap.Logger, error) {
 	return zap.NewProduction()
 }
 
+// initAuthAdmin returns the client that deletes auth users on account
+// erasure. AUTH_ADMIN=fake keeps local runs away from the real project.
+func initAuthAdmin() authadmin.Client {
+	if os.Getenv("AUTH_ADMIN") == "fake" {
+		return &authadmin.Fake{}
+	}
+	return authadmin.NewSupabase(os.Getenv("SUPABASE_ID"), os.Getenv("SUPABASE_SERVICE_ROLE_KEY"))
+}
+
 func initDB() (*pg.DB, error) {
 	host := os.Getenv("SUPABASE_HOST")
 	user := os.Getenv("SUPABASE_USER")
@@ -46,12 +64,13 @@ func initDB() (*pg.DB, error) {
 	return pg.Connect(opt), nil
 }
 
-func NewApp(db *pg.DB, logger *zap.Logger) *App {
+func NewApp(db *pg.DB, logger *zap.Logger, authAdmin authadmin.Client) *App {
 	router := mux.NewRouter()
 	app := &App{
-		Router: router,
-		db:     db,
-		logger: logger,
+		Router:    router,
+		db:        db,
+		logger:    logger,
+		authAdmin: authAdmin,
 	}
 	app.RegisterRoutes()
 	return app
@@ -64,6 +83,8 @@ func (a *App) RegisterRoutes() {
 		Logger:      a.logger,
 		SupabaseID: os.Getenv("SUPABASE_ID"),
 		SupabaseKey: os.Getenv("SUPABASE_KEY"),
+		AuthAdmin:   a.authAdmin,
+		ExportKey:   []byte(os.Getenv("EXPORT_SIGNING_KEY")),
 	}
 	app.RegisterRoutes(a.Router, h)
 }
@@ -94,7 +115,14 @@ func main() {
 	}
 
 	// Initialize app
-	app := NewApp(db, logger)
+	authAdmin := initAuthAdmin()
+	app := NewApp(db, logger, authAdmin)
+
+	// Start background jobs
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	runner := &jobs.Runner{DB: db, Logger: logger, AuthAdmin: authAdmin}
+	runner.Start(ctx)
 
 	// Start server
 	port := os.Getenv("PORT")
@@ -114,31 +142,28 @@ func main() {
 }
 
 func runMigrations(db *pg.DB, logger *zap.Logger) error {
-	// Read migration file
-	migrationSQL, err := os.ReadFile("db/migrations/001_create_tables.sql")
+	// Migration files are applied in lexical order and must be idempotent,
+	// since every file is executed on each start-up
+	files, err := filepath.Glob("db/migrations/*.sql")
 	if err != nil {
-		return fmt.Errorf("failed to read migration file: %w", err)
+		return fmt.Errorf("failed to list migration files: %w", err)
 	}
-
-	// Execute migration
-	_, err = db.Exec(string(migrationSQL))
-	if err != nil {
-		return fmt.Errorf("failed to execute migration: %w", err)
+	sort.Strings(files)
+
+	for _, file := range files {
+		// Read migration file
+		migrationSQL, err := os.ReadFile(file)
+		if err != nil {
+			return fmt.Errorf("failed to read migration file %s: %w", file, err)
+		}
+
+		// Execute migration
+		_, err = db.Exec(string(migrationSQL))
+		if err != nil {
+			return fmt.Errorf("failed to execute migration %s: %w", file, err)
+		}
 	}
 
-	logger.Info("Successfully ran database migrations")
+	logger.Info("Successfully ran database migrations", zap.Int("files", len(files)))
 	return nil
 }
-
-
-
-
-
-
-
-
-
-
-
-
-
@@ -7,6 +7,9 @@ import (
 	"os"
 	"strings"
 
+	"back-end/audit"
+	"back-end/problem"
+
 	"github.com/supabase-community/gotrue-go"
 	"go.uber.org/zap"
 )
@@ -16,38 +19,80 @@ var (
     supabaseKey = os.Getenv("SUPABASE_KEY")
 )
 
+// AuthMiddleware rejects requests without a valid bearer token. The
+// token's user is the actor of the request.
 func AuthMiddleware(logger *zap.Logger, next http.HandlerFunc) http.HandlerFunc {
 	return func(w http.ResponseWriter, r *http.Request) {
+		// OptionalAuthMiddleware may have verified the token already
+		if audit.FromContext(r.Context()).ID != "" {
+			next(w, r)
+			return
+		}
+
 		authHeader := r.Header.Get("Authorization")
 		if authHeader == "" {
 			logger.Error("Missing authorization token")
-			http.Error(w, "Unauthorized", http.StatusUnauthorized)
+			problem.Error(w, r, http.StatusUnauthorized, problem.CodeUnauthorized, "Unauthorized")
 			return
 		}
 
-		parts := strings.Split(authHeader, " ")
-		if len(parts) != 2 || parts[0] != "Bearer" {
-			logger.Error("Invalid authorization header format")
-			http.Error(w, "Invalid authorization header", http.StatusUnauthorized)
+		actor, ok := authenticate(logger, w, r, authHeader)
+		if !ok {
 			return
 		}
+		next(w, r.WithContext(audit.NewContext(r.Context(), actor)))
+	}
+}
 
-		token := parts[1]
-		
-		projectID := strings.TrimPrefix(supabaseUrl, "https://")
-		projectID = strings.TrimSuffix(projectID, ".supabase.co")
-		authURL := fmt.Sprintf("https://%s.supabase.co/auth/v1", projectID)
-		
-		client := gotrue.New(authURL, supabaseKey)
-		authedClient := client.WithToken(token)
-		_, err := authedClient.GetUser()
-		if err != nil {
-			logger.Error("Invalid token", zap.Error(err))
-			http.Error(w, "Invalid token", http.StatusUnauthorized)
-			return
-		}
+// OptionalAuthMiddleware verifies the bearer token of writes that send
+// one, so their changes are audited as the token's user. Writes without a
+// token go through as anonymous; an invalid token is still rejected.
+// Reads are not audited and skip the round trip to Supabase.
+func OptionalAuthMiddleware(logger *zap.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			authHeader := r.Header.Get("Authorization")
+			if authHeader == "" || r.Method == http.MethodGet || r.Method == http.MethodHead || r.Method == http.MethodOptions {
+				next.ServeHTTP(w, r)
+				return
+			}
+
+			actor, ok := authenticate(logger, w, r, authHeader)
+			if !ok {
+				return
+			}
+			next.ServeHTTP(w, r.WithContext(audit.NewContext(r.Context(), actor)))
+		})
+	}
+}
 
-		
-		next(w, r)
+// authenticate verifies a bearer token with Supabase and returns its user
+// as an actor, writing a 401 when the token is not valid.
+func authenticate(logger *zap.Logger, w http.ResponseWriter, r *http.Request, authHeader string) (audit.Actor, bool) {
+	parts := strings.Split(authHeader, " ")
+	if len(parts) != 2 || parts[0] != "Bearer" {
+		logger.Error("Invalid authorization header format")
+		problem.Error(w, r, http.StatusUnauthorized, problem.CodeUnauthorized, "Invalid authorization header")
+		return audit.Actor{}, false
 	}
+
+	token := parts[1]
+	
+	projectID := strings.TrimPrefix(supabaseUrl, "https://")
+	projectID = strings.TrimSuffix(projectID, ".supabase.co")
+	authURL := fmt.Sprintf("https://%s.supabase.co/auth/v1", projectID)
+	
+	client := gotrue.New(authURL, supabaseKey)
+	authedClient := client.WithToken(token)
+	user, err := authedClient.GetUser()
+	if err != nil {
+		logger.Error("Invalid token", zap.Error(err))
+		problem.Error(w, r, http.StatusUnauthorized, problem.CodeUnauthorized, "Invalid token")
+		return audit.Actor{}, false
+	}
+
+	// Changes made on this request are audited as the token's user,
+	// acting as a coach or admin when app_metadata says so
+	role, _ := user.AppMetadata["role"].(string)
+	return audit.RoleActor(user.ID.String(), role), true
 }
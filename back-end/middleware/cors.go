@@ -11,7 +11,12 @@ func CorsMiddleware(next http.Handler) http.Handler {
         w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS, PATCH")
         
         // Allow common headers
-        w.Header().Set("Access-Control-Allow-Headers", "Accept, Content-Type, Content-Length, Accept-Encoding, Authorization, X-CSRF-Token")
+        w.Header().Set("Access-Control-Allow-Headers", "Accept, Content-Type, Content-Length, Accept-Encoding, Authorization, X-CSRF-Token, X-Request-ID, Idempotency-Key, If-Match, If-None-Match")
+
+        // Let browsers read the version tag used for conditional requests
+        // and the request id quoted in error responses, tell replayed
+        // idempotent responses apart and follow list pages
+        w.Header().Set("Access-Control-Expose-Headers", "ETag, X-Request-ID, Idempotent-Replayed, Link")
         
         // Allow credentials
         w.Header().Set("Access-Control-Allow-Credentials", "true")
@@ -0,0 +1,230 @@
+// middleware/idempotency.go
+package middleware
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"back-end/audit"
+	"back-end/models"
+	"back-end/problem"
+
+	"github.com/go-pg/pg/v10"
+	"go.uber.org/zap"
+)
+
+const (
+	// IdempotencyKeyHeader names the client-chosen key of a request
+	IdempotencyKeyHeader = "Idempotency-Key"
+	// ReplayedHeader marks responses served from a stored earlier response
+	ReplayedHeader = "Idempotent-Replayed"
+
+	maxIdempotencyKeyLength = 255
+
+	// maxIdempotentBodySize bounds the bodies read for hashing. It matches
+	// the largest body the API accepts, an import file.
+	maxIdempotentBodySize = 10 << 20
+
+	// IdempotencyKeyTTL is how long a response stays available for replay.
+	IdempotencyKeyTTL = 24 * time.Hour
+
+	// idempotencyLockTimeout is how long a request may stay in flight
+	// before a retry may assume it died and run it again.
+	idempotencyLockTimeout = time.Minute
+)
+
+// Response headers stored with a response and sent again on replay
+var replayedHeaders = []string{"Content-Type", "ETag", "Location"}
+
+// IdempotencyMiddleware makes POST requests sent with an Idempotency-Key
+// safe to retry. The first request with a key runs and its response is
+// stored; repeats get that response back without running again. Reusing
+// a key for a different request is answered with 422, and a repeat that
+// arrives while the first is still running with 409. Keys belong to the
+// authenticated user, so requests with a key need a bearer token; it must
+// run after OptionalAuthMiddleware.
+//
+// Responses with a 5xx status are not stored, so the client can retry them.
+func IdempotencyMiddleware(db *pg.DB, logger *zap.Logger) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			key := r.Header.Get(IdempotencyKeyHeader)
+			if r.Method != http.MethodPost || key == "" {
+				next.ServeHTTP(w, r)
+				return
+			}
+			if len(key) > maxIdempotencyKeyLength {
+				problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidRequest, "Idempotency-Key must be at most 255 characters")
+				return
+			}
+
+			// Keys are kept per user, so anonymous callers cannot replay
+			// each other's responses
+			scope := audit.FromContext(r.Context()).ID
+			if scope == "" {
+				problem.Error(w, r, http.StatusUnauthorized, problem.CodeUnauthorized, "Idempotency-Key requires a bearer token")
+				return
+			}
+
+			body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxIdempotentBodySize))
+			if err != nil {
+				var tooLarge *http.MaxBytesError
+				if errors.As(err, &tooLarge) {
+					problem.Error(w, r, http.StatusRequestEntityTooLarge, problem.CodeInvalidRequest,
+						fmt.Sprintf("The request body must not be larger than %d MB", maxIdempotentBodySize>>20))
+					return
+				}
+				problem.Error(w, r, http.StatusBadRequest, problem.CodeInvalidRequest, "Invalid request body")
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+
+			record := &models.IdempotencyKey{
+				Scope:       scope,
+				Key:         key,
+				Method:      r.Method,
+				Path:        r.URL.RequestURI(),
+				RequestHash: requestHash(r, body),
+			}
+			claimed, existing, err := claimIdempotencyKey(db, record)
+			if err != nil {
+				logger.Error("Failed to claim idempotency key", zap.Error(err))
+				problem.Error(w, r, http.StatusInternalServerError, problem.CodeInternal, "Failed to process request")
+				return
+			}
+
+			if !claimed {
+				switch {
+				case existing.RequestHash != record.RequestHash:
+					problem.Error(w, r, http.StatusUnprocessableEntity, problem.CodeIdempotencyKeyReused, "Idempotency-Key was already used for a different request")
+				case !existing.Completed():
+					w.Header().Set("Retry-After", "1")
+					problem.Error(w, r, http.StatusConflict, problem.CodeRequestInProgress, "A request with this Idempotency-Key is still being processed")
+				default:
+					replay(w, existing)
+				}
+				return
+			}
+
+			rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+			next.ServeHTTP(rec, r)
+
+			if err := storeIdempotentResponse(db, record, rec); err != nil {
+				logger.Error("Failed to store idempotent response", zap.Error(err))
+			}
+		})
+	}
+}
+
+func requestHash(r *http.Request, body []byte) string {
+	h := sha256.New()
+	io.WriteString(h, r.Method+" "+r.URL.RequestURI()+"\n")
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// claimIdempotencyKey records that record's request is now running. It
+// fails if the key is already taken, unless the earlier entry expired or
+// its request stopped without finishing; the entry found is returned.
+func claimIdempotencyKey(db *pg.DB, record *models.IdempotencyKey) (bool, *models.IdempotencyKey, error) {
+	// A purge can delete the entry between the insert and the select, in
+	// which case the claim is tried again
+	for attempt := 0; attempt < 2; attempt++ {
+		// Postgres keeps microseconds; locked_at is compared when storing
+		now := time.Now().Truncate(time.Microsecond)
+		record.LockedAt = now
+		record.CreatedAt = now
+		record.ExpiresAt = now.Add(IdempotencyKeyTTL)
+
+		res, err := db.Model(record).
+			OnConflict("(scope, key) DO UPDATE").
+			Set("method = EXCLUDED.method, path = EXCLUDED.path, request_hash = EXCLUDED.request_hash").
+			Set("status_code = NULL, response_headers = NULL, response_body = NULL").
+			Set("locked_at = EXCLUDED.locked_at, created_at = EXCLUDED.created_at, expires_at = EXCLUDED.expires_at").
+			Where("idempotency_key.expires_at < ?", now).
+			WhereOr("idempotency_key.status_code IS NULL AND idempotency_key.locked_at < ?", now.Add(-idempotencyLockTimeout)).
+			Insert()
+		if err != nil {
+			return false, nil, err
+		}
+		if res.RowsAffected() > 0 {
+			return true, nil, nil
+		}
+
+		existing := &models.IdempotencyKey{Scope: record.Scope, Key: record.Key}
+		err = db.Model(existing).WherePK().Select()
+		if err == pg.ErrNoRows {
+			continue
+		}
+		if err != nil {
+			return false, nil, err
+		}
+		return false, existing, nil
+	}
+	return false, nil, pg.ErrNoRows
+}
+
+// storeIdempotentResponse saves the response for replay, or releases the
+// key when the request failed on our side.
+func storeIdempotentResponse(db *pg.DB, record *models.IdempotencyKey, rec *responseRecorder) error {
+	if rec.status >= http.StatusInternalServerError {
+		_, err := db.Model(record).WherePK().Where("locked_at = ?", record.LockedAt).Delete()
+		return err
+	}
+
+	record.StatusCode = rec.status
+	record.ResponseBody = rec.body.Bytes()
+	record.ResponseHeaders = make(map[string]string)
+	for _, name := range replayedHeaders {
+		if value := rec.Header().Get(name); value != "" {
+			record.ResponseHeaders[name] = value
+		}
+	}
+	_, err := db.Model(record).
+		Column("status_code", "response_headers", "response_body").
+		WherePK().
+		Where("locked_at = ?", record.LockedAt).
+		Update()
+	return err
+}
+
+func replay(w http.ResponseWriter, stored *models.IdempotencyKey) {
+	for name, value := range stored.ResponseHeaders {
+		w.Header().Set(name, value)
+	}
+	w.Header().Set(ReplayedHeader, "true")
+	w.WriteHeader(stored.StatusCode)
+	w.Write(stored.ResponseBody)
+}
+
+// responseRecorder passes a response through to the client while keeping
+// a copy of its status and body.
+type responseRecorder struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	body        bytes.Buffer
+}
+
+func (rec *responseRecorder) WriteHeader(status int) {
+	if rec.wroteHeader {
+		return
+	}
+	rec.status = status
+	rec.wroteHeader = true
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+func (rec *responseRecorder) Write(b []byte) (int, error) {
+	if !rec.wroteHeader {
+		rec.WriteHeader(http.StatusOK)
+	}
+	rec.body.Write(b)
+	return rec.ResponseWriter.Write(b)
+}
@@ -0,0 +1,21 @@
+// middleware/request_id.go
+package middleware
+
+import (
+	"net/http"
+
+	"back-end/requestid"
+)
+
+// RequestIDMiddleware gives every request an id, reusing the client's
+// X-Request-ID when it is usable, and echoes it on the response.
+func RequestIDMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		id := r.Header.Get(requestid.Header)
+		if !requestid.Accept(id) {
+			id = requestid.New()
+		}
+		w.Header().Set(requestid.Header, id)
+		next.ServeHTTP(w, r.WithContext(requestid.NewContext(r.Context(), id)))
+	})
+}
@@ -0,0 +1,23 @@
+// models/account_erasure.go
+package models
+
+import "time"
+
+// AccountErasure tracks the erasure of a user's account. Each step records
+// when it completed, so an erasure that fails part way resumes from the
+// step that failed.
+type AccountErasure struct {
+	ID                int        `json:"id" db:"id"`
+	AuthUserID        string     `json:"-" db:"auth_user_id"`
+	DataErasedAt      *time.Time `json:"dataErasedAt" db:"data_erased_at"`
+	AuthUserDeletedAt *time.Time `json:"authUserDeletedAt" db:"auth_user_deleted_at"`
+	Attempts          int        `json:"attempts" db:"attempts" pg:",use_zero"`
+	LastError         string     `json:"lastError,omitempty" db:"last_error" pg:",use_zero"`
+	CreatedAt         time.Time  `json:"createdAt" db:"created_at"`
+	CompletedAt       *time.Time `json:"completedAt" db:"completed_at"`
+}
+
+// Done reports whether every step has completed.
+func (e *AccountErasure) Done() bool {
+	return e.CompletedAt != nil
+}
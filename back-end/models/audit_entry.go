@@ -0,0 +1,53 @@
+// models/audit_entry.go
+package models
+
+import "time"
+
+// Actors that change user data
+const (
+	ActorUser      = "user"
+	ActorCoach     = "coach"
+	ActorAdmin     = "admin"
+	ActorAnonymous = "anonymous"
+)
+
+// Audited actions
+const (
+	AuditCreate  = "create"
+	AuditUpdate  = "update"
+	AuditDelete  = "delete"
+	AuditRestore = "restore"
+	AuditRevert  = "revert"
+	AuditReorder = "reorder"
+)
+
+// Audited entities
+const (
+	AuditEntityProfile = "profile"
+	AuditEntityTask    = "task"
+)
+
+// AuditChange is the old and new value of one changed field.
+type AuditChange struct {
+	From interface{} `json:"from"`
+	To   interface{} `json:"to"`
+}
+
+// AuditEntry records one write to a profile or task. Before and After are
+// the record as the API renders it, with profile measurements in metric
+// units; Before is empty for a create. Entries are never changed.
+type AuditEntry struct {
+	ID        int64                  `json:"id" db:"id"`
+	ActorType string                 `json:"actorType" db:"actor_type"`
+	ActorID   string                 `json:"actorId,omitempty" db:"actor_id"`
+	Action    string                 `json:"action" db:"action"`
+	Entity    string                 `json:"entity" db:"entity"`
+	EntityID  int                    `json:"entityId" db:"entity_id"`
+	UserID    int                    `json:"userId" db:"user_id"`
+	Version   int                    `json:"version" db:"version" pg:",use_zero"`
+	Before    map[string]interface{} `json:"before,omitempty" db:"before"`
+	After     map[string]interface{} `json:"after,omitempty" db:"after"`
+	Changes   map[string]AuditChange `json:"changes" db:"changes"`
+	RequestID string                 `json:"requestId,omitempty" db:"request_id"`
+	CreatedAt time.Time              `json:"createdAt" db:"created_at"`
+}
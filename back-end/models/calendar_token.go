@@ -0,0 +1,11 @@
+// models/calendar_token.go
+package models
+
+import "time"
+
+type CalendarToken struct {
+	ID        int       `json:"id" db:"id"`
+	UserID    int       `json:"userId" db:"user_id"`
+	TokenHash string    `json:"-" db:"token_hash"`
+	CreatedAt time.Time `json:"createdAt" db:"created_at"`
+}
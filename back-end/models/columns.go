@@ -0,0 +1,49 @@
+// models/columns.go
+package models
+
+import (
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/go-pg/pg/v10/orm"
+)
+
+// Models clients write to, used to name columns in error responses
+var writableModels = []interface{}{
+	UserProfile{},
+	WorkoutTask{},
+	WorkoutGroup{},
+	TaskOccurrence{},
+	PlanPause{},
+}
+
+var (
+	jsonFieldsOnce sync.Once
+	jsonFields     map[string]map[string]string
+)
+
+// JSONField returns the JSON name a model gives a column of table, so
+// database errors can point at the field the client sent. Unknown tables
+// and columns are returned unchanged.
+func JSONField(table, column string) string {
+	jsonFieldsOnce.Do(func() {
+		jsonFields = make(map[string]map[string]string)
+		for _, m := range writableModels {
+			t := orm.GetTable(reflect.TypeOf(m))
+			fields := make(map[string]string)
+			for _, f := range t.Fields {
+				name, _, _ := strings.Cut(f.Field.Tag.Get("json"), ",")
+				if name != "" && name != "-" {
+					fields[f.SQLName] = name
+				}
+			}
+			jsonFields[strings.Trim(string(t.SQLName), `"`)] = fields
+		}
+	})
+
+	if name, ok := jsonFields[table][column]; ok {
+		return name
+	}
+	return column
+}
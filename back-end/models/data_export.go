@@ -0,0 +1,26 @@
+// models/data_export.go
+package models
+
+import "time"
+
+// Export statuses
+const (
+	ExportPending = "pending"
+	ExportReady   = "ready"
+	ExportFailed  = "failed"
+)
+
+// DataExport is an archive of everything stored about a user, built in
+// the background. Archive is only loaded when the export is downloaded.
+type DataExport struct {
+	ID          int        `json:"id" db:"id"`
+	UserID      int        `json:"-" db:"user_id"`
+	Status      string     `json:"status" db:"status"`
+	Archive     []byte     `json:"-" db:"archive"`
+	Error       string     `json:"error,omitempty" db:"error"`
+	CreatedAt   time.Time  `json:"createdAt" db:"created_at"`
+	CompletedAt *time.Time `json:"completedAt,omitempty" db:"completed_at"`
+	ExpiresAt   *time.Time `json:"expiresAt,omitempty" db:"expires_at"`
+	// DownloadURL is a signed link to the archive once it is ready
+	DownloadURL string `json:"downloadUrl,omitempty" pg:"-"`
+}
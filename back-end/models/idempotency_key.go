@@ -0,0 +1,27 @@
+// models/idempotency_key.go
+package models
+
+import "time"
+
+// IdempotencyKey stores the response to a request sent with an
+// Idempotency-Key so retries can be answered without running it again.
+// Keys are scoped to the auth user id of the caller that sent them.
+type IdempotencyKey struct {
+	Scope       string `db:"scope" pg:",pk"`
+	Key         string `db:"key" pg:",pk"`
+	Method      string `db:"method"`
+	Path        string `db:"path"`
+	RequestHash string `db:"request_hash"`
+	// StatusCode is zero while the first request is in flight
+	StatusCode      int               `db:"status_code"`
+	ResponseHeaders map[string]string `db:"response_headers"`
+	ResponseBody    []byte            `db:"response_body"`
+	LockedAt        time.Time         `db:"locked_at"`
+	CreatedAt       time.Time         `db:"created_at"`
+	ExpiresAt       time.Time         `db:"expires_at"`
+}
+
+// Completed reports whether the stored response can be replayed.
+func (k *IdempotencyKey) Completed() bool {
+	return k.StatusCode != 0
+}
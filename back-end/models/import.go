@@ -0,0 +1,46 @@
+// models/import.go
+package models
+
+import "time"
+
+// Import statuses
+const (
+	ImportPending   = "pending"
+	ImportRunning   = "running"
+	ImportCompleted = "completed"
+	ImportFailed    = "failed"
+)
+
+// ImportRowError explains why a row of an imported file was not imported.
+// Row is the line of the file, or the position in a JSON array.
+type ImportRowError struct {
+	Row     int    `json:"row"`
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+// Import is a workout log file from another app, imported in the
+// background. Every exercise done on a day becomes one completed task;
+// Total counts those tasks and Processed how many have been handled.
+type Import struct {
+	ID         int              `json:"id" db:"id"`
+	UserID     int              `json:"userId" db:"user_id"`
+	Format     string           `json:"format" db:"format"`
+	WeightUnit string           `json:"weightUnit,omitempty" db:"weight_unit" pg:",use_zero"`
+	Status     string           `json:"status" db:"status"`
+	Source     []byte           `json:"-" db:"source"`
+	Total      int              `json:"total" db:"total" pg:",use_zero"`
+	Processed  int              `json:"processed" db:"processed" pg:",use_zero"`
+	Created    int              `json:"created" db:"created" pg:",use_zero"`
+	Skipped    int              `json:"skipped" db:"skipped" pg:",use_zero"`
+	Errors     []ImportRowError `json:"errors" db:"errors"`
+	Failure    string           `json:"failure,omitempty" db:"failure" pg:",use_zero"`
+	// The actor and request that asked for the import, for the history
+	// of the tasks it creates
+	ActorType   string     `json:"-" db:"actor_type"`
+	ActorID     string     `json:"-" db:"actor_id" pg:",use_zero"`
+	RequestID   string     `json:"-" db:"request_id" pg:",use_zero"`
+	CreatedAt   time.Time  `json:"createdAt" db:"created_at"`
+	UpdatedAt   time.Time  `json:"updatedAt" db:"updated_at"`
+	CompletedAt *time.Time `json:"completedAt,omitempty" db:"completed_at"`
+}
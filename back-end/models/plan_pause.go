@@ -0,0 +1,27 @@
+// models/plan_pause.go
+package models
+
+import "time"
+
+// PlanPause suspends a user's plan from StartDate through EndDate
+// inclusive. No occurrences are generated while paused and the paused days
+// neither break streaks nor count against adherence.
+type PlanPause struct {
+	ID        int       `json:"id" db:"id"`
+	UserID    int       `json:"userId" db:"user_id"`
+	StartDate Date      `json:"startDate" db:"start_date"`
+	EndDate   Date      `json:"endDate" db:"end_date"`
+	Reason    string    `json:"reason,omitempty" db:"reason" pg:",use_zero"`
+	CreatedAt time.Time `json:"createdAt" db:"created_at"`
+	UpdatedAt time.Time `json:"updatedAt" db:"updated_at"`
+}
+
+// Contains reports whether d falls within the pause.
+func (p *PlanPause) Contains(d Date) bool {
+	return !d.Before(p.StartDate) && !d.After(p.EndDate)
+}
+
+// Days returns the length of the pause in days.
+func (p *PlanPause) Days() int {
+	return int(p.EndDate.Sub(p.StartDate.Time).Hours()/24) + 1
+}
@@ -0,0 +1,24 @@
+// models/reschedule_event.go
+package models
+
+import "time"
+
+// Reschedule actions
+const (
+	RescheduleSkipped = "skipped"
+	RescheduleMoved   = "moved"
+)
+
+// RescheduleEvent records one occurrence the rescheduler skipped or moved.
+type RescheduleEvent struct {
+	ID           int       `json:"id" db:"id"`
+	UserID       int       `json:"userId" db:"user_id"`
+	OccurrenceID int       `json:"occurrenceId" db:"occurrence_id"`
+	TaskID       int       `json:"taskId" db:"task_id"`
+	Policy       string    `json:"policy" db:"policy"`
+	Action       string    `json:"action" db:"action"`
+	FromDate     Date      `json:"fromDate" db:"from_date"`
+	ToDate       *Date     `json:"toDate,omitempty" db:"to_date"`
+	Reason       string    `json:"reason,omitempty" db:"reason" pg:",use_zero"`
+	CreatedAt    time.Time `json:"createdAt" db:"created_at"`
+}
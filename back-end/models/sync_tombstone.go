@@ -0,0 +1,23 @@
+// models/sync_tombstone.go
+package models
+
+import "time"
+
+// Entities reported by the sync API
+const (
+	SyncEntityProfile    = "profile"
+	SyncEntityTask       = "task"
+	SyncEntityGroup      = "group"
+	SyncEntityOccurrence = "occurrence"
+)
+
+// SyncTombstone records a deleted row so offline clients can drop their
+// copy. Tombstones are written by database triggers.
+type SyncTombstone struct {
+	ID        int64     `json:"-" db:"id"`
+	UserID    int       `json:"-" db:"user_id"`
+	Entity    string    `json:"entity" db:"entity"`
+	EntityID  int       `json:"id" db:"entity_id"`
+	SyncSeq   int64     `json:"-" db:"sync_seq"`
+	DeletedAt time.Time `json:"deletedAt" db:"deleted_at"`
+}
@@ -0,0 +1,28 @@
+// models/task_occurrence.go
+package models
+
+import "time"
+
+// Occurrence statuses
+const (
+	OccurrenceScheduled = "scheduled"
+	OccurrenceCompleted = "completed"
+	OccurrenceSkipped   = "skipped"
+)
+
+// TaskOccurrence is one dated instance of a scheduled task. Non-recurring
+// tasks have a single occurrence; recurring tasks get one per date their
+// rule produces.
+type TaskOccurrence struct {
+	ID           int          `json:"id" db:"id"`
+	TaskID       int          `json:"taskId" db:"task_id"`
+	UserID       int          `json:"userId" db:"user_id"`
+	OccursOn     Date         `json:"occursOn" db:"occurs_on"`
+	ScheduledFor Date         `json:"scheduledFor" db:"scheduled_for"`
+	Status       string       `json:"status" db:"status"`
+	CompletedAt  *time.Time   `json:"completedAt,omitempty" db:"completed_at"`
+	Task         *WorkoutTask `json:"task,omitempty" pg:"rel:has-one"`
+	Version      int          `json:"version" db:"version"`
+	CreatedAt    time.Time    `json:"createdAt" db:"created_at"`
+	UpdatedAt    time.Time    `json:"updatedAt" db:"updated_at"`
+}
@@ -3,8 +3,13 @@ package models
 import (
 	"database/sql/driver"
 	"fmt"
+	"regexp"
 	"strings"
 	"time"
+
+	"back-end/catalog"
+	"back-end/units"
+	"back-end/validate"
 )
 
 type StringArray []string
@@ -63,6 +68,35 @@ func (a *StringArray) Scan(value interface{}) error {
 	}
 }
 
+// Missed workout policies applied by the rescheduler
+const (
+	MissedWorkoutSkip  = "skip"
+	MissedWorkoutPush  = "push"
+	MissedWorkoutMerge = "merge"
+)
+
+// Unit systems used to display weights and heights
+const (
+	UnitSystemMetric   = units.Metric
+	UnitSystemImperial = units.Imperial
+)
+
+// Defaults for profiles that have not chosen their own settings
+const (
+	DefaultTimezone  = "UTC"
+	DefaultWeekStart = "monday"
+	DefaultLocale    = "en-US"
+)
+
+var weekStartDays = map[string]time.Weekday{
+	"sunday":   time.Sunday,
+	"monday":   time.Monday,
+	"saturday": time.Saturday,
+}
+
+// localePattern accepts BCP 47 tags such as "en", "en-US" or "zh-Hant-TW".
+var localePattern = regexp.MustCompile(`^[A-Za-z]{2,3}(-[A-Za-z0-9]{2,8})*$`)
+
 type UserProfile struct {
 	ID                      int         `json:"id" db:"id"`
 	UserID                  string      `json:"user_id"`
@@ -75,8 +109,161 @@ type UserProfile struct {
 	AvailableEquipment    StringArray `json:"availableEquipment" db:"available_equipment"`
 	PreferredWorkoutDuration int      `json:"preferredWorkoutDuration" db:"preferred_workout_duration"`
 	WorkoutDaysPerWeek    int         `json:"workoutDaysPerWeek" db:"workout_days_per_week"`
+	MissedWorkoutPolicy   string      `json:"missedWorkoutPolicy" db:"missed_workout_policy"`
+	MergeCapMinutes       int         `json:"mergeCapMinutes" db:"merge_cap_minutes" pg:",use_zero"`
+	Timezone              string      `json:"timezone" db:"timezone"`
+	WeekStart             string      `json:"weekStart" db:"week_start"`
+	Locale                string      `json:"locale" db:"locale"`
+	UnitSystem            string      `json:"unitSystem" db:"unit_system"`
+	// WeightUnit and HeightUnit name the units Weight and Height are given
+	// in. They are not stored: values are kept in kilograms and centimetres.
+	WeightUnit            string      `json:"weightUnit,omitempty" pg:"-"`
+	HeightUnit            string      `json:"heightUnit,omitempty" pg:"-"`
+	Version               int         `json:"version" db:"version"`
 	CreatedAt             time.Time   `json:"createdAt" db:"created_at"`
 	UpdatedAt             time.Time   `json:"updatedAt" db:"updated_at"`
+	// DeletedAt is set while the profile is in the trash. Queries skip
+	// trashed profiles unless they ask for them.
+	DeletedAt             *time.Time  `json:"deletedAt,omitempty" db:"deleted_at" pg:",soft_delete"`
+}
+
+func IsValidMissedWorkoutPolicy(p string) bool {
+	return p == MissedWorkoutSkip || p == MissedWorkoutPush || p == MissedWorkoutMerge
+}
+
+// ApplySettingDefaults fills in scheduling and locale settings left empty
+// by the client.
+func (p *UserProfile) ApplySettingDefaults() {
+	if p.MissedWorkoutPolicy == "" {
+		p.MissedWorkoutPolicy = MissedWorkoutSkip
+	}
+	if p.Timezone == "" {
+		p.Timezone = DefaultTimezone
+	}
+	if p.WeekStart == "" {
+		p.WeekStart = DefaultWeekStart
+	}
+	if p.Locale == "" {
+		p.Locale = DefaultLocale
+	}
+	if p.UnitSystem == "" {
+		p.UnitSystem = UnitSystemMetric
+	}
+}
+
+// PreserveSettings keeps the existing value of any setting the client left
+// empty in a full update.
+func (p *UserProfile) PreserveSettings(existing *UserProfile) {
+	if p.MissedWorkoutPolicy == "" {
+		p.MissedWorkoutPolicy = existing.MissedWorkoutPolicy
+	}
+	if p.Timezone == "" {
+		p.Timezone = existing.Timezone
+	}
+	if p.WeekStart == "" {
+		p.WeekStart = existing.WeekStart
+	}
+	if p.Locale == "" {
+		p.Locale = existing.Locale
+	}
+	if p.UnitSystem == "" {
+		p.UnitSystem = existing.UnitSystem
+	}
 }
 
+// Fitness levels a profile can have
+var FitnessLevels = []string{"beginner", "intermediate", "advanced"}
+
+// Validate checks a profile whose measurements are already in kilograms
+// and centimetres.
+func (p *UserProfile) Validate() error {
+	var v validate.Validator
+	if p.DeletedAt != nil {
+		v.Add("deletedAt", validate.CodeNotAllowed, "deletedAt is set by deleting the profile")
+	}
+	if v.Required("user_id", p.UserID != "") {
+		v.MaxLength("user_id", p.UserID, 255)
+	}
+	v.Range("age", p.Age, 13, 120)
+	v.RangeFloat("weight", p.Weight, 20, 500)
+	v.RangeFloat("height", p.Height, 50, 300)
+	v.OneOf("fitnessLevel", p.FitnessLevel, FitnessLevels...)
+	v.Strings("fitnessGoals", p.FitnessGoals, 10, 100, nil)
+	v.Strings("healthConditions", p.HealthConditions, 20, 200, nil)
+	v.Strings("availableEquipment", p.AvailableEquipment, 30, 100, catalog.IsKnownEquipment)
+	v.Range("preferredWorkoutDuration", p.PreferredWorkoutDuration, 5, 300)
+	v.Range("workoutDaysPerWeek", p.WorkoutDaysPerWeek, 1, 7)
 
+	v.OneOf("missedWorkoutPolicy", p.MissedWorkoutPolicy, MissedWorkoutSkip, MissedWorkoutPush, MissedWorkoutMerge)
+	v.Range("mergeCapMinutes", p.MergeCapMinutes, 0, 600)
+	if _, err := time.LoadLocation(p.Timezone); err != nil || p.Timezone == "" || p.Timezone == "Local" {
+		v.Add("timezone", validate.CodeNotAllowed, "timezone must be an IANA name such as Europe/Berlin")
+	}
+	v.OneOf("weekStart", p.WeekStart, "sunday", "monday", "saturday")
+	if !localePattern.MatchString(p.Locale) {
+		v.Add("locale", validate.CodeInvalid, "locale must be a language tag such as en-US")
+	}
+	v.OneOf("unitSystem", p.UnitSystem, units.Metric, units.Imperial)
+	return v.Err()
+}
+
+// Location returns the profile's timezone, falling back to UTC if it is
+// unset or unknown.
+func (p *UserProfile) Location() *time.Location {
+	if p.Timezone == "" {
+		return time.UTC
+	}
+	loc, err := time.LoadLocation(p.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
+
+// Today returns the current calendar date in the profile's timezone.
+func (p *UserProfile) Today() Date {
+	return DateOf(time.Now().In(p.Location()))
+}
+
+// FirstDayOfWeek returns the day the profile's weeks start on.
+func (p *UserProfile) FirstDayOfWeek() time.Weekday {
+	if wd, ok := weekStartDays[p.WeekStart]; ok {
+		return wd
+	}
+	return time.Monday
+}
+
+// MergeCap returns the longest session, in minutes, that missed work may be
+// merged into. Zero means no cap.
+func (p *UserProfile) MergeCap() int {
+	if p.MergeCapMinutes > 0 {
+		return p.MergeCapMinutes
+	}
+	return p.PreferredWorkoutDuration
+}
+
+// NormalizeUnits converts Weight and Height from the units the client gave
+// into kilograms and centimetres. Values without a unit are taken to be
+// metric already.
+func (p *UserProfile) NormalizeUnits() error {
+	weight, err := units.Mass.ToCanonical(p.Weight, p.WeightUnit)
+	if err != nil {
+		return validate.Field("weightUnit", validate.CodeNotAllowed, "%s", err.Error())
+	}
+	height, err := units.Length.ToCanonical(p.Height, p.HeightUnit)
+	if err != nil {
+		return validate.Field("heightUnit", validate.CodeNotAllowed, "%s", err.Error())
+	}
+	p.Weight, p.Height = weight, height
+	p.WeightUnit, p.HeightUnit = "", ""
+	return nil
+}
+
+// RenderUnits converts stored Weight and Height into the given unit system
+// and records the units used.
+func (p *UserProfile) RenderUnits(system string) {
+	p.Weight = units.Mass.FromCanonical(p.Weight, system)
+	p.Height = units.Length.FromCanonical(p.Height, system)
+	p.WeightUnit = units.Mass.Unit(system)
+	p.HeightUnit = units.Length.Unit(system)
+}
@@ -0,0 +1,102 @@
+// models/workout_group.go
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"back-end/validate"
+)
+
+// Group types. Supersets and circuits are rep based; HIIT, EMOM and Tabata
+// blocks are driven by work and rest intervals.
+const (
+	GroupTypeSuperset = "superset"
+	GroupTypeCircuit  = "circuit"
+	GroupTypeHIIT     = "hiit"
+	GroupTypeEMOM     = "emom"
+	GroupTypeTabata   = "tabata"
+)
+
+var GroupTypes = []string{GroupTypeSuperset, GroupTypeCircuit, GroupTypeHIIT, GroupTypeEMOM, GroupTypeTabata}
+
+type WorkoutGroup struct {
+	ID               int           `json:"id" db:"id"`
+	UserID           int           `json:"userId" db:"user_id"`
+	Name             string        `json:"name" db:"name"`
+	Type             string        `json:"type" db:"type"`
+	Rounds           int           `json:"rounds" db:"rounds"`
+	WorkSeconds      int           `json:"workSeconds" db:"work_seconds" pg:",use_zero"`
+	RestSeconds      int           `json:"restSeconds" db:"rest_seconds" pg:",use_zero"`
+	RoundRestSeconds int           `json:"roundRestSeconds" db:"round_rest_seconds" pg:",use_zero"`
+	Position         int           `json:"position" db:"position" pg:",use_zero"`
+	CompletedRounds  int           `json:"completedRounds" db:"completed_rounds" pg:",use_zero"`
+	Tasks            []WorkoutTask `json:"tasks,omitempty" pg:"rel:has-many,join_fk:group_id"`
+	Version          int           `json:"version" db:"version"`
+	CreatedAt        time.Time     `json:"createdAt" db:"created_at"`
+	UpdatedAt        time.Time     `json:"updatedAt" db:"updated_at"`
+}
+
+func IsValidGroupType(t string) bool {
+	for _, gt := range GroupTypes {
+		if gt == t {
+			return true
+		}
+	}
+	return false
+}
+
+// IsInterval reports whether the group is timed by work/rest intervals
+// rather than by reps.
+func (g *WorkoutGroup) IsInterval() bool {
+	return g.Type == GroupTypeHIIT || g.Type == GroupTypeEMOM || g.Type == GroupTypeTabata
+}
+
+// ApplyDefaults fills in the conventional structure for a group type when
+// the client left it unspecified.
+func (g *WorkoutGroup) ApplyDefaults() {
+	switch g.Type {
+	case GroupTypeTabata:
+		if g.Rounds == 0 {
+			g.Rounds = 8
+		}
+		if g.WorkSeconds == 0 {
+			g.WorkSeconds = 20
+		}
+		if g.RestSeconds == 0 {
+			g.RestSeconds = 10
+		}
+	case GroupTypeEMOM:
+		if g.WorkSeconds == 0 {
+			g.WorkSeconds = 60
+		}
+	}
+	if g.Rounds == 0 {
+		g.Rounds = 1
+	}
+}
+
+// Done reports whether every round of the group has been logged.
+func (g *WorkoutGroup) Done() bool {
+	return g.CompletedRounds >= g.Rounds
+}
+
+// Validate checks the group's settings and any inline tasks, after
+// ApplyDefaults.
+func (g *WorkoutGroup) Validate() error {
+	var v validate.Validator
+	v.Required("userId", g.UserID > 0)
+	v.MaxLength("name", g.Name, 255)
+	v.OneOf("type", g.Type, GroupTypes...)
+	v.Range("rounds", g.Rounds, 1, 100)
+	v.Range("workSeconds", g.WorkSeconds, 0, 3600)
+	v.Range("restSeconds", g.RestSeconds, 0, 3600)
+	v.Range("roundRestSeconds", g.RoundRestSeconds, 0, 3600)
+	if g.IsInterval() && g.WorkSeconds == 0 {
+		v.Add("workSeconds", validate.CodeRequired, "workSeconds is required for %s groups", g.Type)
+	}
+	for i := range g.Tasks {
+		v.Nested(fmt.Sprintf("tasks[%d]", i), g.Tasks[i].Validate())
+	}
+	return v.Err()
+}
@@ -1,16 +1,82 @@
 // models/workout_task.go
 package models
 
-import "time"
+import (
+	"time"
+
+	"back-end/validate"
+)
+
+// Task categories. Warm-up and cool-down tasks are generated around a
+// session and are excluded from training analytics.
+const (
+	TaskCategoryExercise = "exercise"
+	TaskCategoryWarmup   = "warmup"
+	TaskCategoryCooldown = "cooldown"
+)
+
+// Task sources. Imported tasks are workout history logged in another app;
+// they are kept out of the plan and its order.
+const (
+	TaskSourcePlan   = "plan"
+	TaskSourceImport = "import"
+)
 
 type WorkoutTask struct {
-	ID          int       `json:"id" db:"id"`
-	UserID      int       `json:"userId" db:"user_id"`
-	Name        string    `json:"name" db:"name"`
-	Sets        int       `json:"sets" db:"sets"`
-	Reps        int       `json:"reps" db:"reps"`
-	Description string    `json:"description,omitempty" db:"description"`
-	Completed   bool      `json:"completed" db:"completed"`
-	CreatedAt   time.Time `json:"createdAt" db:"created_at"`
-	UpdatedAt   time.Time `json:"updatedAt" db:"updated_at"`
-}
\ No newline at end of file
+	ID            int       `json:"id" db:"id"`
+	UserID        int       `json:"userId" db:"user_id"`
+	Name          string    `json:"name" db:"name"`
+	Sets          int       `json:"sets" db:"sets"`
+	Reps          int       `json:"reps" db:"reps"`
+	Description   string    `json:"description,omitempty" db:"description"`
+	RestSeconds   int       `json:"restSeconds,omitempty" db:"rest_seconds" pg:",use_zero"`
+	Tempo         string    `json:"tempo,omitempty" db:"tempo" pg:",use_zero"`
+	Completed     bool      `json:"completed" db:"completed" pg:",use_zero"`
+	Category      string    `json:"category" db:"category"`
+	Source        string    `json:"source" db:"source"`
+	ScheduledFor  *Date     `json:"scheduledFor,omitempty" db:"scheduled_for"`
+	Recurrence    string    `json:"recurrence,omitempty" db:"recurrence" pg:",use_zero"`
+	Position      int       `json:"position" db:"position" pg:",use_zero"`
+	GroupID       *int      `json:"groupId,omitempty" db:"group_id"`
+	GroupPosition int       `json:"groupPosition" db:"group_position" pg:",use_zero"`
+	Version       int       `json:"version" db:"version"`
+	CreatedAt     time.Time `json:"createdAt" db:"created_at"`
+	UpdatedAt     time.Time `json:"updatedAt" db:"updated_at"`
+	// DeletedAt is set while the task is in the trash. Queries skip
+	// trashed tasks unless they ask for them.
+	DeletedAt *time.Time `json:"deletedAt,omitempty" db:"deleted_at" pg:",soft_delete"`
+}
+
+// IsTraining reports whether the task counts as training work rather than
+// generated warm-up or cool-down.
+func (t *WorkoutTask) IsTraining() bool {
+	return t.Category == "" || t.Category == TaskCategoryExercise
+}
+
+func IsValidTaskCategory(c string) bool {
+	return c == TaskCategoryExercise || c == TaskCategoryWarmup || c == TaskCategoryCooldown
+}
+
+// Validate checks the task's fields. Recurrence rules are checked by the
+// schedule package.
+func (t *WorkoutTask) Validate() error {
+	var v validate.Validator
+	v.Required("userId", t.UserID > 0)
+	if t.DeletedAt != nil {
+		v.Add("deletedAt", validate.CodeNotAllowed, "deletedAt is set by deleting the task")
+	}
+	if v.Required("name", t.Name != "") {
+		v.MaxLength("name", t.Name, 255)
+	}
+	v.Range("sets", t.Sets, 1, 100)
+	v.Range("reps", t.Reps, 1, 1000)
+	v.MaxLength("description", t.Description, 2000)
+	v.Range("restSeconds", t.RestSeconds, 0, 3600)
+	v.MaxLength("tempo", t.Tempo, 16)
+	v.OneOf("category", t.Category, TaskCategoryExercise, TaskCategoryWarmup, TaskCategoryCooldown)
+	v.MaxLength("recurrence", t.Recurrence, 500)
+	if t.GroupPosition < 0 {
+		v.Add("groupPosition", validate.CodeOutOfRange, "groupPosition must not be negative")
+	}
+	return v.Err()
+}
@@ -0,0 +1,289 @@
+// paging/paging.go
+package paging
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-pg/pg/v10"
+	"github.com/go-pg/pg/v10/orm"
+)
+
+const (
+	DefaultLimit = 20
+	MaxLimit     = 100
+)
+
+var errInvalidCursor = errors.New("Invalid cursor")
+
+// Type is the type of a keyset column, which the cursor values of the
+// column must parse as.
+type Type int
+
+const (
+	Text Type = iota
+	Int
+	// Time is a timestamp, formatted as RFC 3339 with fractional seconds
+	Time
+	// Date is a calendar date without a time
+	Date
+)
+
+// Column is one sort column of a keyset.
+type Column struct {
+	Name string
+	Type Type
+	Desc bool
+}
+
+// TextColumn, IntColumn, TimeColumn and DateColumn name a column of the
+// type, sorted in ascending order.
+func TextColumn(name string) Column { return Column{Name: name, Type: Text} }
+func IntColumn(name string) Column  { return Column{Name: name, Type: Int} }
+func TimeColumn(name string) Column { return Column{Name: name, Type: Time} }
+func DateColumn(name string) Column { return Column{Name: name, Type: Date} }
+
+// valid reports whether a cursor value parses as the column's type, so a
+// tampered cursor is rejected before Postgres fails to cast it.
+func (c Column) valid(value string) bool {
+	var err error
+	switch c.Type {
+	case Int:
+		_, err = strconv.ParseInt(value, 10, 64)
+	case Time:
+		_, err = time.Parse(time.RFC3339Nano, value)
+	case Date:
+		_, err = time.Parse(time.DateOnly, value)
+	}
+	return err == nil
+}
+
+// Keyset is the order of a list. The columns together must be unique,
+// typically by ending with the primary key, so every row has one place
+// in the order.
+type Keyset []Column
+
+// Asc returns a keyset sorting by columns in ascending order.
+func Asc(columns ...Column) Keyset {
+	keyset := make(Keyset, len(columns))
+	for i, c := range columns {
+		c.Desc = false
+		keyset[i] = c
+	}
+	return keyset
+}
+
+// Desc returns a keyset sorting by columns in descending order.
+func Desc(columns ...Column) Keyset {
+	keyset := Asc(columns...)
+	for i := range keyset {
+		keyset[i].Desc = true
+	}
+	return keyset
+}
+
+// valid reports whether cursor values fit the keyset, one value of the
+// right type per column.
+func (k Keyset) valid(values []string) bool {
+	if len(values) != len(k) {
+		return false
+	}
+	for i, c := range k {
+		if !c.valid(values[i]) {
+			return false
+		}
+	}
+	return true
+}
+
+// uniform reports whether every column sorts the same way, which lets
+// Apply use a single row comparison.
+func (k Keyset) uniform() bool {
+	for _, c := range k {
+		if c.Desc != k[0].Desc {
+			return false
+		}
+	}
+	return true
+}
+
+// Params are the paging options of a list request.
+type Params struct {
+	Keyset Keyset
+	Limit  int
+	// After holds the keyset values of the last row already returned
+	After []string
+	Count bool
+}
+
+// FromRequest reads ?limit=, ?cursor= and ?count=true for a list ordered
+// by keyset. Offsets are rejected rather than ignored so old clients do
+// not page forever.
+func FromRequest(r *http.Request, keyset Keyset) (Params, error) {
+	q := r.URL.Query()
+	p := Params{Keyset: keyset, Limit: DefaultLimit}
+
+	if q.Has("offset") {
+		return p, errors.New("offset is not supported, follow next_cursor instead")
+	}
+	if s := q.Get("limit"); s != "" {
+		limit, err := strconv.Atoi(s)
+		if err != nil || limit < 1 || limit > MaxLimit {
+			return p, fmt.Errorf("limit must be between 1 and %d", MaxLimit)
+		}
+		p.Limit = limit
+	}
+	if s := q.Get("cursor"); s != "" {
+		after, err := decodeCursor(s)
+		if err != nil || !keyset.valid(after) {
+			return p, errInvalidCursor
+		}
+		p.After = after
+	}
+	if s := q.Get("count"); s != "" {
+		count, err := strconv.ParseBool(s)
+		if err != nil {
+			return p, errors.New("count must be true or false")
+		}
+		p.Count = count
+	}
+	return p, nil
+}
+
+// Total counts the rows q matches when the client asked for it, and
+// returns nil otherwise. Call it before Apply.
+func (p Params) Total(q *orm.Query) (*int, error) {
+	if !p.Count {
+		return nil, nil
+	}
+	n, err := q.Clone().Count()
+	if err != nil {
+		return nil, err
+	}
+	return &n, nil
+}
+
+// Apply orders q by the keyset, starts it after the cursor and fetches one
+// row more than the page so Trim can tell whether another page follows.
+func (p Params) Apply(q *orm.Query) *orm.Query {
+	for _, c := range p.Keyset {
+		q = q.OrderExpr("? "+direction(c), pg.Ident(c.Name))
+	}
+	if p.After != nil {
+		// Postgres resolves the quoted cursor values to the column types
+		cond, params := p.after()
+		q = q.Where(cond, params...)
+	}
+	return q.Limit(p.Limit + 1)
+}
+
+// after builds the condition selecting rows past the cursor. Columns
+// sorted one way compare as a row, which an index on them can serve;
+// mixed directions expand to (a > x) OR (a = x AND b < y) and so on.
+func (p Params) after() (string, []interface{}) {
+	if p.Keyset.uniform() {
+		placeholders := make([]string, len(p.Keyset))
+		params := make([]interface{}, 0, len(p.Keyset)+1)
+		for i, c := range p.Keyset {
+			placeholders[i] = "?"
+			params = append(params, pg.Ident(c.Name))
+		}
+		params = append(params, pg.In(p.After))
+		return "(" + strings.Join(placeholders, ", ") + ") " + comparison(p.Keyset[0]) + " (?)", params
+	}
+
+	var terms []string
+	var params []interface{}
+	for i, c := range p.Keyset {
+		var conds []string
+		for j := 0; j < i; j++ {
+			conds = append(conds, "? = ?")
+			params = append(params, pg.Ident(p.Keyset[j].Name), p.After[j])
+		}
+		conds = append(conds, "? "+comparison(c)+" ?")
+		params = append(params, pg.Ident(c.Name), p.After[i])
+		terms = append(terms, "("+strings.Join(conds, " AND ")+")")
+	}
+	return "(" + strings.Join(terms, " OR ") + ")", params
+}
+
+func direction(c Column) string {
+	if c.Desc {
+		return "DESC"
+	}
+	return "ASC"
+}
+
+func comparison(c Column) string {
+	if c.Desc {
+		return "<"
+	}
+	return ">"
+}
+
+// Trim cuts the extra row fetched by Apply and returns the cursor of the
+// next page, or "" on the last page. key returns a row's keyset values.
+func Trim[T any](p Params, rows []T, key func(*T) []string) ([]T, string) {
+	if rows == nil {
+		rows = []T{}
+	}
+	if len(rows) <= p.Limit {
+		return rows, ""
+	}
+	rows = rows[:p.Limit]
+	return rows, encodeCursor(key(&rows[len(rows)-1]))
+}
+
+// Page is the envelope of a list response.
+type Page struct {
+	Data       interface{} `json:"data"`
+	NextCursor *string     `json:"next_cursor"`
+	Total      *int        `json:"total,omitempty"`
+}
+
+// NewPage wraps a page of rows.
+func NewPage(data interface{}, next string, total *int) Page {
+	page := Page{Data: data, Total: total}
+	if next != "" {
+		page.NextCursor = &next
+	}
+	return page
+}
+
+// Link returns an RFC 8288 Link header value pointing at the next page,
+// keeping the request's other query parameters.
+func Link(r *http.Request, next string) string {
+	u := *r.URL
+	q := u.Query()
+	q.Set("cursor", next)
+	u.RawQuery = q.Encode()
+	return fmt.Sprintf(`<%s>; rel="next"`, u.RequestURI())
+}
+
+// Cursors are opaque to clients; they are base64 so nobody is tempted to
+// build them by hand.
+func encodeCursor(values []string) string {
+	b, _ := json.Marshal(values)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func decodeCursor(s string) ([]string, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	var values []string
+	if err := json.Unmarshal(b, &values); err != nil {
+		return nil, err
+	}
+	if len(values) == 0 {
+		return nil, errInvalidCursor
+	}
+	return values, nil
+}
@@ -0,0 +1,223 @@
+// planner/estimate.go
+package planner
+
+import (
+	"sort"
+	"strconv"
+	"strings"
+
+	"back-end/models"
+)
+
+// Options holds the assumptions used when a task or group leaves a timing
+// unspecified. DefaultOptions matches a typical gym session.
+type Options struct {
+	RepSeconds            int `json:"repSeconds"`
+	SetRestSeconds        int `json:"setRestSeconds"`
+	TransitionSeconds     int `json:"transitionSeconds"`
+	GroupSwitchSeconds    int `json:"groupSwitchSeconds"`
+	GroupRoundRestSeconds int `json:"groupRoundRestSeconds"`
+	WarmupSeconds         int `json:"warmupSeconds"`
+}
+
+var DefaultOptions = Options{
+	RepSeconds:            3,
+	SetRestSeconds:        60,
+	TransitionSeconds:     60,
+	GroupSwitchSeconds:    15,
+	GroupRoundRestSeconds: 90,
+	WarmupSeconds:         300,
+}
+
+type Estimate struct {
+	WarmupSeconds     int `json:"warmupSeconds"`
+	WorkSeconds       int `json:"workSeconds"`
+	RestSeconds       int `json:"restSeconds"`
+	TransitionSeconds int `json:"transitionSeconds"`
+	CooldownSeconds   int `json:"cooldownSeconds"`
+	TotalSeconds      int `json:"totalSeconds"`
+	TotalMinutes      int `json:"totalMinutes"`
+}
+
+func (e *Estimate) add(o Estimate) {
+	e.WarmupSeconds += o.WarmupSeconds
+	e.WorkSeconds += o.WorkSeconds
+	e.RestSeconds += o.RestSeconds
+	e.TransitionSeconds += o.TransitionSeconds
+	e.CooldownSeconds += o.CooldownSeconds
+}
+
+func (e *Estimate) finish() {
+	e.TotalSeconds = e.WarmupSeconds + e.WorkSeconds + e.RestSeconds + e.TransitionSeconds + e.CooldownSeconds
+	e.TotalMinutes = (e.TotalSeconds + 59) / 60
+}
+
+// Block is one unit of a session: either a standalone task or a workout
+// group whose member tasks are performed together.
+type Block struct {
+	Group *models.WorkoutGroup `json:"group,omitempty"`
+	Tasks []models.WorkoutTask `json:"tasks"`
+}
+
+// Blocks arranges persisted tasks into session order. Tasks are taken in
+// position order and a group is placed where its first member appears.
+func Blocks(tasks []models.WorkoutTask, groups []models.WorkoutGroup) []Block {
+	sorted := make([]models.WorkoutTask, len(tasks))
+	copy(sorted, tasks)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return sorted[i].Position < sorted[j].Position
+	})
+
+	// Members are carried on the block, so the group copies drop theirs
+	byID := make(map[int]*models.WorkoutGroup, len(groups))
+	for i := range groups {
+		g := groups[i]
+		g.Tasks = nil
+		byID[g.ID] = &g
+	}
+
+	var blocks []Block
+	index := make(map[int]int)
+	for _, task := range sorted {
+		if task.GroupID == nil || byID[*task.GroupID] == nil {
+			blocks = append(blocks, Block{Tasks: []models.WorkoutTask{task}})
+			continue
+		}
+		if i, ok := index[*task.GroupID]; ok {
+			blocks[i].Tasks = append(blocks[i].Tasks, task)
+			continue
+		}
+		index[*task.GroupID] = len(blocks)
+		blocks = append(blocks, Block{Group: byID[*task.GroupID], Tasks: []models.WorkoutTask{task}})
+	}
+
+	for _, b := range blocks {
+		if b.Group != nil {
+			sort.SliceStable(b.Tasks, func(i, j int) bool {
+				return b.Tasks[i].GroupPosition < b.Tasks[j].GroupPosition
+			})
+		}
+	}
+	return blocks
+}
+
+// EstimateSession estimates how long the blocks take when performed in
+// order, including warm-up and the transitions between blocks. A session
+// with generated warm-up tasks is timed from those tasks instead of the
+// flat warm-up allowance.
+func EstimateSession(blocks []Block, opts Options) Estimate {
+	var est Estimate
+	if !hasCategory(blocks, models.TaskCategoryWarmup) {
+		est.WarmupSeconds = opts.WarmupSeconds
+	}
+	for i, b := range blocks {
+		if i > 0 {
+			est.TransitionSeconds += opts.TransitionSeconds
+		}
+		if b.Group != nil {
+			est.add(estimateGroup(b.Group, b.Tasks, opts))
+			continue
+		}
+		for _, task := range b.Tasks {
+			t := estimateTask(task, opts)
+			switch task.Category {
+			case models.TaskCategoryWarmup:
+				est.WarmupSeconds += t.WorkSeconds + t.RestSeconds
+			case models.TaskCategoryCooldown:
+				est.CooldownSeconds += t.WorkSeconds + t.RestSeconds
+			default:
+				est.add(t)
+			}
+		}
+	}
+	est.finish()
+	return est
+}
+
+func hasCategory(blocks []Block, category string) bool {
+	for _, b := range blocks {
+		for _, task := range b.Tasks {
+			if task.Category == category {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func estimateTask(task models.WorkoutTask, opts Options) Estimate {
+	var est Estimate
+	if task.Sets <= 0 {
+		return est
+	}
+	est.WorkSeconds = task.Sets * task.Reps * repSeconds(task.Tempo, opts)
+	est.RestSeconds = (task.Sets - 1) * restSeconds(task, opts)
+	return est
+}
+
+func estimateGroup(group *models.WorkoutGroup, tasks []models.WorkoutTask, opts Options) Estimate {
+	var est Estimate
+	rounds := group.Rounds
+	if rounds < 1 {
+		rounds = 1
+	}
+
+	roundRest := group.RoundRestSeconds
+	if roundRest == 0 && !group.IsInterval() {
+		roundRest = opts.GroupRoundRestSeconds
+	}
+
+	if group.IsInterval() {
+		// Each member is worked for one interval per round
+		est.WorkSeconds = rounds * len(tasks) * group.WorkSeconds
+		est.RestSeconds = rounds * len(tasks) * group.RestSeconds
+	} else {
+		// Members run back to back within a round with only a short switch
+		for _, task := range tasks {
+			sets := task.Sets
+			if sets < 1 {
+				sets = 1
+			}
+			est.WorkSeconds += rounds * sets * task.Reps * repSeconds(task.Tempo, opts)
+		}
+		if len(tasks) > 1 {
+			est.TransitionSeconds = rounds * (len(tasks) - 1) * opts.GroupSwitchSeconds
+		}
+	}
+	est.RestSeconds += (rounds - 1) * roundRest
+	return est
+}
+
+func restSeconds(task models.WorkoutTask, opts Options) int {
+	if task.RestSeconds > 0 {
+		return task.RestSeconds
+	}
+	return opts.SetRestSeconds
+}
+
+// repSeconds returns the time under tension of one rep. Tempo is written
+// as eccentric-pause-concentric-pause, e.g. "3-1-1-0" or "31X0", where X
+// means explosive and counts as one second.
+func repSeconds(tempo string, opts Options) int {
+	tempo = strings.ReplaceAll(strings.TrimSpace(tempo), "-", "")
+	if len(tempo) != 4 {
+		return opts.RepSeconds
+	}
+
+	total := 0
+	for _, c := range strings.ToUpper(tempo) {
+		if c == 'X' {
+			total++
+			continue
+		}
+		n, err := strconv.Atoi(string(c))
+		if err != nil {
+			return opts.RepSeconds
+		}
+		total += n
+	}
+	if total == 0 {
+		return opts.RepSeconds
+	}
+	return total
+}
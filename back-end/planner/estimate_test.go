@@ -0,0 +1,86 @@
+package planner
+
+import (
+	"testing"
+
+	"back-end/models"
+)
+
+func task(name string, sets, reps int) models.WorkoutTask {
+	return models.WorkoutTask{Name: name, Sets: sets, Reps: reps, Category: models.TaskCategoryExercise}
+}
+
+func single(tasks ...models.WorkoutTask) []Block {
+	blocks := make([]Block, len(tasks))
+	for i, t := range tasks {
+		blocks[i] = Block{Tasks: []models.WorkoutTask{t}}
+	}
+	return blocks
+}
+
+func TestEstimateSession(t *testing.T) {
+	withTiming := task("Bench Press", 3, 10)
+	withTiming.Tempo = "31X0"
+	withTiming.RestSeconds = 90
+	badTempo := task("Bench Press", 3, 10)
+	badTempo.Tempo = "3-1-1"
+	warmup := task("Jumping Jacks", 1, 10)
+	warmup.Category = models.TaskCategoryWarmup
+	cooldown := task("Stretch", 2, 10)
+	cooldown.Category = models.TaskCategoryCooldown
+
+	tests := []struct {
+		name   string
+		blocks []Block
+		want   Estimate
+	}{
+		{
+			"default timings",
+			single(task("Back Squat", 5, 5)),
+			Estimate{WarmupSeconds: 300, WorkSeconds: 75, RestSeconds: 240, TotalSeconds: 615, TotalMinutes: 11},
+		},
+		{
+			"tempo and rest",
+			single(withTiming),
+			Estimate{WarmupSeconds: 300, WorkSeconds: 150, RestSeconds: 180, TotalSeconds: 630, TotalMinutes: 11},
+		},
+		{
+			"unreadable tempo",
+			single(badTempo),
+			Estimate{WarmupSeconds: 300, WorkSeconds: 90, RestSeconds: 120, TotalSeconds: 510, TotalMinutes: 9},
+		},
+		{
+			"transitions between blocks",
+			single(task("Row", 1, 10), task("Curl", 1, 10)),
+			Estimate{WarmupSeconds: 300, WorkSeconds: 60, TransitionSeconds: 60, TotalSeconds: 420, TotalMinutes: 7},
+		},
+		{
+			"warm-up tasks replace the allowance",
+			single(warmup, task("Row", 1, 10), cooldown),
+			Estimate{WarmupSeconds: 30, WorkSeconds: 30, TransitionSeconds: 120, CooldownSeconds: 120, TotalSeconds: 300, TotalMinutes: 5},
+		},
+		{
+			"superset",
+			[]Block{{
+				Group: &models.WorkoutGroup{Type: models.GroupTypeSuperset, Rounds: 3},
+				Tasks: []models.WorkoutTask{task("Row", 1, 10), task("Curl", 1, 10)},
+			}},
+			Estimate{WarmupSeconds: 300, WorkSeconds: 180, RestSeconds: 180, TransitionSeconds: 45, TotalSeconds: 705, TotalMinutes: 12},
+		},
+		{
+			"interval group",
+			[]Block{{
+				Group: &models.WorkoutGroup{Type: models.GroupTypeTabata, Rounds: 8, WorkSeconds: 20, RestSeconds: 10},
+				Tasks: []models.WorkoutTask{task("Burpee", 1, 0)},
+			}},
+			Estimate{WarmupSeconds: 300, WorkSeconds: 160, RestSeconds: 80, TotalSeconds: 540, TotalMinutes: 9},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := EstimateSession(tt.blocks, DefaultOptions); got != tt.want {
+				t.Fatalf("EstimateSession = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
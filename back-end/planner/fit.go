@@ -0,0 +1,177 @@
+// planner/fit.go
+package planner
+
+import (
+	"fmt"
+
+	"back-end/models"
+)
+
+// Adjustment actions applied by Fit, in the order they are tried.
+const (
+	ActionSuperset   = "superset"
+	ActionReduceSets = "reduce_sets"
+	ActionDrop       = "drop"
+)
+
+// minAccessorySets is the floor Fit reduces accessory volume to before it
+// starts dropping exercises.
+const minAccessorySets = 2
+
+type Adjustment struct {
+	Action string   `json:"action"`
+	Tasks  []string `json:"tasks"`
+	Detail string   `json:"detail,omitempty"`
+}
+
+type FitResult struct {
+	Blocks      []Block      `json:"blocks"`
+	Estimate    Estimate     `json:"estimate"`
+	Adjustments []Adjustment `json:"adjustments"`
+	Fits        bool         `json:"fits"`
+}
+
+// Fit shapes a workout so its estimate fits within budgetMinutes. The first
+// exercise block is treated as the main lift and is never changed, nor are
+// warm-up and cool-down blocks; the remaining accessory work is first
+// paired into supersets, then reduced in sets and finally dropped from the
+// end until the session fits. A budget of zero or less leaves the workout
+// untouched.
+func Fit(blocks []Block, budgetMinutes int, opts Options) FitResult {
+	res := FitResult{Blocks: cloneBlocks(blocks), Adjustments: []Adjustment{}}
+	budget := budgetMinutes * 60
+
+	fits := func() bool {
+		res.Estimate = EstimateSession(res.Blocks, opts)
+		return budget <= 0 || res.Estimate.TotalSeconds <= budget
+	}
+	accessory := func(i int) bool {
+		if !isTraining(res.Blocks[i]) {
+			return false
+		}
+		for j := 0; j < i; j++ {
+			if isTraining(res.Blocks[j]) {
+				return true
+			}
+		}
+		return false
+	}
+
+	// Pair standalone accessory exercises into supersets, latest first
+	for i := len(res.Blocks) - 2; i >= 0 && !fits(); i-- {
+		a, b := res.Blocks[i], res.Blocks[i+1]
+		if !accessory(i) || !accessory(i+1) ||
+			a.Group != nil || b.Group != nil || len(a.Tasks) != 1 || len(b.Tasks) != 1 {
+			continue
+		}
+		res.Blocks[i] = superset(a.Tasks[0], b.Tasks[0])
+		res.Blocks = append(res.Blocks[:i+1], res.Blocks[i+2:]...)
+		res.Adjustments = append(res.Adjustments, Adjustment{
+			Action: ActionSuperset,
+			Tasks:  []string{a.Tasks[0].Name, b.Tasks[0].Name},
+		})
+		i--
+	}
+
+	// Take one set at a time off accessory blocks, latest first
+	for changed := true; changed && !fits(); {
+		changed = false
+		for i := len(res.Blocks) - 1; i >= 0 && !fits(); i-- {
+			if accessory(i) && reduceSets(&res.Blocks[i]) {
+				changed = true
+				res.Adjustments = append(res.Adjustments, Adjustment{
+					Action: ActionReduceSets,
+					Tasks:  blockNames(res.Blocks[i]),
+					Detail: fmt.Sprintf("reduced to %d sets", blockSets(res.Blocks[i])),
+				})
+			}
+		}
+	}
+
+	// Drop accessory blocks from the end
+	for i := len(res.Blocks) - 1; i >= 0 && !fits(); i-- {
+		if !accessory(i) {
+			continue
+		}
+		dropped := res.Blocks[i]
+		res.Blocks = append(res.Blocks[:i], res.Blocks[i+1:]...)
+		res.Adjustments = append(res.Adjustments, Adjustment{
+			Action: ActionDrop,
+			Tasks:  blockNames(dropped),
+		})
+	}
+
+	res.Fits = fits()
+	return res
+}
+
+func isTraining(b Block) bool {
+	for _, task := range b.Tasks {
+		if !task.IsTraining() {
+			return false
+		}
+	}
+	return true
+}
+
+func superset(a, b models.WorkoutTask) Block {
+	rounds := a.Sets
+	if b.Sets > rounds {
+		rounds = b.Sets
+	}
+	group := &models.WorkoutGroup{
+		UserID: a.UserID,
+		Name:   a.Name + " + " + b.Name,
+		Type:   models.GroupTypeSuperset,
+		Rounds: rounds,
+	}
+	group.ApplyDefaults()
+
+	// Sets become rounds of the superset
+	a.Sets, b.Sets = 1, 1
+	a.GroupPosition, b.GroupPosition = 0, 1
+	return Block{Group: group, Tasks: []models.WorkoutTask{a, b}}
+}
+
+func reduceSets(b *Block) bool {
+	if b.Group != nil {
+		if b.Group.IsInterval() || b.Group.Rounds <= minAccessorySets {
+			return false
+		}
+		b.Group.Rounds--
+		return true
+	}
+	if len(b.Tasks) != 1 || b.Tasks[0].Sets <= minAccessorySets {
+		return false
+	}
+	b.Tasks[0].Sets--
+	return true
+}
+
+func blockSets(b Block) int {
+	if b.Group != nil {
+		return b.Group.Rounds
+	}
+	return b.Tasks[0].Sets
+}
+
+func blockNames(b Block) []string {
+	names := make([]string, len(b.Tasks))
+	for i, t := range b.Tasks {
+		names[i] = t.Name
+	}
+	return names
+}
+
+func cloneBlocks(blocks []Block) []Block {
+	out := make([]Block, len(blocks))
+	for i, b := range blocks {
+		out[i].Tasks = append([]models.WorkoutTask(nil), b.Tasks...)
+		if b.Group != nil {
+			g := *b.Group
+			g.Tasks = nil
+			out[i].Group = &g
+		}
+	}
+	return out
+}
@@ -0,0 +1,130 @@
+package planner
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+
+	"back-end/models"
+)
+
+// session is a 27 minute workout: a warm-up, the main lift, three
+// accessories and a cool-down.
+func session() []Block {
+	warmup := task("Jumping Jacks", 1, 10)
+	warmup.Category = models.TaskCategoryWarmup
+	cooldown := task("Stretch", 1, 20)
+	cooldown.Category = models.TaskCategoryCooldown
+	return single(warmup, task("Back Squat", 5, 5), task("Row", 4, 10), task("Curl", 4, 10), task("Dip", 4, 10), cooldown)
+}
+
+func adjustments(res FitResult) []string {
+	out := []string{}
+	for _, a := range res.Adjustments {
+		s := a.Action + " " + strings.Join(a.Tasks, ", ")
+		if a.Detail != "" {
+			s += ": " + a.Detail
+		}
+		out = append(out, s)
+	}
+	return out
+}
+
+func blockList(blocks []Block) []string {
+	out := []string{}
+	for _, b := range blocks {
+		out = append(out, fmt.Sprintf("%s x%d", strings.Join(blockNames(b), " + "), blockSets(b)))
+	}
+	return out
+}
+
+func TestFit(t *testing.T) {
+	tests := []struct {
+		name        string
+		budget      int
+		fits        bool
+		adjustments []string
+		blocks      []string
+	}{
+		{
+			name: "fits", budget: 27, fits: true,
+			adjustments: []string{},
+			blocks:      []string{"Jumping Jacks x1", "Back Squat x5", "Row x4", "Curl x4", "Dip x4", "Stretch x1"},
+		},
+		{
+			name: "no budget", budget: 0, fits: true,
+			adjustments: []string{},
+			blocks:      []string{"Jumping Jacks x1", "Back Squat x5", "Row x4", "Curl x4", "Dip x4", "Stretch x1"},
+		},
+		{
+			name: "superset", budget: 26, fits: true,
+			adjustments: []string{"superset Curl, Dip"},
+			blocks:      []string{"Jumping Jacks x1", "Back Squat x5", "Row x4", "Curl + Dip x4", "Stretch x1"},
+		},
+		{
+			name: "fewer sets", budget: 25, fits: true,
+			adjustments: []string{"superset Curl, Dip", "reduce_sets Curl, Dip: reduced to 3 sets"},
+			blocks:      []string{"Jumping Jacks x1", "Back Squat x5", "Row x4", "Curl + Dip x3", "Stretch x1"},
+		},
+		{
+			name: "drop accessories", budget: 16, fits: true,
+			adjustments: []string{
+				"superset Curl, Dip",
+				"reduce_sets Curl, Dip: reduced to 3 sets",
+				"reduce_sets Row: reduced to 3 sets",
+				"reduce_sets Curl, Dip: reduced to 2 sets",
+				"reduce_sets Row: reduced to 2 sets",
+				"drop Curl, Dip",
+			},
+			blocks: []string{"Jumping Jacks x1", "Back Squat x5", "Row x2", "Stretch x1"},
+		},
+		{
+			name: "cannot fit", budget: 5, fits: false,
+			adjustments: []string{
+				"superset Curl, Dip",
+				"reduce_sets Curl, Dip: reduced to 3 sets",
+				"reduce_sets Row: reduced to 3 sets",
+				"reduce_sets Curl, Dip: reduced to 2 sets",
+				"reduce_sets Row: reduced to 2 sets",
+				"drop Curl, Dip",
+				"drop Row",
+			},
+			blocks: []string{"Jumping Jacks x1", "Back Squat x5", "Stretch x1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			blocks := session()
+			res := Fit(blocks, tt.budget, DefaultOptions)
+
+			if !reflect.DeepEqual(blocks, session()) {
+				t.Fatal("Fit changed the blocks it was given")
+			}
+			if res.Fits != tt.fits {
+				t.Errorf("Fits = %v, want %v with an estimate of %d s", res.Fits, tt.fits, res.Estimate.TotalSeconds)
+			}
+			if got := adjustments(res); !reflect.DeepEqual(got, tt.adjustments) {
+				t.Errorf("adjustments = %q, want %q", got, tt.adjustments)
+			}
+			if got := blockList(res.Blocks); !reflect.DeepEqual(got, tt.blocks) {
+				t.Errorf("blocks = %q, want %q", got, tt.blocks)
+			}
+			if res.Estimate != EstimateSession(res.Blocks, DefaultOptions) {
+				t.Errorf("estimate %+v is not the fitted session's", res.Estimate)
+			}
+
+			// The warm-up, main lift and cool-down come through unchanged
+			original, fitted := session(), res.Blocks
+			for _, pair := range [][2]Block{
+				{original[0], fitted[0]},
+				{original[1], fitted[1]},
+				{original[len(original)-1], fitted[len(fitted)-1]},
+			} {
+				if !reflect.DeepEqual(pair[0], pair[1]) {
+					t.Errorf("block %v became %v", blockNames(pair[0]), blockNames(pair[1]))
+				}
+			}
+		})
+	}
+}
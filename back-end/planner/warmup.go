@@ -0,0 +1,141 @@
+// planner/warmup.go
+package planner
+
+import (
+	"fmt"
+
+	"back-end/catalog"
+	"back-end/models"
+)
+
+// Limits on how many muscle groups get their own drill, so generated
+// warm-ups and cool-downs stay short.
+const (
+	maxWarmupDrills    = 3
+	maxCooldownStretch = 4
+)
+
+// loadedEquipment marks lifts that are worth ramping up to.
+var loadedEquipment = map[string]bool{"barbell": true, "dumbbells": true, "kettlebell": true}
+
+// MuscleGroups returns the muscle groups trained by the session's exercise
+// tasks, in order of first appearance. Tasks missing from the catalog are
+// ignored.
+func MuscleGroups(blocks []Block) []string {
+	seen := make(map[string]bool)
+	var groups []string
+	for _, b := range blocks {
+		for _, task := range b.Tasks {
+			if !task.IsTraining() {
+				continue
+			}
+			ex, ok := catalog.Lookup(task.Name)
+			if !ok {
+				continue
+			}
+			for _, m := range ex.MuscleGroups {
+				if !seen[m] {
+					seen[m] = true
+					groups = append(groups, m)
+				}
+			}
+		}
+	}
+	return groups
+}
+
+// GenerateWarmup builds a general warm-up, mobility drills for the
+// session's muscle groups and ramp-up sets for the first heavy lift.
+func GenerateWarmup(blocks []Block) []models.WorkoutTask {
+	tasks := []models.WorkoutTask{{
+		Name:        "Jumping Jacks",
+		Sets:        2,
+		Reps:        30,
+		RestSeconds: 15,
+		Tempo:       "1-0-1-0",
+		Description: "General warm-up: easy pace to raise heart rate and body temperature",
+	}}
+
+	for i, m := range MuscleGroups(blocks) {
+		if i == maxWarmupDrills {
+			break
+		}
+		drills := catalog.ByKindAndMuscle(catalog.KindMobility, m)
+		if len(drills) == 0 || containsTask(tasks, drills[0].Name) {
+			continue
+		}
+		tasks = append(tasks, models.WorkoutTask{
+			Name:        drills[0].Name,
+			Sets:        1,
+			Reps:        10,
+			Description: fmt.Sprintf("Mobility for %s, controlled range of motion", m),
+		})
+	}
+
+	if lift, ok := firstHeavyLift(blocks); ok {
+		tasks = append(tasks, models.WorkoutTask{
+			Name:        lift.Name + " Ramp-up",
+			Sets:        3,
+			Reps:        5,
+			RestSeconds: 45,
+			Description: fmt.Sprintf("Ramp-up sets for %s: 8 reps at ~40%%, 5 at ~60%%, 3 at ~80%% of working weight", lift.Name),
+		})
+	}
+
+	for i := range tasks {
+		tasks[i].Category = models.TaskCategoryWarmup
+	}
+	return tasks
+}
+
+// GenerateCooldown builds static stretches for the session's muscle groups.
+func GenerateCooldown(blocks []Block) []models.WorkoutTask {
+	var tasks []models.WorkoutTask
+	for _, m := range MuscleGroups(blocks) {
+		if len(tasks) == maxCooldownStretch {
+			break
+		}
+		stretches := catalog.ByKindAndMuscle(catalog.KindStretch, m)
+		if len(stretches) == 0 || containsTask(tasks, stretches[0].Name) {
+			continue
+		}
+		tasks = append(tasks, models.WorkoutTask{
+			Name:        stretches[0].Name,
+			Sets:        1,
+			Reps:        10,
+			Tempo:       "2-0-2-0",
+			Description: fmt.Sprintf("Hold for 10 slow breaths to stretch the %s", m),
+			Category:    models.TaskCategoryCooldown,
+		})
+	}
+	return tasks
+}
+
+func firstHeavyLift(blocks []Block) (catalog.Exercise, bool) {
+	for _, b := range blocks {
+		for _, task := range b.Tasks {
+			if !task.IsTraining() {
+				continue
+			}
+			ex, ok := catalog.Lookup(task.Name)
+			if !ok || ex.Kind != catalog.KindCompound {
+				continue
+			}
+			for _, e := range ex.Equipment {
+				if loadedEquipment[e] {
+					return ex, true
+				}
+			}
+		}
+	}
+	return catalog.Exercise{}, false
+}
+
+func containsTask(tasks []models.WorkoutTask, name string) bool {
+	for _, t := range tasks {
+		if t.Name == name {
+			return true
+		}
+	}
+	return false
+}
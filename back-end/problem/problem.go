@@ -0,0 +1,169 @@
+// problem/problem.go
+package problem
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+
+	"back-end/dberr"
+	"back-end/models"
+	"back-end/requestid"
+	"back-end/validate"
+
+	"github.com/go-pg/pg/v10"
+)
+
+// ContentType is the media type of RFC 7807 problem details.
+const ContentType = "application/problem+json"
+
+// Stable error codes. Clients branch on these rather than on the detail
+// text, which is meant for people and may change.
+const (
+	CodeInvalidRequest       = "invalid_request"
+	CodeValidation           = "validation_failed"
+	CodeUnauthorized         = "unauthorized"
+	CodeForbidden            = "forbidden"
+	CodeNotFound             = "not_found"
+	CodeMethodNotAllowed     = "method_not_allowed"
+	CodeConflict             = "conflict"
+	CodeAlreadyExists        = "already_exists"
+	CodeVersionMismatch      = "version_mismatch"
+	CodePreconditionRequired = "precondition_required"
+	CodeUnsupportedMediaType = "unsupported_media_type"
+	CodeGone                 = "gone"
+	CodeIdempotencyKeyReused = "idempotency_key_reused"
+	CodeRequestInProgress    = "request_in_progress"
+	CodeInternal             = "internal_error"
+)
+
+// Problem is an RFC 7807 problem details object. Code and RequestID are
+// extension members; Errors lists field errors for validation failures.
+type Problem struct {
+	Type      string          `json:"type"`
+	Title     string          `json:"title"`
+	Status    int             `json:"status"`
+	Detail    string          `json:"detail,omitempty"`
+	Instance  string          `json:"instance,omitempty"`
+	Code      string          `json:"code"`
+	RequestID string          `json:"requestId,omitempty"`
+	Errors    validate.Errors `json:"errors,omitempty"`
+}
+
+// New returns a problem for status with a stable code. The type URI is
+// derived from the code so each code documents one kind of problem.
+func New(status int, code, detail string) *Problem {
+	return &Problem{
+		Type:   "/problems/" + code,
+		Title:  http.StatusText(status),
+		Status: status,
+		Detail: detail,
+		Code:   code,
+	}
+}
+
+func (p *Problem) Error() string {
+	return p.Detail
+}
+
+// From maps well-known errors to a problem: problems pass through, field
+// errors become 422, a missing row 404, and constraint violations 409 or
+// 422 naming the offending fields. It returns nil for any other error.
+func From(err error) *Problem {
+	var p *Problem
+	if errors.As(err, &p) {
+		return p
+	}
+
+	var errs validate.Errors
+	if errors.As(err, &errs) {
+		p := New(http.StatusUnprocessableEntity, CodeValidation, "Validation failed")
+		p.Errors = errs
+		return p
+	}
+
+	if errors.Is(err, pg.ErrNoRows) {
+		return New(http.StatusNotFound, CodeNotFound, "Record not found")
+	}
+
+	if v := dberr.Classify(err); v != nil {
+		return fromViolation(v)
+	}
+	return nil
+}
+
+// fromViolation describes a constraint violation in terms of the JSON
+// fields the client sent.
+func fromViolation(v *dberr.Violation) *Problem {
+	fields := make([]string, len(v.Columns))
+	for i, column := range v.Columns {
+		fields[i] = models.JSONField(v.Table, column)
+	}
+
+	var p *Problem
+	var code, format string
+	switch v.Kind {
+	case dberr.Unique:
+		detail := "Record already exists"
+		if len(fields) > 0 {
+			detail = "A record with this " + strings.Join(fields, " and ") + " already exists"
+		}
+		p = New(http.StatusConflict, CodeAlreadyExists, detail)
+		code, format = validate.CodeDuplicate, "%s is already in use"
+	case dberr.Referenced:
+		return New(http.StatusConflict, CodeConflict, "Record is still referenced by other records")
+	case dberr.ForeignKey:
+		p = New(http.StatusUnprocessableEntity, CodeValidation, "Validation failed")
+		code, format = validate.CodeNotFound, "%s refers to a record that does not exist"
+	case dberr.NotNull:
+		p = New(http.StatusUnprocessableEntity, CodeValidation, "Validation failed")
+		code, format = validate.CodeRequired, "%s is required"
+	default:
+		p = New(http.StatusUnprocessableEntity, CodeValidation, "Validation failed")
+		code, format = validate.CodeInvalid, "%s is not an allowed value"
+	}
+
+	for _, field := range fields {
+		p.Errors = append(p.Errors, validate.Field(field, code, format, field)...)
+	}
+	if len(p.Errors) == 0 && p.Status == http.StatusUnprocessableEntity {
+		p.Detail = "Record breaks the constraint " + v.Constraint
+	}
+	return p
+}
+
+// Write sends p as application/problem+json, filling in the instance and
+// request id from r.
+func Write(w http.ResponseWriter, r *http.Request, p *Problem) {
+	body := *p
+	if body.Instance == "" {
+		body.Instance = r.URL.Path
+	}
+	body.RequestID = requestid.FromContext(r.Context())
+
+	w.Header().Set("Content-Type", ContentType)
+	w.Header().Del("Content-Length")
+	w.WriteHeader(body.Status)
+	json.NewEncoder(w).Encode(body)
+}
+
+// Error is the problem details counterpart of http.Error.
+func Error(w http.ResponseWriter, r *http.Request, status int, code, detail string) {
+	Write(w, r, New(status, code, detail))
+}
+
+// NotFoundHandler answers requests that match no route.
+func NotFoundHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Error(w, r, http.StatusNotFound, CodeNotFound, "No route matches this URL")
+	})
+}
+
+// MethodNotAllowedHandler answers requests using a method a route does
+// not support.
+func MethodNotAllowedHandler() http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		Error(w, r, http.StatusMethodNotAllowed, CodeMethodNotAllowed, "Method not allowed for this URL")
+	})
+}
@@ -0,0 +1,50 @@
+// requestid/requestid.go
+package requestid
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+)
+
+// Header carries the request id in both directions. A client may send its
+// own id to correlate retries; otherwise one is generated.
+const Header = "X-Request-ID"
+
+// maxLength bounds ids accepted from clients so they stay safe to log.
+const maxLength = 128
+
+type contextKey struct{}
+
+// New returns a random 32 character hex id.
+func New() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
+
+// Accept reports whether an id sent by a client can be used as is.
+func Accept(id string) bool {
+	if id == "" || len(id) > maxLength {
+		return false
+	}
+	for _, c := range id {
+		if c < 0x21 || c > 0x7e {
+			return false
+		}
+	}
+	return true
+}
+
+// NewContext returns a copy of ctx carrying id.
+func NewContext(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, contextKey{}, id)
+}
+
+// FromContext returns the request id stored in ctx, or "".
+func FromContext(ctx context.Context) string {
+	id, _ := ctx.Value(contextKey{}).(string)
+	return id
+}
@@ -0,0 +1,242 @@
+// schedule/materialize.go
+package schedule
+
+import (
+	"fmt"
+	"time"
+
+	"back-end/models"
+
+	"github.com/go-pg/pg/v10/orm"
+)
+
+// MaxWindowDays caps how far a single expansion may reach.
+const MaxWindowDays = 366
+
+// Occurrences are kept materialized up to FutureDays after today, ten
+// days beyond the furthest feed looks ahead so a missed daily refresh
+// leaves no gap. None are created for past dates: those keep what was
+// materialized while they were upcoming, so a task scheduled to start in
+// the past has no missed history. Stats look back PastDays. Reads within
+// that window never write.
+const (
+	PastDays   = 365
+	FutureDays = 190
+)
+
+// ValidateTask checks a task's schedule: a recurrence rule must parse and
+// needs a scheduled_for date to start from.
+func ValidateTask(task *models.WorkoutTask) error {
+	if task.Recurrence == "" {
+		return nil
+	}
+	if task.ScheduledFor == nil {
+		return fmt.Errorf("recurrence requires scheduledFor")
+	}
+	_, err := ParseRule(task.Recurrence)
+	return err
+}
+
+// Today returns the current date in the timezone of the given profile.
+func Today(db orm.DB, userID int) (models.Date, error) {
+	var profile models.UserProfile
+	err := db.Model(&profile).Column("id", "timezone").Where("id = ?", userID).Select()
+	if err != nil {
+		return models.Date{}, err
+	}
+	return profile.Today(), nil
+}
+
+// Expand returns the dates within [from, to] on which the task is due.
+// Unscheduled tasks have no dates. Paused days shift the program: a
+// one-off task dated inside a pause moves past it, and a recurring task
+// skips paused dates without using up its COUNT, with its UNTIL extended
+// by the paused days it covers.
+func Expand(task models.WorkoutTask, from, to models.Date, pauses Pauses) ([]models.Date, error) {
+	if task.ScheduledFor == nil {
+		return nil, nil
+	}
+	start := *task.ScheduledFor
+
+	if task.Recurrence == "" {
+		d := pauses.Shift(start)
+		if d.Before(from) || d.After(to) {
+			return nil, nil
+		}
+		return []models.Date{d}, nil
+	}
+
+	rule, err := ParseRule(task.Recurrence)
+	if err != nil {
+		return nil, err
+	}
+	if rule.Until != nil {
+		// Extend until the added days no longer reach further pauses
+		until := *rule.Until
+		for {
+			extended := rule.Until.AddDays(pauses.DaysBetween(start, until))
+			if extended.Equal(until.Time) {
+				break
+			}
+			until = extended
+		}
+		rule.Until = &until
+	}
+	return rule.Between(start, from, to, pauses.Contains), nil
+}
+
+// Materialize makes sure an occurrence row exists for every date from
+// today on the user's scheduled tasks are due within [from, to], honouring
+// plan pauses. Existing occurrences, including completed or moved ones,
+// are left as they are.
+func Materialize(db orm.DB, userID int, from, to models.Date) error {
+	// One-off tasks shifted past a pause may start before the window ends
+	// yet land inside it, so all scheduled tasks are considered. Imported
+	// history already has its completed occurrence and is never shifted.
+	var tasks []models.WorkoutTask
+	err := db.Model(&tasks).
+		Where("user_id = ?", userID).
+		Where("scheduled_for IS NOT NULL").
+		Where("source = ?", models.TaskSourcePlan).
+		Select()
+	if err != nil {
+		return err
+	}
+	return materialize(db, userID, tasks, from, to)
+}
+
+// Refresh materializes the user's occurrences within the kept window
+// ahead of today. It runs when the user's pauses change, and daily as the
+// window moves forward.
+func Refresh(db orm.DB, userID int) error {
+	today, err := Today(db, userID)
+	if err != nil {
+		return err
+	}
+	return Materialize(db, userID, today, today.AddDays(FutureDays))
+}
+
+// RefreshTask materializes a task's occurrences within the kept window,
+// from today or the day it starts if that is later, after it is created
+// or its schedule changes.
+func RefreshTask(db orm.DB, task *models.WorkoutTask) error {
+	if task.ScheduledFor == nil || task.Source == models.TaskSourceImport {
+		return nil
+	}
+	today, err := Today(db, task.UserID)
+	if err != nil {
+		return err
+	}
+	from := today
+	if task.ScheduledFor.After(today) {
+		from = *task.ScheduledFor
+	}
+	return materialize(db, task.UserID, []models.WorkoutTask{*task}, from, today.AddDays(FutureDays))
+}
+
+func materialize(db orm.DB, userID int, tasks []models.WorkoutTask, from, to models.Date) error {
+	// Past dates are never filled in, or they would count as missed
+	today, err := Today(db, userID)
+	if err != nil {
+		return err
+	}
+	if from.Before(today) {
+		from = today
+	}
+	if to.Before(from) {
+		return nil
+	}
+
+	pauses, err := LoadPauses(db, userID)
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	var occurrences []models.TaskOccurrence
+	for _, task := range tasks {
+		dates, err := Expand(task, from, to, pauses)
+		if err != nil {
+			return fmt.Errorf("task %d: %w", task.ID, err)
+		}
+		for _, d := range dates {
+			occurrences = append(occurrences, models.TaskOccurrence{
+				TaskID:       task.ID,
+				UserID:       userID,
+				OccursOn:     d,
+				ScheduledFor: d,
+				Status:       models.OccurrenceScheduled,
+				CreatedAt:    now,
+				UpdatedAt:    now,
+			})
+		}
+	}
+	if len(occurrences) == 0 {
+		return nil
+	}
+
+	_, err = db.Model(&occurrences).
+		OnConflict("(task_id, occurs_on) DO NOTHING").
+		Insert()
+	return err
+}
+
+// Occurrences materializes and returns the user's occurrences due within
+// [from, to], ordered by date and then by task position.
+func Occurrences(db orm.DB, userID int, from, to models.Date) ([]models.TaskOccurrence, error) {
+	if err := Materialize(db, userID, from, to); err != nil {
+		return nil, err
+	}
+	return LoadOccurrences(db, userID, from, to)
+}
+
+// LoadOccurrences returns the user's materialized occurrences due within
+// [from, to] like Occurrences, without writing. Within the kept window
+// every occurrence is materialized already.
+func LoadOccurrences(db orm.DB, userID int, from, to models.Date) ([]models.TaskOccurrence, error) {
+	var occurrences []models.TaskOccurrence
+	err := db.Model(&occurrences).
+		Relation("Task").
+		Where("task_occurrence.user_id = ?", userID).
+		Where("task_occurrence.scheduled_for BETWEEN ? AND ?", from, to).
+		// The join finds no task for occurrences of trashed tasks
+		Where("task.id IS NOT NULL").
+		Order("task_occurrence.scheduled_for ASC", "task.position ASC", "task_occurrence.id ASC").
+		Select()
+	return occurrences, err
+}
+
+// ClearFuture removes pending occurrences of a task from the given date on,
+// so they are regenerated after its schedule changes.
+func ClearFuture(db orm.DB, taskID int, from models.Date) error {
+	_, err := db.Model((*models.TaskOccurrence)(nil)).
+		Where("task_id = ?", taskID).
+		Where("status = ?", models.OccurrenceScheduled).
+		Where("occurs_on >= ?", from).
+		Delete()
+	return err
+}
+
+// ClearPending removes every pending occurrence of the user generated for
+// from onwards, so they are regenerated after pauses change.
+func ClearPending(db orm.DB, userID int, from models.Date) error {
+	_, err := db.Model((*models.TaskOccurrence)(nil)).
+		Where("user_id = ?", userID).
+		Where("status = ?", models.OccurrenceScheduled).
+		Where("occurs_on >= ?", from).
+		Delete()
+	return err
+}
+
+// SkipPaused marks pending occurrences that were moved into a pause, for
+// example by the rescheduler, as skipped.
+func SkipPaused(db orm.DB, pause *models.PlanPause) error {
+	_, err := db.Model((*models.TaskOccurrence)(nil)).
+		Set("status = ?", models.OccurrenceSkipped).
+		Set("updated_at = ?", time.Now()).
+		Where("user_id = ?", pause.UserID).
+		Where("status = ?", models.OccurrenceScheduled).
+		Where("scheduled_for BETWEEN ? AND ?", pause.StartDate, pause.EndDate).
+		Update()
+	return err
+}
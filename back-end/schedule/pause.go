@@ -0,0 +1,67 @@
+// schedule/pause.go
+package schedule
+
+import (
+	"back-end/models"
+
+	"github.com/go-pg/pg/v10/orm"
+)
+
+// Pauses is a user's set of plan pauses.
+type Pauses []models.PlanPause
+
+// LoadPauses returns every pause of the user in start order.
+func LoadPauses(db orm.DB, userID int) (Pauses, error) {
+	var pauses Pauses
+	err := db.Model(&pauses).
+		Where("user_id = ?", userID).
+		Order("start_date ASC").
+		Select()
+	return pauses, err
+}
+
+// Contains reports whether d falls within any pause.
+func (p Pauses) Contains(d models.Date) bool {
+	return p.find(d) != nil
+}
+
+// Active returns the pause covering d, if any.
+func (p Pauses) Active(d models.Date) *models.PlanPause {
+	return p.find(d)
+}
+
+func (p Pauses) find(d models.Date) *models.PlanPause {
+	for i := range p {
+		if p[i].Contains(d) {
+			return &p[i]
+		}
+	}
+	return nil
+}
+
+// DaysBetween counts the paused days within [from, to].
+func (p Pauses) DaysBetween(from, to models.Date) int {
+	days := 0
+	for _, pause := range p {
+		start, end := pause.StartDate, pause.EndDate
+		if start.Before(from) {
+			start = from
+		}
+		if end.After(to) {
+			end = to
+		}
+		if !end.Before(start) {
+			days += int(end.Sub(start.Time).Hours()/24) + 1
+		}
+	}
+	return days
+}
+
+// Shift moves a date that falls inside a pause forward by the pause's
+// length, repeating if it lands in another pause.
+func (p Pauses) Shift(d models.Date) models.Date {
+	for pause := p.find(d); pause != nil; pause = p.find(d) {
+		d = d.AddDays(pause.Days())
+	}
+	return d
+}
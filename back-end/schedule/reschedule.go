@@ -0,0 +1,290 @@
+// schedule/reschedule.go
+package schedule
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"back-end/models"
+	"back-end/planner"
+
+	"github.com/go-pg/pg/v10"
+)
+
+const (
+	// MissedLookbackDays is how far back missed occurrences are rescheduled.
+	// Anything older is skipped rather than moved into the present.
+	MissedLookbackDays = 7
+
+	// rescheduleHorizonDays is how far ahead occurrences are materialized so
+	// push and merge can see upcoming sessions.
+	rescheduleHorizonDays = 14
+)
+
+// liveTask selects occurrences whose task is not in the trash, for queries
+// that do not join the task.
+const liveTask = "EXISTS (SELECT 1 FROM workout_tasks AS t WHERE t.id = task_occurrence.task_id AND t.deleted_at IS NULL)"
+
+// rescheduler applies one user's missed workout policy inside a transaction.
+type rescheduler struct {
+	tx      *pg.Tx
+	profile *models.UserProfile
+	today   models.Date
+	groups  []models.WorkoutGroup
+	events  []models.RescheduleEvent
+	moved   map[int]bool
+}
+
+// Reschedule applies the profile's missed workout policy to every pending
+// occurrence dated before today, the current date in the profile's
+// timezone, and returns what it skipped or moved.
+//
+//   - skip marks missed occurrences as skipped.
+//   - push moves the missed session to today and shifts the rest of that
+//     week's pending sessions forward by the same number of days. Weeks
+//     start on the profile's week start day.
+//   - merge adds the missed session to the next scheduled session, or to
+//     today if there is none, as long as the combined estimate stays within
+//     the profile's merge cap. Otherwise the missed session is skipped.
+func Reschedule(ctx context.Context, db *pg.DB, profile *models.UserProfile, today models.Date) ([]models.RescheduleEvent, error) {
+	// Nothing is moved while the plan is paused; missed work is dealt with
+	// once it resumes
+	pauses, err := LoadPauses(db, profile.ID)
+	if err != nil {
+		return nil, err
+	}
+	if pauses.Contains(today) {
+		return nil, nil
+	}
+
+	// Missed occurrences were materialized while they were upcoming
+	if err := Materialize(db, profile.ID, today, today.AddDays(rescheduleHorizonDays)); err != nil {
+		return nil, err
+	}
+
+	r := &rescheduler{profile: profile, today: today, moved: make(map[int]bool)}
+	err = db.RunInTransaction(ctx, func(tx *pg.Tx) error {
+		r.tx = tx
+		r.events = nil
+
+		var missed []models.TaskOccurrence
+		err := tx.Model(&missed).
+			Relation("Task").
+			Where("task_occurrence.user_id = ?", profile.ID).
+			Where("task_occurrence.status = ?", models.OccurrenceScheduled).
+			Where("task_occurrence.scheduled_for < ?", today).
+			// Occurrences of trashed tasks wait for a restore
+			Where("task.id IS NOT NULL").
+			Order("task_occurrence.scheduled_for ASC", "task.position ASC").
+			For("UPDATE OF task_occurrence").
+			Select()
+		if err != nil || len(missed) == 0 {
+			return err
+		}
+
+		if err := tx.Model(&r.groups).Where("user_id = ?", profile.ID).Select(); err != nil {
+			return err
+		}
+
+		// Occurrences older than the lookback are too stale to move
+		cutoff := today.AddDays(-MissedLookbackDays)
+		var recent []models.TaskOccurrence
+		for _, o := range missed {
+			if o.ScheduledFor.Before(cutoff) {
+				if err := r.skip(o, "older than the reschedule lookback"); err != nil {
+					return err
+				}
+				continue
+			}
+			recent = append(recent, o)
+		}
+		if len(recent) == 0 {
+			return r.record()
+		}
+
+		switch profile.MissedWorkoutPolicy {
+		case models.MissedWorkoutPush:
+			err = r.push(recent)
+		case models.MissedWorkoutMerge:
+			err = r.merge(recent)
+		default:
+			for _, o := range recent {
+				if err = r.skip(o, ""); err != nil {
+					break
+				}
+			}
+		}
+		if err != nil {
+			return err
+		}
+		return r.record()
+	})
+	return r.events, err
+}
+
+func (r *rescheduler) record() error {
+	if len(r.events) == 0 {
+		return nil
+	}
+	_, err := r.tx.Model(&r.events).Insert()
+	return err
+}
+
+func (r *rescheduler) event(o models.TaskOccurrence, action string, to *models.Date, reason string) {
+	r.events = append(r.events, models.RescheduleEvent{
+		UserID:       r.profile.ID,
+		OccurrenceID: o.ID,
+		TaskID:       o.TaskID,
+		Policy:       r.profile.MissedWorkoutPolicy,
+		Action:       action,
+		FromDate:     o.ScheduledFor,
+		ToDate:       to,
+		Reason:       reason,
+		CreatedAt:    time.Now(),
+	})
+}
+
+func (r *rescheduler) skip(o models.TaskOccurrence, reason string) error {
+	_, err := r.tx.Model(&o).
+		Set("status = ?", models.OccurrenceSkipped).
+		Set("updated_at = ?", time.Now()).
+		WherePK().
+		Update()
+	if err != nil {
+		return err
+	}
+	r.event(o, models.RescheduleSkipped, nil, reason)
+	return nil
+}
+
+func (r *rescheduler) move(o models.TaskOccurrence, to models.Date, reason string) error {
+	_, err := r.tx.Model(&o).
+		Set("scheduled_for = ?", to).
+		Set("updated_at = ?", time.Now()).
+		WherePK().
+		Update()
+	if err != nil {
+		return err
+	}
+	r.moved[o.ID] = true
+	r.event(o, models.RescheduleMoved, &to, reason)
+	return nil
+}
+
+// weekEnd returns the last day of the week containing d, for weeks starting
+// on weekStart.
+func weekEnd(d models.Date, weekStart time.Weekday) models.Date {
+	offset := (int(d.Weekday()) - int(weekStart) + 7) % 7
+	return d.AddDays(6 - offset)
+}
+
+func (r *rescheduler) push(missed []models.TaskOccurrence) error {
+	for _, o := range missed {
+		if r.moved[o.ID] {
+			continue
+		}
+
+		// Shift everything pending from the missed day to the end of its
+		// week by the same delay, keeping the spacing between sessions
+		from := o.ScheduledFor
+		delay := int(r.today.Sub(from.Time).Hours() / 24)
+		var pending []models.TaskOccurrence
+		err := r.tx.Model(&pending).
+			Where("user_id = ?", r.profile.ID).
+			Where("status = ?", models.OccurrenceScheduled).
+			Where(liveTask).
+			Where("scheduled_for BETWEEN ? AND ?", from, weekEnd(from, r.profile.FirstDayOfWeek())).
+			Order("scheduled_for ASC", "id ASC").
+			For("UPDATE").
+			Select()
+		if err != nil {
+			return err
+		}
+
+		reason := fmt.Sprintf("pushed %d day(s) after missing %s", delay, from)
+		for _, p := range pending {
+			if r.moved[p.ID] {
+				continue
+			}
+			if err := r.move(p, p.ScheduledFor.AddDays(delay), reason); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func (r *rescheduler) merge(missed []models.TaskOccurrence) error {
+	// Missed occurrences arrive ordered by date; merge one day at a time
+	for start := 0; start < len(missed); {
+		end := start
+		for end < len(missed) && missed[end].ScheduledFor.Equal(missed[start].ScheduledFor.Time) {
+			end++
+		}
+		day := missed[start:end]
+		start = end
+
+		target, existing, err := r.nextSession()
+		if err != nil {
+			return err
+		}
+
+		tasks := make([]models.WorkoutTask, 0, len(existing)+len(day))
+		for _, o := range append(existing, day...) {
+			if o.Task != nil {
+				tasks = append(tasks, *o.Task)
+			}
+		}
+		est := planner.EstimateSession(planner.Blocks(tasks, r.groups), planner.DefaultOptions)
+
+		if limit := r.profile.MergeCap(); limit > 0 && est.TotalMinutes > limit {
+			reason := fmt.Sprintf("merging into %s would take %d min, over the %d min cap", target, est.TotalMinutes, limit)
+			for _, o := range day {
+				if err := r.skip(o, reason); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		reason := fmt.Sprintf("merged into the session on %s", target)
+		for _, o := range day {
+			if err := r.move(o, target, reason); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// nextSession finds the first day from today with pending occurrences and
+// returns it with those occurrences. Today is returned when nothing is
+// scheduled within the horizon.
+func (r *rescheduler) nextSession() (models.Date, []models.TaskOccurrence, error) {
+	var next models.TaskOccurrence
+	err := r.tx.Model(&next).
+		Where("user_id = ?", r.profile.ID).
+		Where("status = ?", models.OccurrenceScheduled).
+		Where(liveTask).
+		Where("scheduled_for BETWEEN ? AND ?", r.today, r.today.AddDays(rescheduleHorizonDays)).
+		Order("scheduled_for ASC").
+		Limit(1).
+		Select()
+	if err == pg.ErrNoRows {
+		return r.today, nil, nil
+	}
+	if err != nil {
+		return r.today, nil, err
+	}
+
+	var existing []models.TaskOccurrence
+	err = r.tx.Model(&existing).
+		Relation("Task").
+		Where("task_occurrence.user_id = ?", r.profile.ID).
+		Where("task_occurrence.status = ?", models.OccurrenceScheduled).
+		Where("task_occurrence.scheduled_for = ?", next.ScheduledFor).
+		Where("task.id IS NOT NULL").
+		Select()
+	return next.ScheduledFor, existing, err
+}
@@ -0,0 +1,246 @@
+// schedule/rrule.go
+package schedule
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"back-end/models"
+)
+
+// Supported recurrence frequencies
+const (
+	FreqDaily   = "DAILY"
+	FreqWeekly  = "WEEKLY"
+	FreqMonthly = "MONTHLY"
+)
+
+var weekdays = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+// Rule is the subset of an RFC 5545 RRULE that workouts need: DAILY,
+// WEEKLY and MONTHLY frequencies with INTERVAL, COUNT, UNTIL, BYDAY
+// (weekly, without ordinals), BYMONTHDAY (monthly) and WKST.
+type Rule struct {
+	Freq       string
+	Interval   int
+	Count      int
+	Until      *models.Date
+	ByDay      []time.Weekday
+	ByMonthDay []int
+	WeekStart  time.Weekday
+}
+
+// ParseRule parses an RRULE value such as "FREQ=WEEKLY;BYDAY=MO,WE,FR".
+// An optional "RRULE:" prefix is accepted.
+func ParseRule(s string) (*Rule, error) {
+	s = strings.TrimPrefix(strings.TrimSpace(s), "RRULE:")
+	if s == "" {
+		return nil, fmt.Errorf("recurrence rule is empty")
+	}
+
+	rule := &Rule{Interval: 1, WeekStart: time.Monday}
+	seen := make(map[string]bool)
+	for _, part := range strings.Split(s, ";") {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 || kv[1] == "" {
+			return nil, fmt.Errorf("invalid recurrence rule part %q", part)
+		}
+		key, value := strings.ToUpper(kv[0]), strings.ToUpper(kv[1])
+		if seen[key] {
+			return nil, fmt.Errorf("recurrence rule repeats %s", key)
+		}
+		seen[key] = true
+
+		switch key {
+		case "FREQ":
+			if value != FreqDaily && value != FreqWeekly && value != FreqMonthly {
+				return nil, fmt.Errorf("unsupported FREQ %q, expected DAILY, WEEKLY or MONTHLY", value)
+			}
+			rule.Freq = value
+		case "INTERVAL":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("INTERVAL must be a positive integer")
+			}
+			rule.Interval = n
+		case "COUNT":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return nil, fmt.Errorf("COUNT must be a positive integer")
+			}
+			rule.Count = n
+		case "UNTIL":
+			// Only the date matters; a trailing time such as T235959Z is ignored
+			if len(value) < 8 {
+				return nil, fmt.Errorf("UNTIL must be YYYYMMDD")
+			}
+			t, err := time.Parse("20060102", value[:8])
+			if err != nil {
+				return nil, fmt.Errorf("UNTIL must be YYYYMMDD")
+			}
+			until := models.DateOf(t)
+			rule.Until = &until
+		case "BYDAY":
+			listed := make(map[time.Weekday]bool)
+			for _, d := range strings.Split(value, ",") {
+				wd, ok := weekdays[d]
+				if !ok {
+					return nil, fmt.Errorf("unsupported BYDAY value %q", d)
+				}
+				if listed[wd] {
+					return nil, fmt.Errorf("BYDAY repeats %s", d)
+				}
+				listed[wd] = true
+				rule.ByDay = append(rule.ByDay, wd)
+			}
+		case "BYMONTHDAY":
+			listed := make(map[int]bool)
+			for _, d := range strings.Split(value, ",") {
+				n, err := strconv.Atoi(d)
+				if err != nil || n < 1 || n > 31 {
+					return nil, fmt.Errorf("BYMONTHDAY values must be between 1 and 31")
+				}
+				if listed[n] {
+					return nil, fmt.Errorf("BYMONTHDAY repeats %d", n)
+				}
+				listed[n] = true
+				rule.ByMonthDay = append(rule.ByMonthDay, n)
+			}
+		case "WKST":
+			wd, ok := weekdays[value]
+			if !ok {
+				return nil, fmt.Errorf("unsupported WKST value %q", value)
+			}
+			rule.WeekStart = wd
+		default:
+			return nil, fmt.Errorf("unsupported recurrence rule part %s", key)
+		}
+	}
+
+	if rule.Freq == "" {
+		return nil, fmt.Errorf("recurrence rule requires FREQ")
+	}
+	if rule.Count > 0 && rule.Until != nil {
+		return nil, fmt.Errorf("COUNT and UNTIL cannot be combined")
+	}
+	if len(rule.ByDay) > 0 && rule.Freq != FreqWeekly {
+		return nil, fmt.Errorf("BYDAY is only supported with FREQ=WEEKLY")
+	}
+	if len(rule.ByMonthDay) > 0 && rule.Freq != FreqMonthly {
+		return nil, fmt.Errorf("BYMONTHDAY is only supported with FREQ=MONTHLY")
+	}
+	return rule, nil
+}
+
+// Between returns the occurrences of the rule starting at dtstart that fall
+// within [from, to], in order. COUNT is applied from dtstart, so
+// occurrences before the window still use up the count. Dates for which
+// skip returns true are left out and do not count; skip may be nil.
+func (r *Rule) Between(dtstart, from, to models.Date, skip func(models.Date) bool) []models.Date {
+	// Without COUNT nothing before the window matters, so expansion starts
+	// at the period containing from
+	period := 0
+	if r.Count == 0 {
+		period = r.periodOf(dtstart, from)
+	}
+
+	var out []models.Date
+	n := 0
+	for ; !r.periodStart(dtstart, period).After(to); period++ {
+		for _, d := range r.periodDates(dtstart, period) {
+			if d.Before(dtstart) {
+				continue
+			}
+			if d.After(to) || (r.Until != nil && d.After(*r.Until)) || (r.Count > 0 && n >= r.Count) {
+				return out
+			}
+			if skip != nil && skip(d) {
+				continue
+			}
+			n++
+			if !d.Before(from) {
+				out = append(out, d)
+			}
+		}
+	}
+	return out
+}
+
+// periodStart returns the first day of the nth period: the day itself for
+// DAILY, the first day of the week for WEEKLY and of the month for MONTHLY.
+func (r *Rule) periodStart(dtstart models.Date, n int) models.Date {
+	switch r.Freq {
+	case FreqWeekly:
+		offset := (int(dtstart.Weekday()) - int(r.WeekStart) + 7) % 7
+		return dtstart.AddDays(-offset + 7*n*r.Interval)
+	case FreqMonthly:
+		return models.NewDate(dtstart.Year(), dtstart.Month()+time.Month(n*r.Interval), 1)
+	}
+	return dtstart.AddDays(n * r.Interval)
+}
+
+// periodOf returns the period containing d, or 0 if d is before dtstart.
+func (r *Rule) periodOf(dtstart, d models.Date) int {
+	if !d.After(dtstart) {
+		return 0
+	}
+	switch r.Freq {
+	case FreqWeekly:
+		days := int(d.Sub(r.periodStart(dtstart, 0).Time).Hours() / 24)
+		return days / (7 * r.Interval)
+	case FreqMonthly:
+		months := (d.Year()-dtstart.Year())*12 + int(d.Month()) - int(dtstart.Month())
+		return months / r.Interval
+	}
+	return int(d.Sub(dtstart.Time).Hours()/24) / r.Interval
+}
+
+// periodDates returns the candidate dates of the nth period in order.
+func (r *Rule) periodDates(dtstart models.Date, n int) []models.Date {
+	start := r.periodStart(dtstart, n)
+	switch r.Freq {
+	case FreqDaily:
+		return []models.Date{start}
+
+	case FreqWeekly:
+		days := r.ByDay
+		if len(days) == 0 {
+			days = []time.Weekday{dtstart.Weekday()}
+		}
+		var dates []models.Date
+		for _, wd := range days {
+			dates = append(dates, start.AddDays((int(wd)-int(r.WeekStart)+7)%7))
+		}
+		sort.Slice(dates, func(i, j int) bool { return dates[i].Before(dates[j]) })
+		return dates
+
+	case FreqMonthly:
+		days := r.ByMonthDay
+		if len(days) == 0 {
+			days = []int{dtstart.Day()}
+		}
+		sorted := append([]int(nil), days...)
+		sort.Ints(sorted)
+		var dates []models.Date
+		for _, day := range sorted {
+			// Days that do not exist in the month are skipped, per RFC 5545
+			d := start.AddDays(day - 1)
+			if d.Month() == start.Month() {
+				dates = append(dates, d)
+			}
+		}
+		return dates
+	}
+	return nil
+}
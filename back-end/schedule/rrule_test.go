@@ -0,0 +1,142 @@
+package schedule
+
+import (
+	"reflect"
+	"testing"
+
+	"back-end/models"
+)
+
+func date(s string) models.Date {
+	d, err := models.ParseDate(s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+func dates(ds []models.Date) []string {
+	out := []string{}
+	for _, d := range ds {
+		out = append(out, d.String())
+	}
+	return out
+}
+
+func TestParseRuleRejects(t *testing.T) {
+	for _, s := range []string{
+		"",
+		"BYDAY=MO",
+		"FREQ=YEARLY",
+		"FREQ=DAILY;FREQ=WEEKLY",
+		"FREQ=WEEKLY;BYDAY=MO,MO",
+		"FREQ=WEEKLY;BYDAY=MO,WE,mo",
+		"FREQ=MONTHLY;BYMONTHDAY=1,15,1",
+		"FREQ=MONTHLY;BYMONTHDAY=32",
+		"FREQ=DAILY;BYDAY=MO",
+		"FREQ=DAILY;COUNT=3;UNTIL=20240110",
+		"FREQ=DAILY;INTERVAL=0",
+	} {
+		if _, err := ParseRule(s); err == nil {
+			t.Errorf("ParseRule(%q) succeeded", s)
+		}
+	}
+}
+
+func TestBetween(t *testing.T) {
+	tests := []struct {
+		name     string
+		rule     string
+		dtstart  string
+		from, to string
+		skip     []string
+		want     []string
+	}{
+		{
+			name: "count", rule: "FREQ=DAILY;COUNT=3",
+			dtstart: "2024-01-01", from: "2024-01-01", to: "2024-01-10",
+			want: []string{"2024-01-01", "2024-01-02", "2024-01-03"},
+		},
+		{
+			name: "count is used up before the window", rule: "FREQ=DAILY;COUNT=3",
+			dtstart: "2024-01-01", from: "2024-01-03", to: "2024-01-10",
+			want: []string{"2024-01-03"},
+		},
+		{
+			name: "skipped dates do not count", rule: "FREQ=DAILY;COUNT=3",
+			dtstart: "2024-01-01", from: "2024-01-01", to: "2024-01-10",
+			skip: []string{"2024-01-02"},
+			want: []string{"2024-01-01", "2024-01-03", "2024-01-04"},
+		},
+		{
+			name: "until", rule: "FREQ=DAILY;UNTIL=20240103T235959Z",
+			dtstart: "2024-01-01", from: "2024-01-01", to: "2024-01-10",
+			want: []string{"2024-01-01", "2024-01-02", "2024-01-03"},
+		},
+		{
+			name: "interval", rule: "FREQ=WEEKLY;INTERVAL=2;BYDAY=TH,MO",
+			dtstart: "2024-01-01", from: "2024-01-01", to: "2024-01-20",
+			want: []string{"2024-01-01", "2024-01-04", "2024-01-15", "2024-01-18"},
+		},
+		{
+			name: "window starting mid-week", rule: "FREQ=WEEKLY;BYDAY=MO,FR",
+			dtstart: "2024-01-01", from: "2024-01-03", to: "2024-01-10",
+			want: []string{"2024-01-05", "2024-01-08"},
+		},
+		// The RFC 5545 WKST example, starting on a Wednesday
+		{
+			name: "week starting on monday", rule: "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,SU;WKST=MO",
+			dtstart: "2024-01-03", from: "2024-01-01", to: "2024-01-31",
+			want: []string{"2024-01-07", "2024-01-15", "2024-01-21", "2024-01-29"},
+		},
+		{
+			name: "week starting on sunday", rule: "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO,SU;WKST=SU",
+			dtstart: "2024-01-03", from: "2024-01-01", to: "2024-01-31",
+			want: []string{"2024-01-14", "2024-01-15", "2024-01-28", "2024-01-29"},
+		},
+		{
+			name: "months without the day are skipped", rule: "FREQ=MONTHLY;BYMONTHDAY=31",
+			dtstart: "2024-01-31", from: "2024-01-01", to: "2024-06-30",
+			want: []string{"2024-01-31", "2024-03-31", "2024-05-31"},
+		},
+		{
+			name: "months without any day end nowhere", rule: "FREQ=MONTHLY;INTERVAL=12;BYMONTHDAY=30",
+			dtstart: "2024-02-01", from: "2024-02-01", to: "2030-12-31",
+			want: []string{},
+		},
+		// Rules that started long ago still expand in today's window
+		{
+			name: "daily from long ago", rule: "FREQ=DAILY",
+			dtstart: "1990-01-01", from: "2030-01-01", to: "2030-01-03",
+			want: []string{"2030-01-01", "2030-01-02", "2030-01-03"},
+		},
+		{
+			name: "weekly from long ago", rule: "FREQ=WEEKLY;INTERVAL=3;BYDAY=MO",
+			dtstart: "1990-01-01", from: "2030-01-01", to: "2030-01-31",
+			want: []string{"2030-01-07", "2030-01-28"},
+		},
+		{
+			name: "monthly from long ago", rule: "FREQ=MONTHLY;INTERVAL=5",
+			dtstart: "2000-01-15", from: "2050-01-01", to: "2050-12-31",
+			want: []string{"2050-01-15", "2050-06-15", "2050-11-15"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rule, err := ParseRule(tt.rule)
+			if err != nil {
+				t.Fatalf("ParseRule: %v", err)
+			}
+			skipped := make(map[string]bool)
+			for _, s := range tt.skip {
+				skipped[s] = true
+			}
+			skip := func(d models.Date) bool { return skipped[d.String()] }
+
+			got := dates(rule.Between(date(tt.dtstart), date(tt.from), date(tt.to), skip))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Fatalf("Between = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,130 @@
+// schedule/stats.go
+package schedule
+
+import (
+	"sort"
+
+	"back-end/models"
+
+	"github.com/go-pg/pg/v10/orm"
+)
+
+// streakLookbackDays bounds how far back streaks are computed.
+const streakLookbackDays = PastDays
+
+type Adherence struct {
+	From       models.Date `json:"from"`
+	To         models.Date `json:"to"`
+	Scheduled  int         `json:"scheduled"`
+	Completed  int         `json:"completed"`
+	Skipped    int         `json:"skipped"`
+	Missed     int         `json:"missed"`
+	PausedDays int         `json:"pausedDays"`
+	Rate       float64     `json:"rate"`
+}
+
+type Stats struct {
+	CurrentStreak int               `json:"currentStreak"`
+	LongestStreak int               `json:"longestStreak"`
+	Adherence     Adherence         `json:"adherence"`
+	Paused        bool              `json:"paused"`
+	ActivePause   *models.PlanPause `json:"activePause,omitempty"`
+}
+
+// sessionDay summarises the training occurrences due on one date.
+type sessionDay struct {
+	date      models.Date
+	total     int
+	completed int
+}
+
+// ComputeStats returns the user's streaks and their adherence over the last
+// days days up to today. Streaks count consecutive session days on which
+// every training task was completed. Paused days and today's unfinished
+// session neither extend nor break a streak, and are left out of
+// adherence. Warm-up and cool-down tasks are ignored. Only materialized
+// occurrences are read; nothing is written.
+func ComputeStats(db orm.DB, userID int, today models.Date, days int) (Stats, error) {
+	var stats Stats
+	from := today.AddDays(-streakLookbackDays)
+
+	pauses, err := LoadPauses(db, userID)
+	if err != nil {
+		return stats, err
+	}
+	stats.ActivePause = pauses.Active(today)
+	stats.Paused = stats.ActivePause != nil
+
+	var occurrences []models.TaskOccurrence
+	err = db.Model(&occurrences).
+		Relation("Task").
+		Where("task_occurrence.user_id = ?", userID).
+		Where("task_occurrence.scheduled_for BETWEEN ? AND ?", from, today).
+		Where("task.category = ?", models.TaskCategoryExercise).
+		Select()
+	if err != nil {
+		return stats, err
+	}
+
+	window := Adherence{From: today.AddDays(1 - days), To: today}
+	window.PausedDays = pauses.DaysBetween(window.From, window.To)
+
+	byDate := make(map[string]*sessionDay)
+	for _, o := range occurrences {
+		d := o.ScheduledFor
+		if pauses.Contains(d) {
+			continue
+		}
+		// Today's pending work is not due yet
+		if d.Equal(today.Time) && o.Status == models.OccurrenceScheduled {
+			continue
+		}
+
+		day := byDate[d.String()]
+		if day == nil {
+			day = &sessionDay{date: d}
+			byDate[d.String()] = day
+		}
+		day.total++
+		if o.Status == models.OccurrenceCompleted {
+			day.completed++
+		}
+
+		if d.Before(window.From) {
+			continue
+		}
+		window.Scheduled++
+		switch o.Status {
+		case models.OccurrenceCompleted:
+			window.Completed++
+		case models.OccurrenceSkipped:
+			window.Skipped++
+		default:
+			window.Missed++
+		}
+	}
+	if window.Scheduled > 0 {
+		window.Rate = float64(window.Completed) / float64(window.Scheduled)
+	}
+	stats.Adherence = window
+
+	sessions := make([]*sessionDay, 0, len(byDate))
+	for _, day := range byDate {
+		sessions = append(sessions, day)
+	}
+	sort.Slice(sessions, func(i, j int) bool { return sessions[i].date.Before(sessions[j].date) })
+
+	run := 0
+	for _, day := range sessions {
+		if day.completed == day.total {
+			run++
+		} else {
+			run = 0
+		}
+		if run > stats.LongestStreak {
+			stats.LongestStreak = run
+		}
+	}
+	stats.CurrentStreak = run
+	return stats, nil
+}
@@ -0,0 +1,113 @@
+// units/units.go
+package units
+
+import (
+	"fmt"
+	"math"
+)
+
+// Unit systems a user can choose to see values in
+const (
+	Metric   = "metric"
+	Imperial = "imperial"
+)
+
+// Units accepted on input. Values are stored in the first unit of each
+// dimension: kilograms, centimetres and metres.
+const (
+	Kilogram   = "kg"
+	Pound      = "lb"
+	Centimeter = "cm"
+	Inch       = "in"
+	Meter      = "m"
+	Kilometer  = "km"
+	Mile       = "mi"
+)
+
+const (
+	poundsPerKilogram  = 2.2046226218487757
+	centimetersPerInch = 2.54
+	metersPerMile      = 1609.344
+)
+
+// Dimension describes one kind of quantity: the unit it is stored in and
+// the unit it is shown in for each unit system.
+type Dimension struct {
+	Name      string
+	Canonical string
+	Imperial  string
+	// Decimals is how many decimal places converted values are rounded to.
+	// It is coarse enough that converting a rounded value back and forth
+	// returns the same number.
+	Decimals int
+	factors  map[string]float64
+}
+
+// Dimensions used by the API. Factors convert one of the unit into the
+// canonical unit.
+var (
+	Mass = Dimension{
+		Name:      "weight",
+		Canonical: Kilogram,
+		Imperial:  Pound,
+		Decimals:  1,
+		factors:   map[string]float64{Kilogram: 1, Pound: 1 / poundsPerKilogram},
+	}
+	Length = Dimension{
+		Name:      "height",
+		Canonical: Centimeter,
+		Imperial:  Inch,
+		Decimals:  1,
+		factors:   map[string]float64{Centimeter: 1, Inch: centimetersPerInch},
+	}
+	Distance = Dimension{
+		Name:      "distance",
+		Canonical: Meter,
+		Imperial:  Mile,
+		Decimals:  3,
+		factors:   map[string]float64{Meter: 1, Kilometer: 1000, Mile: metersPerMile},
+	}
+)
+
+// IsValidSystem reports whether s is a known unit system.
+func IsValidSystem(s string) bool {
+	return s == Metric || s == Imperial
+}
+
+// Unit returns the unit values of the dimension are shown in for a system.
+func (d Dimension) Unit(system string) string {
+	if system == Imperial {
+		return d.Imperial
+	}
+	return d.Canonical
+}
+
+// ToCanonical converts value from unit into the canonical unit. An empty
+// unit means the value is already canonical.
+func (d Dimension) ToCanonical(value float64, unit string) (float64, error) {
+	if unit == "" {
+		return value, nil
+	}
+	factor, ok := d.factors[unit]
+	if !ok {
+		return 0, fmt.Errorf("unsupported %s unit %q", d.Name, unit)
+	}
+	if factor == 1 {
+		return value, nil
+	}
+	return round(value*factor, 2), nil
+}
+
+// FromCanonical converts a canonical value into the unit used by system.
+func (d Dimension) FromCanonical(value float64, system string) float64 {
+	unit := d.Unit(system)
+	if unit == d.Canonical {
+		return value
+	}
+	return round(value/d.factors[unit], d.Decimals)
+}
+
+func round(v float64, decimals int) float64 {
+	p := math.Pow(10, float64(decimals))
+	return math.Round(v*p) / p
+}
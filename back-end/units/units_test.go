@@ -0,0 +1,137 @@
+package units
+
+import (
+	"math"
+	"testing"
+)
+
+// stored rounds a canonical value the way the DECIMAL(5,2) weight and
+// height columns do.
+func stored(v float64) float64 {
+	return math.Round(v*100) / 100
+}
+
+func TestConversions(t *testing.T) {
+	tests := []struct {
+		name      string
+		dim       Dimension
+		unit      string
+		value     float64
+		canonical float64
+	}{
+		{"kg is canonical", Mass, Kilogram, 72.5, 72.5},
+		{"lb", Mass, Pound, 160, 72.57},
+		{"lowest weight in lb", Mass, Pound, 44.1, 20},
+		{"highest weight in lb", Mass, Pound, 1102.3, 499.99},
+		{"cm is canonical", Length, Centimeter, 180, 180},
+		{"in", Length, Inch, 70, 177.8},
+		{"lowest height in in", Length, Inch, 19.7, 50.04},
+		{"highest height in in", Length, Inch, 118.1, 299.97},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.dim.ToCanonical(tt.value, tt.unit)
+			if err != nil {
+				t.Fatalf("ToCanonical: %v", err)
+			}
+			if stored(got) != tt.canonical {
+				t.Fatalf("ToCanonical(%v, %q) = %v, want %v", tt.value, tt.unit, got, tt.canonical)
+			}
+			system := Metric
+			if tt.unit == tt.dim.Imperial {
+				system = Imperial
+			}
+			if back := tt.dim.FromCanonical(stored(got), system); back != tt.value {
+				t.Fatalf("FromCanonical(%v, %q) = %v, want %v", stored(got), system, back, tt.value)
+			}
+		})
+	}
+}
+
+func TestUnsupportedUnit(t *testing.T) {
+	if _, err := Mass.ToCanonical(10, Inch); err == nil {
+		t.Fatal("converting a weight from inches succeeded")
+	}
+	if v, err := Length.ToCanonical(180, ""); err != nil || v != 180 {
+		t.Fatalf("ToCanonical(180, \"\") = %v, %v", v, err)
+	}
+}
+
+// The ranges UserProfile.Validate accepts, in canonical units
+var roundTripRanges = []struct {
+	name     string
+	dim      Dimension
+	min, max float64
+}{
+	{"weight", Mass, 20, 500},
+	{"height", Length, 50, 300},
+}
+
+// A value entered in imperial units, stored and shown again in imperial
+// units is the value entered, for every value a client can show with the
+// dimension's decimals.
+func TestImperialInputRoundTrip(t *testing.T) {
+	for _, r := range roundTripRanges {
+		t.Run(r.name, func(t *testing.T) {
+			scale := math.Pow(10, float64(r.dim.Decimals))
+			lo := math.Ceil(r.dim.FromCanonical(r.min, Imperial) * scale)
+			hi := math.Floor(r.dim.FromCanonical(r.max, Imperial) * scale)
+			for i := lo; i <= hi; i++ {
+				value := i / scale
+				canonical, err := r.dim.ToCanonical(value, r.dim.Imperial)
+				if err != nil {
+					t.Fatalf("ToCanonical: %v", err)
+				}
+				if c := stored(canonical); c < r.min || c > r.max {
+					t.Fatalf("%v %s is stored as %v, outside [%v, %v]", value, r.dim.Imperial, c, r.min, r.max)
+				}
+				if back := r.dim.FromCanonical(stored(canonical), Imperial); back != value {
+					t.Fatalf("%v %s comes back as %v", value, r.dim.Imperial, back)
+				}
+			}
+		})
+	}
+}
+
+// A stored value shown in imperial units and saved back unchanged, as a
+// form does, settles after the first save: later saves change nothing.
+func TestStoredValueRoundTrip(t *testing.T) {
+	for _, r := range roundTripRanges {
+		t.Run(r.name, func(t *testing.T) {
+			for i := math.Round(r.min * 100); i <= math.Round(r.max*100); i++ {
+				value := i / 100
+				shown := r.dim.FromCanonical(value, Imperial)
+				first, err := r.dim.ToCanonical(shown, r.dim.Imperial)
+				if err != nil {
+					t.Fatalf("ToCanonical: %v", err)
+				}
+				first = stored(first)
+				// Off by at most half a shown step, plus the column's rounding
+				maxDrift := 0.5/math.Pow(10, float64(r.dim.Decimals))*r.dim.factors[r.dim.Imperial] + 0.005
+				if math.Abs(first-value) > maxDrift {
+					t.Fatalf("%v drifted to %v", value, first)
+				}
+
+				reshown := r.dim.FromCanonical(first, Imperial)
+				if reshown != shown {
+					t.Fatalf("%v is shown as %v, then as %v", value, shown, reshown)
+				}
+				second, _ := r.dim.ToCanonical(reshown, r.dim.Imperial)
+				if stored(second) != first {
+					t.Fatalf("%v is stored as %v, then as %v", value, first, stored(second))
+				}
+			}
+		})
+	}
+}
+
+// Metric values are shown as stored.
+func TestMetricIsCanonical(t *testing.T) {
+	for _, r := range roundTripRanges {
+		for _, v := range []float64{r.min, r.max, stored((r.min + r.max) / 3)} {
+			if got := r.dim.FromCanonical(v, Metric); got != v {
+				t.Errorf("%s: FromCanonical(%v, metric) = %v", r.name, v, got)
+			}
+		}
+	}
+}
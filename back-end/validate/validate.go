@@ -0,0 +1,150 @@
+// validate/validate.go
+package validate
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Error codes reported for a field
+const (
+	CodeRequired     = "required"
+	CodeOutOfRange   = "out_of_range"
+	CodeTooLong      = "too_long"
+	CodeTooMany      = "too_many"
+	CodeNotAllowed   = "not_allowed"
+	CodeInvalid      = "invalid"
+	CodeUnknownField = "unknown_field"
+	CodeType         = "invalid_type"
+	CodeDuplicate    = "duplicate"
+	CodeNotFound     = "not_found"
+)
+
+// FieldError describes one invalid field. Field is the JSON path, with
+// array elements written as name[index].
+type FieldError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// Errors is a list of field errors. It is returned as an error by
+// validation so callers can tell it apart from other failures.
+type Errors []FieldError
+
+func (e Errors) Error() string {
+	msgs := make([]string, len(e))
+	for i, fe := range e {
+		msgs[i] = fe.Field + ": " + fe.Message
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Field returns a single field error.
+func Field(field, code, format string, args ...interface{}) Errors {
+	return Errors{{Field: field, Code: code, Message: fmt.Sprintf(format, args...)}}
+}
+
+// Validator collects field errors. The zero value is ready to use.
+type Validator struct {
+	errs Errors
+}
+
+// Add records an error for a field.
+func (v *Validator) Add(field, code, format string, args ...interface{}) {
+	v.errs = append(v.errs, Field(field, code, format, args...)...)
+}
+
+// Merge records err against field. Field errors are kept as they are;
+// any other error becomes an invalid error on field.
+func (v *Validator) Merge(field string, err error) {
+	if err == nil {
+		return
+	}
+	if errs, ok := err.(Errors); ok {
+		v.errs = append(v.errs, errs...)
+		return
+	}
+	v.Add(field, CodeInvalid, "%s", err.Error())
+}
+
+// Nested records the errors of a nested object, such as an element of an
+// array of objects, under prefix.
+func (v *Validator) Nested(prefix string, err error) {
+	errs, ok := err.(Errors)
+	if !ok {
+		v.Merge(prefix, err)
+		return
+	}
+	for _, fe := range errs {
+		fe.Field = prefix + "." + fe.Field
+		v.errs = append(v.errs, fe)
+	}
+}
+
+// Required records an error unless present holds and reports whether it did.
+func (v *Validator) Required(field string, present bool) bool {
+	if !present {
+		v.Add(field, CodeRequired, "%s is required", field)
+	}
+	return present
+}
+
+// Range checks that n lies within [min, max].
+func (v *Validator) Range(field string, n, min, max int) {
+	if n < min || n > max {
+		v.Add(field, CodeOutOfRange, "%s must be between %d and %d", field, min, max)
+	}
+}
+
+// RangeFloat checks that f lies within [min, max].
+func (v *Validator) RangeFloat(field string, f, min, max float64) {
+	if f < min || f > max {
+		v.Add(field, CodeOutOfRange, "%s must be between %g and %g", field, min, max)
+	}
+}
+
+// MaxLength checks that s has at most max characters.
+func (v *Validator) MaxLength(field, s string, max int) {
+	if len([]rune(s)) > max {
+		v.Add(field, CodeTooLong, "%s must be at most %d characters", field, max)
+	}
+}
+
+// OneOf checks that s is one of the allowed values.
+func (v *Validator) OneOf(field, s string, allowed ...string) {
+	for _, a := range allowed {
+		if s == a {
+			return
+		}
+	}
+	v.Add(field, CodeNotAllowed, "%s must be one of %s", field, strings.Join(allowed, ", "))
+}
+
+// Strings checks a string array: at most maxItems elements, each non-empty
+// and at most maxLength characters, and accepted by allowed if it is not
+// nil.
+func (v *Validator) Strings(field string, items []string, maxItems, maxLength int, allowed func(string) bool) {
+	if len(items) > maxItems {
+		v.Add(field, CodeTooMany, "%s must have at most %d items", field, maxItems)
+	}
+	for i, item := range items {
+		path := fmt.Sprintf("%s[%d]", field, i)
+		switch {
+		case strings.TrimSpace(item) == "":
+			v.Add(path, CodeRequired, "%s must not be empty", path)
+		case len([]rune(item)) > maxLength:
+			v.Add(path, CodeTooLong, "%s must be at most %d characters", path, maxLength)
+		case allowed != nil && !allowed(item):
+			v.Add(path, CodeNotAllowed, "%s %q is not supported", path, item)
+		}
+	}
+}
+
+// Err returns the collected errors, or nil if there are none.
+func (v *Validator) Err() error {
+	if len(v.errs) == 0 {
+		return nil
+	}
+	return v.errs
+}